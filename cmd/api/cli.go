@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/config"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const usage = `usage:
+  api                                   run the server
+  api export [flags] people|matches     download the pool or the match history
+  api import [flags] FILE               upload people exported from another environment
+
+run "api export -h" or "api import -h" for flags`
+
+// runCommand runs a CLI subcommand against a running server and returns the exit code
+func runCommand(args []string, cfg *config.Config) int {
+	var err error
+	switch args[0] {
+	case "export":
+		err = exportCommand(args[1:], cfg)
+	case "import":
+		err = importCommand(args[1:], cfg)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func exportCommand(args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	server := flags.String("server", "http://localhost:"+cfg.Port, "base URL of the server")
+	format := flags.String("format", "jsonl", "file format, jsonl or csv")
+	out := flags.String("o", "", "file to write, standard output when empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || (flags.Arg(0) != "people" && flags.Arg(0) != "matches") {
+		return errors.New("export what? people or matches")
+	}
+
+	query := url.Values{"format": {*format}}
+	resp, err := http.Get(*server + "/v1/admin/export/" + flags.Arg(0) + "?" + query.Encode())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return problemError(resp)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func importCommand(args []string, cfg *config.Config) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	server := flags.String("server", "http://localhost:"+cfg.Port, "base URL of the server")
+	format := flags.String("format", "", "file format, jsonl or csv; guessed from the file name when empty")
+	dryRun := flags.Bool("dry-run", false, "only validate the file")
+	match := flags.Bool("match", false, "match each imported person, in file order")
+	actor := flags.String("actor", os.Getenv("USER"), "who the import is audited as made by")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("import needs exactly one file")
+	}
+
+	path := flags.Arg(0)
+	if *format == "" {
+		*format = "jsonl"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			*format = "csv"
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	query := url.Values{
+		"format":  {*format},
+		"dry_run": {strconv.FormatBool(*dryRun)},
+		"match":   {strconv.FormatBool(*match)},
+	}
+	req, err := http.NewRequest(http.MethodPost, *server+"/v1/admin/import/people?"+query.Encode(), file)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set(audit.ActorHeader, *actor)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return problemError(resp)
+	}
+
+	var result dto.ImportPeopleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.DryRun {
+		fmt.Printf("%d people are valid, nothing was imported\n", result.Imported)
+	} else {
+		fmt.Printf("imported %d people, %d matches made\n", result.Imported, len(result.Matches))
+	}
+	return nil
+}
+
+// problemError turns a problem response into an error listing every rejected field
+func problemError(resp *http.Response) error {
+	var problem dto.Problem
+	if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil || problem.Detail == "" {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+
+	lines := []string{problem.Detail}
+	for _, field := range problem.Errors {
+		lines = append(lines, "  "+field.Field+" "+field.Message)
+	}
+	return errors.New(strings.Join(lines, "\n"))
+}
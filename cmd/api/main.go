@@ -1,12 +1,33 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"matching_system/internal/api/grpcserver"
+	"matching_system/internal/api/handlers"
 	"matching_system/internal/api/routes"
+	"matching_system/internal/audit"
+	"matching_system/internal/cluster"
 	"matching_system/internal/config"
+	"matching_system/internal/events"
+	"matching_system/internal/metrics"
+	"matching_system/internal/pools"
+	"matching_system/internal/replication"
+	"matching_system/internal/services"
+	"matching_system/internal/storage"
+	"matching_system/internal/tracing"
+	"matching_system/internal/webhooks"
 	"matching_system/pkg/logger"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 )
 
 // @title Matching System API
@@ -26,20 +47,224 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Subcommands are clients of a running server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], cfg))
+	}
+
 	// Initialize logger
-	logger := logger.New()
+	logger, err := logger.Setup(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal("Invalid LOG_LEVEL or LOG_FORMAT: ", err)
+	}
 
 	// Set Gin mode
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Trace requests through the services, if an exporter is configured
+	shutdownTracing, err := tracing.Setup(cfg.TracingExporter)
+	if err != nil {
+		logger.Fatal("Invalid TRACING_EXPORTER", "error", err)
+	}
+
+	// Pool changes go to the event bus; side effects subscribe there instead of living in matching code
+	bus := events.NewBus()
+	broker := events.NewBroker(cfg.EventHistorySize)
+	bus.Subscribe(broker.Publish)
+	bus.Subscribe(events.LogHandler(logger))
+	// Prometheus metrics count pool changes from the bus and matches from the services
+	m := metrics.New()
+	bus.Subscribe(m.HandleEvent)
+
+	opts := []services.Option{
+		services.WithIdempotency(cfg.IdempotencyTTL, cfg.IdempotencyCapacity),
+		services.WithMatchHistory(cfg.MatchHistorySize),
+		services.WithEvents(bus),
+		services.WithAddObserver(m.ObserveAdd),
+		services.WithLogger(logger),
+	}
+	// Record who made every write, and the person before and after, in the audit log if enabled
+	var auditLog *audit.Log
+	var registryOpts []pools.Option
+	if cfg.AuditLogPath != "" {
+		auditLog, err = audit.Open(cfg.AuditLogPath,
+			audit.WithMaxSize(int64(cfg.AuditLogMaxSize)<<20),
+			audit.WithMaxFiles(cfg.AuditLogMaxFiles),
+		)
+		if err != nil {
+			logger.Fatal("Failed to open the audit log", "error", err)
+		}
+		opts = append(opts, services.WithAudit(auditLog.Record))
+		registryOpts = append(registryOpts, pools.WithAudit(auditLog.Record))
+	}
+	switch cfg.PeopleStore {
+	case "indexed":
+		opts = append(opts, services.WithPeopleStore(func() storage.PeopleStore { return storage.NewIndexedStore() }))
+	case "map":
+		opts = append(opts, services.WithPeopleStore(func() storage.PeopleStore { return storage.NewMapStore() }))
+	default:
+		logger.Fatal("Unknown PEOPLE_STORE", "store", cfg.PeopleStore)
+	}
+
+	// Open every pool, restoring each from its latest snapshot and write-ahead log if enabled, and
+	// snapshot them periodically until shutdown. In a cluster the raft log and snapshots take their place.
+	var registry handlers.PoolRegistry
+	var local *pools.Registry
+	var node *cluster.Node
+	if cfg.ClusterNodeID != "" {
+		if cfg.ReplicationLeader != "" {
+			logger.Fatal("CLUSTER_NODE_ID and REPLICATION_LEADER cannot be combined")
+		}
+		if cfg.SnapshotDir != "" || cfg.WALPath != "" {
+			logger.Warn("SNAPSHOT_DIR and WAL_PATH are ignored in a cluster, see CLUSTER_DIR")
+		}
+		peers, err := cluster.ParsePeers(cfg.ClusterPeers)
+		if err != nil {
+			logger.Fatal("Invalid CLUSTER_PEERS", "error", err)
+		}
+		node, err = cluster.NewNode(cluster.Config{
+			NodeID:            cfg.ClusterNodeID,
+			Peers:             peers,
+			Dir:               cfg.ClusterDir,
+			SnapshotThreshold: uint64(cfg.ClusterSnapshotThreshold),
+		}, logger, cluster.WithServiceOptions(opts...), cluster.WithRegistryOptions(registryOpts...))
+		if err != nil {
+			logger.Fatal("Failed to join the cluster", "error", err)
+		}
+		registry = node
+		logger.Info("Running as a cluster node", "node", cfg.ClusterNodeID)
+	} else {
+		var backend pools.Backend = pools.NewDiskBackend(pools.DiskConfig{
+			SnapshotDir:      cfg.SnapshotDir,
+			SnapshotInterval: cfg.SnapshotInterval,
+			SnapshotRetain:   cfg.SnapshotRetain,
+			WALPath:          cfg.WALPath,
+		}, logger, opts...)
+		if cfg.ReplicationLeader != "" {
+			// a follower starts over from the leader's state, there is nothing of its own to restore
+			if cfg.SnapshotDir != "" || cfg.WALPath != "" {
+				logger.Warn("SNAPSHOT_DIR and WAL_PATH are ignored on a follower")
+			}
+			backend = pools.NewMemoryBackend(opts...)
+		}
+		var err error
+		local, err = pools.NewRegistry(backend, registryOpts...)
+		if err != nil {
+			logger.Fatal("Failed to open pools", "error", err)
+		}
+		registry = local
+	}
+
+	// Follow the leader, if any, serving reads only until promoted
+	var follower *replication.Follower
+	if cfg.ReplicationLeader != "" {
+		follower = replication.NewFollower(cfg.ReplicationLeader, local, logger,
+			replication.WithPollInterval(cfg.ReplicationPollInterval),
+		)
+		follower.Start()
+		logger.Info("Following the leader", "leader", cfg.ReplicationLeader)
+	}
+	m.WatchPools(registry)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Request contexts end when shutdown starts, which closes the event streams, WebSockets and gRPC
+	// streams that would otherwise hold up the drain; ordinary requests still run to completion
+	streams, endStreams := context.WithCancel(context.Background())
+
+	// Start gRPC server
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		logger.Fatal("Failed to listen for gRPC", "error", err)
+	}
+	grpcServer := grpcserver.Register(registry, broker, grpc.ChainUnaryInterceptor(
+		grpcserver.WriteCheck(follower.CheckWritable),
+		grpcserver.AuditSource(),
+	), grpc.ChainStreamInterceptor(
+		grpcserver.EndStreams(streams),
+	))
+	go func() {
+		logger.Info("Starting gRPC server", "port", cfg.GRPCPort)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Fatal("Failed to start gRPC server", "error", err)
+		}
+	}()
+
+	// Deliver pool events to webhook subscribers
+	webhookStore := webhooks.NewStore()
+	dispatcher := webhooks.NewDispatcher(broker, webhookStore,
+		webhooks.WithHTTPClient(&http.Client{Timeout: cfg.WebhookTimeout}),
+		webhooks.WithRetry(cfg.WebhookMaxAttempts, time.Second, time.Minute),
+		webhooks.WithLogger(logger),
+		webhooks.WithLostObserver(m.ObserveWebhookEventsLost),
+	)
+	dispatcher.Start()
+
 	// Create router
-	router := routes.Setup()
+	router := routes.Setup(registry, broker, webhookStore, follower, node, m, logger, auditLog)
+
+	server := &http.Server{
+		Addr:        ":" + cfg.Port,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return streams },
+	}
+	server.RegisterOnShutdown(endStreams)
 
 	// Start server
-	logger.Info("Starting server on port " + cfg.Port)
-	if err := router.Run(":" + cfg.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	go func() {
+		logger.Info("Starting server", "port", cfg.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	// a second signal kills the process right away
+	stop()
+	logger.Info("Shutting down", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// stop accepting connections and let in-flight requests finish, over HTTP and gRPC alike
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Requests still running after the shutdown timeout were cut", "error", err)
+		server.Close()
+	}
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+
+	// with no more writes, deliver the webhooks of the events published so far
+	if follower != nil {
+		follower.Stop()
+	}
+	if err := dispatcher.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Webhook deliveries still pending after the shutdown timeout were abandoned")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("Failed to export the last spans", "error", err)
+	}
+
+	if node != nil {
+		if err := node.Shutdown(); err != nil {
+			logger.Error("Failed to leave the cluster", "error", err)
+		}
+	} else {
+		// one last snapshot of every pool
+		local.Close()
+	}
+	if auditLog != nil {
+		auditLog.Close()
 	}
+	logger.Info("Shut down")
 }
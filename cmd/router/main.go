@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"matching_system/internal/config"
+	"matching_system/internal/sharding"
+	"matching_system/pkg/logger"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const usage = `usage:
+  router                      route API calls to the shards in SHARDS
+  router rebalance [flags]    move every pool to the shard owning it in SHARDS
+
+run "router rebalance -h" for flags`
+
+// rebalanceTimeout bounds each call to a shard while rebalancing; imports of large pools take a while
+const rebalanceTimeout = 5 * time.Minute
+
+func main() {
+	cfg := config.Load()
+	logger, err := logger.Setup(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal("Invalid LOG_LEVEL or LOG_FORMAT: ", err)
+	}
+
+	shards, err := sharding.ParseShards(cfg.Shards)
+	if err != nil {
+		logger.Fatal("Invalid SHARDS", "error", err)
+	}
+	ring, err := sharding.NewRing(shards, cfg.ShardVirtualNodes)
+	if err != nil {
+		logger.Fatal("Invalid SHARDS", "error", err)
+	}
+
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:], ring))
+	}
+
+	if cfg.Environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// like the API server, forwarded streams end when shutdown starts so they do not hold up the drain
+	streams, endStreams := context.WithCancel(context.Background())
+	server := &http.Server{
+		Addr:        ":" + cfg.RouterPort,
+		Handler:     sharding.NewRouter(ring, sharding.WithLogger(logger)),
+		BaseContext: func(net.Listener) context.Context { return streams },
+	}
+	server.RegisterOnShutdown(endStreams)
+	go func() {
+		logger.Info("Routing to shards", "shards", len(shards), "port", cfg.RouterPort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start router", "error", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	logger.Info("Shutting down", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Requests still running after the shutdown timeout were cut", "error", err)
+		server.Close()
+	}
+}
+
+// runCommand runs a subcommand and returns the exit code
+func runCommand(args []string, ring *sharding.Ring) int {
+	var err error
+	switch args[0] {
+	case "rebalance":
+		err = rebalanceCommand(args[1:], ring)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func rebalanceCommand(args []string, ring *sharding.Ring) error {
+	flags := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only list the pools that would move")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	moves, err := sharding.Rebalance(context.Background(), &http.Client{Timeout: rebalanceTimeout}, ring, *dryRun)
+	verb := "moved"
+	if *dryRun {
+		verb = "would move"
+	}
+	for _, move := range moves {
+		fmt.Printf("%s pool %s with %d people from shard %s to %s\n", verb, move.Pool, move.People, move.From, move.To)
+	}
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		fmt.Println("every pool is on the shard owning it")
+	}
+	return nil
+}
@@ -46,6 +46,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/dto.AddPersonRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key used to replay the original response on retries",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -54,6 +60,123 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/dto.AddPersonResponse"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Server-Sent Events stream of every person_added, person_updated, matched, person_removed\nand dates_exhausted event. Each event carries its ID, so a reconnecting client resumes\nwith Last-Event-ID. Only recent events are kept; if the requested ID is too old, a\nreset event is sent before the oldest events still available.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Stream pool events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Resume after this event ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Same as Last-Event-ID, for clients that cannot set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only stream the events of this pool",
+                        "name": "pool",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/events.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "post": {
+                "description": "Execute a GraphQL query or mutation over people and matches. GET only runs queries.\nQueries nested too deeply or resolving too many fields are rejected before they run.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pool, for /v1/pools/{pool}/graphql",
+                        "name": "pool",
+                        "in": "path"
+                    },
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/graphqlapi.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
                     }
                 }
             }
@@ -86,7 +209,7 @@ const docTemplate = `{
         },
         "/query-single-people": {
             "get": {
-                "description": "Query single people",
+                "description": "Query single people. Deprecated, use GET /v1/people instead.",
                 "consumes": [
                     "application/json"
                 ],
@@ -97,6 +220,7 @@ const docTemplate = `{
                     "match"
                 ],
                 "summary": "Query single people",
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "integer",
@@ -112,6 +236,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/dto.QueryPeopleResponse"
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
                     }
                 }
             }
@@ -136,6 +266,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only remove the person if their ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -144,87 +280,1827 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/dto.RemovePersonResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "dto.AddPersonRequest": {
-            "type": "object",
-            "required": [
-                "gender",
-                "height",
-                "name",
-                "wanted_dates"
-            ],
-            "properties": {
-                "gender": {
-                    "type": "string",
-                    "enum": [
-                        "male",
-                        "female"
-                    ]
-                },
-                "height": {
-                    "type": "integer",
-                    "maximum": 250,
-                    "minimum": 100
-                },
-                "name": {
-                    "type": "string"
-                },
-                "wanted_dates": {
-                    "type": "integer",
-                    "minimum": 0
+        },
+        "/single-person/{id}": {
+            "get": {
+                "description": "Get a single active person, with their version as the ETag",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "match"
+                ],
+                "summary": "Get a single person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetPersonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
                 }
             }
         },
-        "dto.AddPersonResponse": {
-            "type": "object",
-            "properties": {
-                "matches": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Match"
+        "/update-single-person/{id}": {
+            "put": {
+                "description": "Replace a single person's attributes and match them again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "match"
+                ],
+                "summary": "Update a single person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Person",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePersonRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only update the person if their ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePersonResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
                     }
-                },
-                "message": {
-                    "type": "string"
-                },
-                "person": {
-                    "$ref": "#/definitions/models.Person"
                 }
             }
         },
-        "dto.QueryPeopleResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "people": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Person"
+        "/v1/admin/audit": {
+            "get": {
+                "description": "List the adds, updates, removals and imports made on request, newest first, with who asked for them and the person before and after. A full page ends with next, to pass as before for the following page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Query the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only writes to this pool",
+                        "name": "pool",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only writes to this person",
+                        "name": "person_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only writes by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "add",
+                            "update",
+                            "remove",
+                            "import",
+                            "delete_pool"
+                        ],
+                        "type": "string",
+                        "description": "Only this kind of write",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only the writes of this request",
+                        "name": "request_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only writes at or after this RFC 3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only writes before this RFC 3339 time",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only entries older than this ID, the next of the previous page",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 100 by default and at most 1000",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueryAuditResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
                     }
                 }
             }
         },
-        "dto.RemovePersonResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                },
-                "success": {
-                    "type": "boolean"
+        "/v1/admin/export/matches": {
+            "get": {
+                "description": "Download the match history, oldest first, as JSON Lines or CSV",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export matches",
+                "parameters": [
+                    {
+                        "enum": [
+                            "jsonl",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "File format, jsonl (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
                 }
             }
         },
-        "models.Match": {
-            "type": "object",
+        "/v1/admin/export/people": {
+            "get": {
+                "description": "Download every active person as JSON Lines or CSV",
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export people",
+                "parameters": [
+                    {
+                        "enum": [
+                            "jsonl",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "File format, jsonl (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/admin/import/people": {
+            "post": {
+                "description": "Add the people of a JSON Lines or CSV file, as written by the export. The whole file is\nvalidated first and nothing is imported if any line is invalid.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import people",
+                "parameters": [
+                    {
+                        "enum": [
+                            "jsonl",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "File format, defaults to csv for a text/csv body and jsonl otherwise",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the file",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Match each imported person, in file order",
+                        "name": "match",
+                        "in": "query"
+                    },
+                    {
+                        "description": "People, one per line or row",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ImportPeopleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/cluster": {
+            "get": {
+                "description": "This node's raft state, the leader it knows of, and how far it has applied and\ncompacted the log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cluster"
+                ],
+                "summary": "Get the cluster status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ClusterStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/cluster/apply": {
+            "post": {
+                "description": "Used by the other nodes to forward writes to the leader. The write is committed only if\nthis node leads; the result, including a failure to commit, is returned as 200.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cluster"
+                ],
+                "summary": "Commit a forwarded write",
+                "parameters": [
+                    {
+                        "description": "Command",
+                        "name": "command",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cluster.Command"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cluster.Result"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/matches": {
+            "get": {
+                "description": "List the match history, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "matches"
+                ],
+                "summary": "List matches",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only matches involving this person",
+                        "name": "person_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit, 0 or omitted returns every match",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueryMatchesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/people": {
+            "get": {
+                "description": "List active people in query order, optionally limited to the top N",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "people"
+                ],
+                "summary": "List single people",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Limit, 0 or omitted returns everyone",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueryPeopleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add a single person and match",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "match"
+                ],
+                "summary": "Add a single person and match",
+                "parameters": [
+                    {
+                        "description": "Person",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AddPersonRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Key used to replay the original response on retries",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AddPersonResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/people/{id}": {
+            "get": {
+                "description": "Get a single active person, with their version as the ETag",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "match"
+                ],
+                "summary": "Get a single person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.GetPersonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a single person's attributes and match them again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "match"
+                ],
+                "summary": "Update a single person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Person",
+                        "name": "person",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePersonRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only update the person if their ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePersonResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Remove a single person",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "match"
+                ],
+                "summary": "Remove a single person",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only remove the person if their ETag matches",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.RemovePersonResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/people/{id}/notifications": {
+            "get": {
+                "description": "Upgrade to a WebSocket that pushes match, dates_exhausted and person_removed events\ninvolving the person. The server pings periodically and disconnects clients that\nstop answering or fall too far behind.",
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Subscribe to a person's notifications",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Person ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/events.Event"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/pools": {
+            "get": {
+                "description": "List every pool, sorted by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pools"
+                ],
+                "summary": "List pools",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueryPoolsResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create an empty pool. People are only matched with people of the same pool, by the\npool's rules. Rules are fixed once the pool exists.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pools"
+                ],
+                "summary": "Create a pool",
+                "parameters": [
+                    {
+                        "description": "Pool",
+                        "name": "pool",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreatePoolRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PoolResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/pools/{pool}": {
+            "get": {
+                "description": "Get a pool and its match rules",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pools"
+                ],
+                "summary": "Get a pool",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pool name",
+                        "name": "pool",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.PoolResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a pool with its people and match history. The default pool cannot be deleted.",
+                "tags": [
+                    "pools"
+                ],
+                "summary": "Delete a pool",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pool name",
+                        "name": "pool",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/pools/{pool}/graphql": {
+            "post": {
+                "description": "Execute a GraphQL query or mutation over people and matches. GET only runs queries.\nQueries nested too deeply or resolving too many fields are rejected before they run.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL endpoint",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Pool, for /v1/pools/{pool}/graphql",
+                        "name": "pool",
+                        "in": "path"
+                    },
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/graphqlapi.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/replication": {
+            "get": {
+                "description": "Whether this server is a leader or a read-only follower and, on a follower, whether\neach pool is connected to the leader and how many operations it has applied",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Get the replication status",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReplicationStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/replication/promote": {
+            "post": {
+                "description": "Stop following the leader and accept writes, on top of what was replicated so far. The\nold leader must no longer take writes, or the two diverge. Promoting a leader does nothing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Promote a follower to leader",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ReplicationStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/replication/stream": {
+            "get": {
+                "description": "Newline-delimited JSON stream for followers. The first message carries the state of the\npool, every later one the write-ahead log records of a batch applied after it, and\nempty messages are heartbeats. A follower that falls too far behind is disconnected and\nstarts over with a new state.",
+                "produces": [
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "replication"
+                ],
+                "summary": "Stream a pool's operations",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/replication.Message"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks": {
+            "get": {
+                "description": "List webhook subscriptions, oldest first. Secrets are never returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueryWebhooksResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe a URL to pool events. Each event is POSTed as JSON, signed with the secret\nin the X-Webhook-Signature header, and retried with exponential backoff on failure.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Subscribe a webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateWebhookRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebhookResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.WebhookResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a webhook subscription and its delivery log. Pending retries are abandoned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the recent deliveries of a webhook with every attempt, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/dto.QueryDeliveriesResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/dto.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "audit.Entry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "at": {
+                    "type": "string"
+                },
+                "before": {
+                    "description": "Before is nil for adds and imports, After for removes. After is the person once matched.",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "ID increases with every entry of a log, newer entries have higher IDs",
+                    "type": "integer"
+                },
+                "log_index": {
+                    "description": "LogIndex is the cluster log entry of the write, in a cluster",
+                    "type": "integer"
+                },
+                "operation": {
+                    "$ref": "#/definitions/audit.Op"
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "pool": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.Op": {
+            "type": "string",
+            "enum": [
+                "add",
+                "update",
+                "remove",
+                "import",
+                "delete_pool"
+            ],
+            "x-enum-varnames": [
+                "OpAdd",
+                "OpUpdate",
+                "OpRemove",
+                "OpImport",
+                "OpDeletePool"
+            ]
+        },
+        "cluster.Command": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor and RequestID are the audit source of the write, which every node records as it applies it",
+                    "type": "string"
+                },
+                "add": {
+                    "$ref": "#/definitions/dto.AddPersonRequest"
+                },
+                "at": {
+                    "description": "At is stamped by the leader; every node applies the command as of that time",
+                    "type": "string"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "expected_version": {
+                    "type": "integer"
+                },
+                "idempotency_key": {
+                    "type": "string"
+                },
+                "lines": {
+                    "description": "Lines are where People were read, which their JSON leaves out",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "op": {
+                    "$ref": "#/definitions/cluster.Op"
+                },
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ImportPerson"
+                    }
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "pool": {
+                    "type": "string"
+                },
+                "request_id": {
+                    "type": "string"
+                },
+                "rules": {
+                    "$ref": "#/definitions/models.MatchRules"
+                },
+                "run_matching": {
+                    "type": "boolean"
+                },
+                "update": {
+                    "$ref": "#/definitions/dto.UpdatePersonRequest"
+                }
+            }
+        },
+        "cluster.Op": {
+            "type": "string",
+            "enum": [
+                "create_pool",
+                "delete_pool",
+                "add",
+                "update",
+                "remove",
+                "import"
+            ],
+            "x-enum-varnames": [
+                "OpCreatePool",
+                "OpDeletePool",
+                "OpAdd",
+                "OpUpdate",
+                "OpRemove",
+                "OpImport"
+            ]
+        },
+        "cluster.Result": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/services.Error"
+                },
+                "index": {
+                    "description": "Index is the log entry of the command, which the proposing node waits to apply",
+                    "type": "integer"
+                },
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Match"
+                    }
+                },
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Person"
+                    }
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "pool": {
+                    "$ref": "#/definitions/models.Pool"
+                }
+            }
+        },
+        "dto.AddPersonRequest": {
+            "type": "object",
+            "required": [
+                "gender",
+                "height",
+                "name",
+                "wanted_dates"
+            ],
+            "properties": {
+                "gender": {
+                    "type": "string",
+                    "enum": [
+                        "male",
+                        "female"
+                    ]
+                },
+                "height": {
+                    "type": "integer",
+                    "maximum": 250,
+                    "minimum": 100
+                },
+                "name": {
+                    "type": "string"
+                },
+                "wanted_dates": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.AddPersonResponse": {
+            "type": "object",
+            "properties": {
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Match"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                }
+            }
+        },
+        "dto.ClusterPeer": {
+            "type": "object",
+            "properties": {
+                "http_addr": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "raft_addr": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ClusterStatus": {
+            "type": "object",
+            "properties": {
+                "applied_index": {
+                    "description": "AppliedIndex is the last log entry this node applied to its pools",
+                    "type": "integer"
+                },
+                "leader": {
+                    "description": "Leader is the ID of the current leader, empty during an election",
+                    "type": "string"
+                },
+                "node_id": {
+                    "type": "string"
+                },
+                "peers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ClusterPeer"
+                    }
+                },
+                "snapshot_index": {
+                    "description": "SnapshotIndex is the last log entry of the latest snapshot; entries up to it are compacted",
+                    "type": "integer"
+                },
+                "state": {
+                    "description": "State is \"leader\", \"follower\" or \"candidate\"",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.CreatePoolRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "rules": {
+                    "description": "Rules default to matching a man with any shorter woman",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.MatchRules"
+                        }
+                    ]
+                }
+            }
+        },
+        "dto.CreateWebhookRequest": {
+            "type": "object",
+            "required": [
+                "event_types",
+                "secret",
+                "url"
+            ],
+            "properties": {
+                "event_types": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret signs every delivery, receivers verify the X-Webhook-Signature header with it",
+                    "type": "string",
+                    "minLength": 16
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.GetPersonResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                }
+            }
+        },
+        "dto.ImportPeopleResponse": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "imported": {
+                    "type": "integer"
+                },
+                "matches": {
+                    "description": "Matches are the matches made while importing, only when matching was requested",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Match"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ImportPerson": {
+            "type": "object",
+            "required": [
+                "gender",
+                "height",
+                "name",
+                "wanted_dates"
+            ],
+            "properties": {
+                "gender": {
+                    "type": "string",
+                    "enum": [
+                        "male",
+                        "female"
+                    ]
+                },
+                "height": {
+                    "type": "integer",
+                    "maximum": 250,
+                    "minimum": 100
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "wanted_dates": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.PoolResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "pool": {
+                    "$ref": "#/definitions/models.Pool"
+                }
+            }
+        },
+        "dto.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ProblemFieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ProblemFieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.QueryAuditResponse": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/audit.Entry"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "next": {
+                    "description": "Next is the before of the next page, left out on the last page",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.QueryDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookDelivery"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.QueryMatchesResponse": {
+            "type": "object",
+            "properties": {
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Match"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.QueryPeopleResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Person"
+                    }
+                }
+            }
+        },
+        "dto.QueryPoolsResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "pools": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Pool"
+                    }
+                }
+            }
+        },
+        "dto.QueryWebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Webhook"
+                    }
+                }
+            }
+        },
+        "dto.RemovePersonResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "success": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.ReplicatedPool": {
+            "type": "object",
+            "properties": {
+                "connected": {
+                    "description": "Connected is whether the pool currently streams from the leader",
+                    "type": "boolean"
+                },
+                "pool": {
+                    "type": "string"
+                },
+                "revision": {
+                    "description": "Revision is the number of operations the pool has applied",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ReplicationStatus": {
+            "type": "object",
+            "properties": {
+                "leader": {
+                    "description": "Leader is the base URL of the leader a follower streams from",
+                    "type": "string"
+                },
+                "pools": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/dto.ReplicatedPool"
+                    }
+                },
+                "role": {
+                    "description": "Role is \"leader\" or \"follower\"",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UpdatePersonRequest": {
+            "type": "object",
+            "required": [
+                "gender",
+                "height",
+                "name",
+                "wanted_dates"
+            ],
+            "properties": {
+                "gender": {
+                    "type": "string",
+                    "enum": [
+                        "male",
+                        "female"
+                    ]
+                },
+                "height": {
+                    "type": "integer",
+                    "maximum": 250,
+                    "minimum": 100
+                },
+                "name": {
+                    "type": "string"
+                },
+                "wanted_dates": {
+                    "type": "integer",
+                    "minimum": 0
+                }
+            }
+        },
+        "dto.UpdatePersonResponse": {
+            "type": "object",
+            "properties": {
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Match"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                }
+            }
+        },
+        "dto.WebhookResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "webhook": {
+                    "$ref": "#/definitions/models.Webhook"
+                }
+            }
+        },
+        "events.Event": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "description": "ID increases with every published event, so clients can resume after the last one they saw",
+                    "type": "integer"
+                },
+                "match": {
+                    "$ref": "#/definitions/models.Match"
+                },
+                "occurred_at": {
+                    "type": "string"
+                },
+                "person": {
+                    "$ref": "#/definitions/models.Person"
+                },
+                "person_id": {
+                    "type": "string"
+                },
+                "pool": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/events.Type"
+                }
+            }
+        },
+        "events.Type": {
+            "type": "string",
+            "enum": [
+                "person_added",
+                "person_updated",
+                "matched",
+                "person_removed",
+                "dates_exhausted"
+            ],
+            "x-enum-varnames": [
+                "PersonAdded",
+                "PersonUpdated",
+                "Matched",
+                "PersonRemoved",
+                "DatesExhausted"
+            ]
+        },
+        "graphqlapi.Request": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
+                }
+            }
+        },
+        "models.DeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "DeliveryPending",
+                "DeliverySucceeded",
+                "DeliveryFailed"
+            ]
+        },
+        "models.Match": {
+            "type": "object",
             "properties": {
+                "matched_at": {
+                    "type": "string"
+                },
                 "person1": {
                     "$ref": "#/definitions/models.Person"
                 },
@@ -233,6 +2109,17 @@ const docTemplate = `{
                 }
             }
         },
+        "models.MatchRules": {
+            "type": "object",
+            "properties": {
+                "max_height_gap": {
+                    "type": "integer"
+                },
+                "min_height_gap": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.Person": {
             "type": "object",
             "properties": {
@@ -248,10 +2135,235 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "version": {
+                    "type": "integer"
+                },
                 "wanted_dates": {
                     "type": "integer"
                 }
             }
+        },
+        "models.Pool": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "rules": {
+                    "$ref": "#/definitions/models.MatchRules"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookAttempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "number": {
+                    "type": "integer"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.WebhookAttempt"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.DeliveryStatus"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "replication.Message": {
+            "type": "object",
+            "properties": {
+                "records": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/wal.Record"
+                    }
+                },
+                "state": {
+                    "$ref": "#/definitions/snapshot.State"
+                }
+            }
+        },
+        "services.Error": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/services.ErrorCode"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "services.ErrorCode": {
+            "type": "string",
+            "enum": [
+                "validation_failed",
+                "malformed_request",
+                "person_not_found",
+                "route_not_found",
+                "method_not_allowed",
+                "version_mismatch",
+                "idempotency_key_mismatch",
+                "webhook_not_found",
+                "pool_not_found",
+                "pool_exists",
+                "read_only",
+                "no_leader",
+                "shard_unavailable",
+                "internal_error"
+            ],
+            "x-enum-varnames": [
+                "CodeValidationFailed",
+                "CodeMalformedRequest",
+                "CodePersonNotFound",
+                "CodeRouteNotFound",
+                "CodeMethodNotAllowed",
+                "CodeVersionMismatch",
+                "CodeIdempotencyKeyMismatch",
+                "CodeWebhookNotFound",
+                "CodePoolNotFound",
+                "CodePoolExists",
+                "CodeReadOnly",
+                "CodeNoLeader",
+                "CodeShardUnavailable",
+                "CodeInternal"
+            ]
+        },
+        "services.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "snapshot.State": {
+            "type": "object",
+            "properties": {
+                "matches": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Match"
+                    }
+                },
+                "people": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Person"
+                    }
+                },
+                "revision": {
+                    "description": "Revision counts the changes applied to the service, so unchanged state is not saved twice",
+                    "type": "integer"
+                },
+                "taken_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "wal.Op": {
+            "type": "string",
+            "enum": [
+                "add",
+                "update",
+                "remove",
+                "import"
+            ],
+            "x-enum-varnames": [
+                "OpAdd",
+                "OpUpdate",
+                "OpRemove",
+                "OpImport"
+            ]
+        },
+        "wal.Record": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "op": {
+                    "$ref": "#/definitions/wal.Op"
+                },
+                "person": {
+                    "description": "Person holds the assigned ID and the new attributes for add, import and update, only the ID for remove",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Person"
+                        }
+                    ]
+                },
+                "revision": {
+                    "description": "Revision is the service revision the operation was applied to",
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,10 @@
+package dto
+
+import "matching_system/internal/audit"
+
+type QueryAuditResponse struct {
+	Entries []audit.Entry `json:"entries"`
+	// Next is the before of the next page, left out on the last page
+	Next    uint64 `json:"next,omitempty"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+// ClusterStatus describes a cluster node as this node sees it
+type ClusterStatus struct {
+	NodeID string `json:"node_id"`
+	// State is "leader", "follower" or "candidate"
+	State string `json:"state"`
+	// Leader is the ID of the current leader, empty during an election
+	Leader string `json:"leader,omitempty"`
+	// AppliedIndex is the last log entry this node applied to its pools
+	AppliedIndex uint64 `json:"applied_index"`
+	// SnapshotIndex is the last log entry of the latest snapshot; entries up to it are compacted
+	SnapshotIndex uint64        `json:"snapshot_index"`
+	Peers         []ClusterPeer `json:"peers"`
+}
+
+type ClusterPeer struct {
+	ID       string `json:"id"`
+	RaftAddr string `json:"raft_addr"`
+	HTTPAddr string `json:"http_addr"`
+}
@@ -10,6 +10,14 @@ type AddPersonRequest struct {
 	WantedDates int    `json:"wanted_dates" binding:"required,min=0"`
 }
 
+// UpdatePersonRequest represents the request body for replacing a person's attributes
+type UpdatePersonRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Height      int    `json:"height" binding:"required,min=100,max=250"`
+	Gender      string `json:"gender" binding:"required,oneof=male female"`
+	WantedDates int    `json:"wanted_dates" binding:"required,min=0"`
+}
+
 type AddPersonResponse struct {
 	Person  models.Person  `json:"person"`
 	Matches []models.Match `json:"matches"`
@@ -25,3 +33,19 @@ type QueryPeopleResponse struct {
 	People  []models.Person `json:"people"`
 	Message string          `json:"message"`
 }
+
+type GetPersonResponse struct {
+	Person  models.Person `json:"person"`
+	Message string        `json:"message"`
+}
+
+type UpdatePersonResponse struct {
+	Person  models.Person  `json:"person"`
+	Matches []models.Match `json:"matches"`
+	Message string         `json:"message"`
+}
+
+type QueryMatchesResponse struct {
+	Matches []models.Match `json:"matches"`
+	Message string         `json:"message"`
+}
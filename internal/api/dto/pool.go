@@ -0,0 +1,20 @@
+package dto
+
+import "matching_system/internal/models"
+
+// CreatePoolRequest represents the request body for creating a pool
+type CreatePoolRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Rules default to matching a man with any shorter woman
+	Rules *models.MatchRules `json:"rules"`
+}
+
+type PoolResponse struct {
+	Pool    models.Pool `json:"pool"`
+	Message string      `json:"message"`
+}
+
+type QueryPoolsResponse struct {
+	Pools   []models.Pool `json:"pools"`
+	Message string        `json:"message"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// Problem is an RFC 7807 problem details body, served as application/problem+json
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code"`
+	Errors   []ProblemFieldError `json:"errors,omitempty"`
+}
+
+// ProblemFieldError describes why a single request field was rejected
+type ProblemFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+// ReplicationStatus describes the role of this server and, on a follower, how far each pool got
+type ReplicationStatus struct {
+	// Role is "leader" or "follower"
+	Role string `json:"role"`
+	// Leader is the base URL of the leader a follower streams from
+	Leader string           `json:"leader,omitempty"`
+	Pools  []ReplicatedPool `json:"pools,omitempty"`
+}
+
+type ReplicatedPool struct {
+	Pool string `json:"pool"`
+	// Connected is whether the pool currently streams from the leader
+	Connected bool `json:"connected"`
+	// Revision is the number of operations the pool has applied
+	Revision uint64 `json:"revision"`
+}
@@ -0,0 +1,24 @@
+package dto
+
+import "matching_system/internal/models"
+
+// ImportPerson is one person read from an import file. ID and Version are optional: a missing ID
+// is generated and a missing version starts at 1.
+type ImportPerson struct {
+	// Line is where the person was read, so validation errors point into the file
+	Line        int    `json:"-"`
+	ID          string `json:"id"`
+	Name        string `json:"name" binding:"required"`
+	Height      int    `json:"height" binding:"required,min=100,max=250"`
+	Gender      string `json:"gender" binding:"required,oneof=male female"`
+	WantedDates int    `json:"wanted_dates" binding:"required,min=0"`
+	Version     int64  `json:"version" binding:"min=0"`
+}
+
+type ImportPeopleResponse struct {
+	DryRun   bool `json:"dry_run"`
+	Imported int  `json:"imported"`
+	// Matches are the matches made while importing, only when matching was requested
+	Matches []models.Match `json:"matches"`
+	Message string         `json:"message"`
+}
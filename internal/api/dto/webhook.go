@@ -0,0 +1,26 @@
+package dto
+
+import "matching_system/internal/models"
+
+// CreateWebhookRequest represents the request body for subscribing a URL to pool events
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=person_added person_updated matched person_removed dates_exhausted"`
+	// Secret signs every delivery, receivers verify the X-Webhook-Signature header with it
+	Secret string `json:"secret" binding:"required,min=16"`
+}
+
+type WebhookResponse struct {
+	Webhook models.Webhook `json:"webhook"`
+	Message string         `json:"message"`
+}
+
+type QueryWebhooksResponse struct {
+	Webhooks []models.Webhook `json:"webhooks"`
+	Message  string           `json:"message"`
+}
+
+type QueryDeliveriesResponse struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"`
+	Message    string                   `json:"message"`
+}
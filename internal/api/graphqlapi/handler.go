@@ -0,0 +1,113 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"matching_system/internal/api/handlers"
+	"matching_system/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// errMutationOverGet refuses changes requested with GET, which links and images can send on a
+// user's behalf
+var errMutationOverGet = &services.Error{Code: services.CodeMethodNotAllowed, Message: "GET only runs queries, send mutations with POST"}
+
+// Request is a GraphQL over HTTP request
+type Request struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type Handler struct {
+	schema graphql.Schema
+}
+
+// NewHandler panics if the schema is invalid, which can only be a programming error
+func NewHandler(matchService services.MatchService, opts ...Option) *Handler {
+	schema, err := NewSchema(matchService, opts...)
+	if err != nil {
+		panic("graphqlapi: invalid schema: " + err.Error())
+	}
+	return &Handler{schema: schema}
+}
+
+// Serve godoc
+// @Summary GraphQL endpoint
+// @Description Execute a GraphQL query or mutation over people and matches. GET only runs queries.
+// @Description Queries nested too deeply or resolving too many fields are rejected before they run.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param pool path string false "Pool, for /v1/pools/{pool}/graphql"
+// @Param request body Request true "GraphQL request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 405 {object} dto.Problem
+// @Router /graphql [post]
+// @Router /v1/pools/{pool}/graphql [post]
+func (h *Handler) Serve(c *gin.Context) {
+	var req Request
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(&req); err != nil {
+			c.Error(services.NewMalformedRequestError("query string is not a valid GraphQL request"))
+			return
+		}
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				c.Error(services.NewValidationError(services.FieldError{Field: "variables", Message: "must be a JSON object"}))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(services.NewMalformedRequestError("request body is not a valid GraphQL request"))
+		return
+	}
+
+	if req.Query == "" {
+		c.Error(services.NewValidationError(services.FieldError{Field: "query", Message: "is required"}))
+		return
+	}
+
+	// a document that does not parse is left for graphql.Do to report
+	document, err := parser.Parse(parser.ParseParams{Source: req.Query})
+	if err == nil {
+		if c.Request.Method == http.MethodGet && !onlyQueries(document) {
+			c.Header("Allow", http.MethodPost)
+			c.Error(errMutationOverGet)
+			return
+		}
+		if err := checkLimits(document, req.Variables); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if service, ok := handlers.ScopedMatchService(c); ok {
+		ctx = NewContext(ctx, service)
+	}
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// onlyQueries reports whether every operation of document is a query
+func onlyQueries(document *ast.Document) bool {
+	for _, definition := range document.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok && operation.Operation != ast.OperationTypeQuery {
+			return false
+		}
+	}
+	return true
+}
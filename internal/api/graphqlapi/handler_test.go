@@ -0,0 +1,214 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/api/middleware"
+	"matching_system/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func setupTestRouter(matchService services.MatchService, opts ...Option) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Problems())
+	handler := NewHandler(matchService, opts...)
+	router.POST("/graphql", handler.Serve)
+	router.GET("/graphql", handler.Serve)
+	return router
+}
+
+func doGraphQL(t *testing.T, router *gin.Engine, query string, variables map[string]interface{}) graphqlResponse {
+	body, _ := json.Marshal(Request{Query: query, Variables: variables})
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response graphqlResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return response
+}
+
+func TestGraphQL_PersonWithMatchesAndCandidates(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService)
+
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 3})
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 190, Gender: "female", WantedDates: 1})
+
+	response := doGraphQL(t, router, `query($id: ID!) {
+		person(id: $id) {
+			name
+			gender
+			wantedDates
+			matches { person1 { name } person2 { name } }
+		}
+		candidates(gender: MALE, height: 175) { name }
+	}`, map[string]interface{}{"id": alice.ID})
+
+	require.Empty(t, response.Errors)
+	person := response.Data["person"].(map[string]interface{})
+	assert.Equal(t, "Alice", person["name"])
+	assert.Equal(t, "FEMALE", person["gender"])
+	assert.Equal(t, float64(2), person["wantedDates"])
+
+	matches := person["matches"].([]interface{})
+	require.Len(t, matches, 1)
+	assert.Equal(t, "Bob", matches[0].(map[string]interface{})["person1"].(map[string]interface{})["name"])
+
+	candidates := response.Data["candidates"].([]interface{})
+	require.Len(t, candidates, 1, "only Alice is shorter than a 175cm man")
+	assert.Equal(t, "Alice", candidates[0].(map[string]interface{})["name"])
+}
+
+func TestGraphQL_AddPersonMutation(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService)
+
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+
+	response := doGraphQL(t, router, `mutation {
+		addPerson(input: {name: "Bob", height: 180, gender: MALE, wantedDates: 2}) {
+			person { id wantedDates }
+			matches { person2 { name } }
+		}
+	}`, nil)
+
+	require.Empty(t, response.Errors)
+	payload := response.Data["addPerson"].(map[string]interface{})
+	assert.Equal(t, float64(1), payload["person"].(map[string]interface{})["wantedDates"])
+	assert.Len(t, payload["matches"], 1)
+	assert.Len(t, matchService.QuerySinglePeople(0), 1, "only Bob should remain")
+}
+
+func TestGraphQL_MutationErrorsCarryCodes(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService)
+
+	response := doGraphQL(t, router, `mutation {
+		addPerson(input: {name: "Tiny", height: 50, gender: FEMALE, wantedDates: 1}) { person { id } }
+	}`, nil)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "validation_failed", response.Errors[0].Extensions["code"])
+
+	response = doGraphQL(t, router, `mutation { removePerson(id: "missing") }`, nil)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "person_not_found", response.Errors[0].Extensions["code"])
+}
+
+func TestGraphQL_WriteCheck(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService, WithWriteCheck(func() error { return services.ErrReadOnly }))
+
+	response := doGraphQL(t, router, `mutation {
+		addPerson(input: {name: "Bob", height: 180, gender: MALE, wantedDates: 2}) { person { id } }
+	}`, nil)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "read_only", response.Errors[0].Extensions["code"])
+	assert.Empty(t, matchService.QuerySinglePeople(0))
+
+	response = doGraphQL(t, router, `{ people { id } }`, nil)
+	assert.Empty(t, response.Errors, "queries are still served")
+}
+
+func TestGraphQL_GetRequest(t *testing.T) {
+	router := setupTestRouter(services.NewMatchService())
+
+	req, _ := http.NewRequest("GET", "/graphql?query="+url.QueryEscape("{ people { id } }"), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"people":[]}}`, w.Body.String())
+}
+
+func TestGraphQL_GetRequestRefusesMutations(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService)
+
+	mutation := `mutation { addPerson(input: {name: "Bob", height: 180, gender: MALE, wantedDates: 2}) { person { id } } }`
+	req, _ := http.NewRequest("GET", "/graphql?query="+url.QueryEscape(mutation), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, http.MethodPost, w.Header().Get("Allow"))
+	assert.Contains(t, w.Body.String(), `"code":"method_not_allowed"`)
+	assert.Empty(t, matchService.QuerySinglePeople(0), "the mutation should not run")
+}
+
+func TestGraphQL_MissingQuery(t *testing.T) {
+	router := setupTestRouter(services.NewMatchService())
+
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, middleware.ProblemContentType, w.Header().Get("Content-Type"))
+}
+
+func TestGraphQL_RejectsExpensiveQueries(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService)
+	post := func(query string, variables map[string]interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(Request{Query: query, Variables: variables})
+		req, _ := http.NewRequest("POST", "/graphql", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for name, query := range map[string]string{
+		"too deep":        `{ person(id: "1") { matches(limit: 1) { person1 { matches(limit: 1) { person2 { matches(limit: 1) { person1 { matches(limit: 1) { person2 { name } } } } } } } } } }`,
+		"too many fields": `{ people { candidates { candidates { name } } } }`,
+		"through fragments": `{ people { ...more } }
+			fragment more on Person { candidates(limit: 100) { candidates(limit: 100) { name } } }`,
+	} {
+		w := post(query, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		assert.Contains(t, w.Body.String(), `"field":"query"`, name)
+	}
+
+	w := post(`query($n: Int) { person(id: "1") { candidates(limit: $n) { candidates(limit: $n) { name } } } }`, map[string]interface{}{"n": 100})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "limits given as variables count too")
+
+	response := doGraphQL(t, router, `{ people { name candidates(limit: 5) { name matches { matchedAt } } } }`, nil)
+	assert.Empty(t, response.Errors, "bounded queries are served")
+}
+
+func TestGraphQL_ValidatesLimits(t *testing.T) {
+	matchService := services.NewMatchService()
+	router := setupTestRouter(matchService)
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+
+	response := doGraphQL(t, router, `{ people(limit: -1) { id } }`, nil)
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "validation_failed", response.Errors[0].Extensions["code"])
+
+	response = doGraphQL(t, router, `query($id: ID!) { person(id: $id) { candidates(limit: 500) { id } } }`, map[string]interface{}{"id": alice.ID})
+	require.Len(t, response.Errors, 1)
+	assert.Equal(t, "validation_failed", response.Errors[0].Extensions["code"])
+}
@@ -0,0 +1,143 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"matching_system/internal/services"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	// maxDepth bounds how deeply the fields of a query nest
+	maxDepth = 8
+	// maxComplexity bounds the number of fields a query may resolve, estimated by complexity
+	maxComplexity = 10000
+	// unlimitedListSize is what a list asked for without a limit counts as
+	unlimitedListSize = 100
+	// defaultNestedLimit and maxNestedLimit bound the lists of a person, which lead back to people
+	// and would otherwise let a query grow with every level
+	defaultNestedLimit = 10
+	maxNestedLimit     = 100
+)
+
+// listFields are the fields returning lists of objects, whose selections resolve once per item
+var listFields = map[string]bool{"people": true, "matches": true, "candidates": true}
+
+// checkLimits rejects a document with an operation nested deeper than maxDepth or estimated to
+// resolve more than maxComplexity fields, before any of it runs
+func checkLimits(document *ast.Document, variables map[string]interface{}) error {
+	w := walker{fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok && fragment.Name != nil {
+			w.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		depth, complexity := w.measure(operation.SelectionSet, true, map[string]bool{})
+		if depth > maxDepth {
+			return services.NewValidationError(services.FieldError{Field: "query", Message: fmt.Sprintf("must not nest fields more than %d deep", maxDepth)})
+		}
+		if complexity > maxComplexity {
+			return services.NewValidationError(services.FieldError{Field: "query", Message: fmt.Sprintf("must not resolve more than %d fields, lower the limits", maxComplexity)})
+		}
+	}
+	return nil
+}
+
+type walker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// measure returns how deeply set nests and how many fields it resolves: each field counts once,
+// and the selections of a list once per item it may return. spreading holds the fragments being
+// measured, a fragment spreading itself is left for validation to reject.
+func (w walker) measure(set *ast.SelectionSet, root bool, spreading map[string]bool) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, c int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			d, c = w.measure(selection.SelectionSet, false, spreading)
+			d++
+			c = 1 + c*w.size(selection, root)
+		case *ast.InlineFragment:
+			d, c = w.measure(selection.SelectionSet, root, spreading)
+		case *ast.FragmentSpread:
+			fragment, ok := w.fragments[selection.Name.Value]
+			if !ok || spreading[selection.Name.Value] {
+				continue
+			}
+			spreading[selection.Name.Value] = true
+			d, c = w.measure(fragment.SelectionSet, root, spreading)
+			delete(spreading, selection.Name.Value)
+		}
+		depth = max(depth, d)
+		// past the maximum the exact figure does not matter, and it could overflow
+		complexity = min(complexity+c, maxComplexity+1)
+	}
+	return depth, complexity
+}
+
+// size is how many items field may return, one unless it is a list
+func (w walker) size(field *ast.Field, root bool) int {
+	if field.SelectionSet == nil || !listFields[field.Name.Value] {
+		return 1
+	}
+	limit, ok := w.limit(field)
+	switch {
+	case ok && limit > 0:
+		return limit
+	case !root && !ok:
+		return defaultNestedLimit
+	}
+	return unlimitedListSize
+}
+
+// limit reads the limit argument of field, ok is false when it is not given as an integer
+func (w walker) limit(field *ast.Field) (int, bool) {
+	for _, argument := range field.Arguments {
+		if argument.Name == nil || argument.Name.Value != "limit" {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			limit, err := strconv.Atoi(value.Value)
+			return limit, err == nil
+		case *ast.Variable:
+			switch limit := w.variables[value.Name.Value].(type) {
+			case float64:
+				return int(limit), true
+			case int:
+				return limit, true
+			case json.Number:
+				n, err := limit.Int64()
+				return int(n), err == nil
+			}
+		}
+	}
+	return 0, false
+}
+
+// limitOf reads the limit argument of a field, rejecting a negative one as HTTP and gRPC do, and one
+// above max when max is not zero
+func limitOf(p graphql.ResolveParams, max int) (int, error) {
+	limit := p.Args["limit"].(int)
+	if limit < 0 {
+		return 0, toResolverError(services.NewValidationError(services.FieldError{Field: "limit", Message: "must be a non-negative integer"}))
+	}
+	if max > 0 && (limit < 1 || limit > max) {
+		return 0, toResolverError(services.NewValidationError(services.FieldError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", max)}))
+	}
+	return limit, nil
+}
@@ -0,0 +1,339 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+
+	"github.com/graphql-go/graphql"
+)
+
+// resolverError exposes the stable service error code to GraphQL clients as an extension
+type resolverError struct {
+	err *services.Error
+}
+
+func (e resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e resolverError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": string(e.err.Code)}
+	if len(e.err.Fields) > 0 {
+		extensions["fields"] = e.err.Fields
+	}
+	return extensions
+}
+
+func toResolverError(err error) error {
+	var serviceErr *services.Error
+	if errors.As(err, &serviceErr) {
+		return resolverError{err: serviceErr}
+	}
+	return err
+}
+
+type matchServiceKey struct{}
+
+// NewContext has the schema resolve the request with service instead of its own, for the pool the
+// request was made on
+func NewContext(ctx context.Context, service services.MatchService) context.Context {
+	return context.WithValue(ctx, matchServiceKey{}, service)
+}
+
+// Option configures the schema
+type Option func(*schemaConfig)
+
+type schemaConfig struct {
+	writeCheck func() error
+}
+
+// WithWriteCheck fails every mutation with the error of check, if any, before it runs, such as on a
+// read-only follower
+func WithWriteCheck(check func() error) Option {
+	return func(config *schemaConfig) {
+		config.writeCheck = check
+	}
+}
+
+// NewSchema builds the GraphQL schema over people and matches, resolved by the service in the
+// context of the request, see NewContext, or matchService
+func NewSchema(matchService services.MatchService, opts ...Option) (graphql.Schema, error) {
+	var config schemaConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	service := func(p graphql.ResolveParams) services.MatchService {
+		if scoped, ok := p.Context.Value(matchServiceKey{}).(services.MatchService); ok {
+			return scoped
+		}
+		return matchService
+	}
+
+	genderEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name: "Gender",
+		Values: graphql.EnumValueConfigMap{
+			"MALE":   &graphql.EnumValueConfig{Value: "male"},
+			"FEMALE": &graphql.EnumValueConfig{Value: "female"},
+		},
+	})
+
+	personType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Person",
+		Fields: graphql.Fields{
+			"id":          personField(graphql.ID, func(p models.Person) interface{} { return p.ID }),
+			"name":        personField(graphql.String, func(p models.Person) interface{} { return p.Name }),
+			"height":      personField(graphql.Int, func(p models.Person) interface{} { return p.Height }),
+			"gender":      personField(genderEnum, func(p models.Person) interface{} { return p.Gender }),
+			"wantedDates": personField(graphql.Int, func(p models.Person) interface{} { return p.WantedDates }),
+			"version":     personField(graphql.Int, func(p models.Person) interface{} { return p.Version }),
+		},
+	})
+
+	matchType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Match",
+		Fields: graphql.Fields{
+			"person1":   matchField(personType, func(m models.Match) interface{} { return m.Person1 }),
+			"person2":   matchField(personType, func(m models.Match) interface{} { return m.Person2 }),
+			"matchedAt": matchField(graphql.DateTime, func(m models.Match) interface{} { return m.MatchedAt }),
+		},
+	})
+
+	limitArg := &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0, Description: "0 returns everything"}
+	// the lists of a person lead back to people, so they are always bounded
+	nestedLimitArg := &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: defaultNestedLimit,
+		Description:  fmt.Sprintf("between 1 and %d", maxNestedLimit),
+	}
+
+	// field-level resolvers, so a single query can fetch a person with their matches and candidates
+	personType.AddFieldConfig("matches", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(matchType))),
+		Description: "match history involving this person, newest first",
+		Args:        graphql.FieldConfigArgument{"limit": nestedLimitArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit, err := limitOf(p, maxNestedLimit)
+			if err != nil {
+				return nil, err
+			}
+			person := p.Source.(models.Person)
+			return service(p).QueryMatches(person.ID, limit), nil
+		},
+	})
+	personType.AddFieldConfig("candidates", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(personType))),
+		Description: "active people this person is compatible with, in matching order",
+		Args:        graphql.FieldConfigArgument{"limit": nestedLimitArg},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			limit, err := limitOf(p, maxNestedLimit)
+			if err != nil {
+				return nil, err
+			}
+			person := p.Source.(models.Person)
+			return service(p).FindCandidates(person.Gender, person.Height, limit), nil
+		},
+	})
+
+	personInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "PersonInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"height":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+			"gender":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(genderEnum)},
+			"wantedDates": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	personPayload := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PersonPayload",
+		Fields: graphql.Fields{
+			"person":  &graphql.Field{Type: graphql.NewNonNull(personType)},
+			"matches": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(matchType)))},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"person": &graphql.Field{
+				Type: personType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					person, ok := service(p).GetSinglePerson(p.Args["id"].(string))
+					if !ok {
+						return nil, nil
+					}
+					return *person, nil
+				},
+			},
+			"people": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(personType))),
+				Description: "the most N possible matched single people",
+				Args:        graphql.FieldConfigArgument{"limit": limitArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, err := limitOf(p, 0)
+					if err != nil {
+						return nil, err
+					}
+					return service(p).QuerySinglePeople(limit), nil
+				},
+			},
+			"matches": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(matchType))),
+				Args: graphql.FieldConfigArgument{
+					"personId": &graphql.ArgumentConfig{Type: graphql.ID},
+					"limit":    limitArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, err := limitOf(p, 0)
+					if err != nil {
+						return nil, err
+					}
+					personID, _ := p.Args["personId"].(string)
+					return service(p).QueryMatches(personID, limit), nil
+				},
+			},
+			"candidates": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(personType))),
+				Description: "previews who a new person of this gender and height would be matched with",
+				Args: graphql.FieldConfigArgument{
+					"gender": &graphql.ArgumentConfig{Type: graphql.NewNonNull(genderEnum)},
+					"height": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"limit":  limitArg,
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					limit, err := limitOf(p, 0)
+					if err != nil {
+						return nil, err
+					}
+					return service(p).FindCandidates(p.Args["gender"].(string), p.Args["height"].(int), limit), nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addPerson": &graphql.Field{
+				Type: graphql.NewNonNull(personPayload),
+				Args: graphql.FieldConfigArgument{
+					"input":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(personInput)},
+					"idempotencyKey": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					req := dto.AddPersonRequest{
+						Name:        input["name"].(string),
+						Height:      input["height"].(int),
+						Gender:      input["gender"].(string),
+						WantedDates: input["wantedDates"].(int),
+					}
+					if err := services.ValidateRequest(req); err != nil {
+						return nil, toResolverError(err)
+					}
+
+					key, _ := p.Args["idempotencyKey"].(string)
+					person, matches, err := service(p).AddSinglePersonAndMatchIdempotent(p.Context, key, req)
+					if err != nil {
+						return nil, toResolverError(err)
+					}
+					return payload(*person, matches), nil
+				},
+			},
+			"updatePerson": &graphql.Field{
+				Type: graphql.NewNonNull(personPayload),
+				Args: graphql.FieldConfigArgument{
+					"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input":           &graphql.ArgumentConfig{Type: graphql.NewNonNull(personInput)},
+					"expectedVersion": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["input"].(map[string]interface{})
+					req := dto.UpdatePersonRequest{
+						Name:        input["name"].(string),
+						Height:      input["height"].(int),
+						Gender:      input["gender"].(string),
+						WantedDates: input["wantedDates"].(int),
+					}
+					if err := services.ValidateRequest(req); err != nil {
+						return nil, toResolverError(err)
+					}
+
+					person, matches, err := service(p).UpdateSinglePerson(p.Context, p.Args["id"].(string), req, int64(p.Args["expectedVersion"].(int)))
+					if err != nil {
+						return nil, toResolverError(err)
+					}
+					return payload(*person, matches), nil
+				},
+			},
+			"removePerson": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id":              &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"expectedVersion": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					err := service(p).RemoveSinglePersonIfMatch(p.Context, p.Args["id"].(string), int64(p.Args["expectedVersion"].(int)))
+					if err != nil {
+						return nil, toResolverError(err)
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+	if config.writeCheck != nil {
+		for _, field := range mutation.Fields() {
+			field.Resolve = checkWrite(config.writeCheck, field.Resolve)
+		}
+	}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+func checkWrite(check func() error, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if err := check(); err != nil {
+			return nil, toResolverError(err)
+		}
+		return resolve(p)
+	}
+}
+
+func personField(fieldType graphql.Output, get func(models.Person) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(fieldType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(models.Person)), nil
+		},
+	}
+}
+
+func matchField(fieldType graphql.Output, get func(models.Match) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: graphql.NewNonNull(fieldType),
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(models.Match)), nil
+		},
+	}
+}
+
+func payload(person models.Person, matches []models.Match) map[string]interface{} {
+	if matches == nil {
+		matches = []models.Match{}
+	}
+	return map[string]interface{}{
+		"person":  person,
+		"matches": matches,
+	}
+}
@@ -0,0 +1,390 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/api/matchingpb"
+	"matching_system/internal/audit"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/pkg/logger"
+	"slices"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// streamBuffer is how many matches a stream may fall behind before it is cut off
+const streamBuffer = 256
+
+// Pools resolves the pool a call names
+type Pools interface {
+	// Get returns a pool and its match service, or services.ErrPoolNotFound
+	Get(name string) (models.Pool, services.MatchService, error)
+}
+
+// Server implements matchingpb.MatchingServiceServer on top of the match services of registry, each
+// call working on the pool it names. Matches made while a client streams them come from broker,
+// which carries the events of every pool.
+type Server struct {
+	matchingpb.UnimplementedMatchingServiceServer
+	registry Pools
+	broker   *events.Broker
+}
+
+func NewServer(registry Pools, broker *events.Broker) *Server {
+	return &Server{
+		registry: registry,
+		broker:   broker,
+	}
+}
+
+// Register creates a gRPC server exposing the matching service, see NewServer
+func Register(registry Pools, broker *events.Broker, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	matchingpb.RegisterMatchingServiceServer(server, NewServer(registry, broker))
+	return server
+}
+
+// pool returns the name and match service of the pool a call names, the default pool when it
+// names none
+func (s *Server) pool(name string) (string, services.MatchService, error) {
+	if name == "" {
+		name = pools.DefaultPool
+	}
+	_, service, err := s.registry.Get(name)
+	if err != nil {
+		return "", nil, toStatus(err)
+	}
+	return name, service, nil
+}
+
+// writeMethods are the calls that change the pool
+var writeMethods = map[string]bool{
+	matchingpb.MatchingService_AddPerson_FullMethodName:    true,
+	matchingpb.MatchingService_RemovePerson_FullMethodName: true,
+	matchingpb.MatchingService_UpdatePerson_FullMethodName: true,
+}
+
+// WriteCheck is a unary interceptor that rejects calls changing the pool with the error of check,
+// such as on a read-only follower
+func WriteCheck(check func() error) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if writeMethods[info.FullMethod] {
+			if err := check(); err != nil {
+				return nil, toStatus(err)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// EndStreams is a stream interceptor that ends the calls' contexts once ctx is done, so that streams
+// open when shutdown starts do not hold up a graceful stop
+func EndStreams(ctx context.Context) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		streamCtx, cancel := context.WithCancel(ss.Context())
+		defer cancel()
+		stop := context.AfterFunc(ctx, cancel)
+		defer stop()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: streamCtx})
+	}
+}
+
+// contextStream is a server stream with another context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// maxRequestIDLength keeps request IDs from callers short enough to store
+const maxRequestIDLength = 128
+
+// AuditSource is a unary interceptor that has the writes of a call audited as made by the actor in
+// its x-actor metadata, with the ID in its x-request-id metadata or a new one. The call logs with
+// the ID too, as HTTP requests do, see logger.FromContext.
+func AuditSource() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		requestID := firstValue(md, "x-request-id")
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		source := audit.NewSource(firstValue(md, audit.ActorHeader), requestID)
+		ctx = logger.NewContext(ctx, logger.FromContext(ctx).With("request_id", requestID))
+		return handler(audit.NewContext(ctx, source), req)
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (s *Server) AddPerson(ctx context.Context, req *matchingpb.AddPersonRequest) (*matchingpb.AddPersonResponse, error) {
+	addReq := dto.AddPersonRequest{
+		Name:        req.GetName(),
+		Height:      int(req.GetHeight()),
+		Gender:      fromGender(req.GetGender()),
+		WantedDates: int(req.GetWantedDates()),
+	}
+	if err := services.ValidateRequest(addReq); err != nil {
+		return nil, toStatus(err)
+	}
+
+	_, service, err := s.pool(req.GetPool())
+	if err != nil {
+		return nil, err
+	}
+
+	person, matches, err := service.AddSinglePersonAndMatchIdempotent(ctx, req.GetIdempotencyKey(), addReq)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &matchingpb.AddPersonResponse{
+		Person:  toPerson(*person),
+		Matches: toMatches(matches),
+	}, nil
+}
+
+func (s *Server) RemovePerson(ctx context.Context, req *matchingpb.RemovePersonRequest) (*matchingpb.RemovePersonResponse, error) {
+	_, service, err := s.pool(req.GetPool())
+	if err != nil {
+		return nil, err
+	}
+	if err := service.RemoveSinglePersonIfMatch(ctx, req.GetId(), req.GetExpectedVersion()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &matchingpb.RemovePersonResponse{}, nil
+}
+
+func (s *Server) UpdatePerson(ctx context.Context, req *matchingpb.UpdatePersonRequest) (*matchingpb.UpdatePersonResponse, error) {
+	updateReq := dto.UpdatePersonRequest{
+		Name:        req.GetName(),
+		Height:      int(req.GetHeight()),
+		Gender:      fromGender(req.GetGender()),
+		WantedDates: int(req.GetWantedDates()),
+	}
+	if err := services.ValidateRequest(updateReq); err != nil {
+		return nil, toStatus(err)
+	}
+
+	_, service, err := s.pool(req.GetPool())
+	if err != nil {
+		return nil, err
+	}
+
+	person, matches, err := service.UpdateSinglePerson(ctx, req.GetId(), updateReq, req.GetExpectedVersion())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &matchingpb.UpdatePersonResponse{
+		Person:  toPerson(*person),
+		Matches: toMatches(matches),
+	}, nil
+}
+
+func (s *Server) GetPerson(ctx context.Context, req *matchingpb.GetPersonRequest) (*matchingpb.Person, error) {
+	_, service, err := s.pool(req.GetPool())
+	if err != nil {
+		return nil, err
+	}
+	person, ok := service.GetSinglePerson(req.GetId())
+	if !ok {
+		return nil, toStatus(services.ErrPersonNotFound)
+	}
+	return toPerson(*person), nil
+}
+
+func (s *Server) QueryPeople(ctx context.Context, req *matchingpb.QueryPeopleRequest) (*matchingpb.QueryPeopleResponse, error) {
+	if req.GetLimit() < 0 {
+		return nil, toStatus(services.NewValidationError(services.FieldError{Field: "limit", Message: "must be a non-negative integer"}))
+	}
+
+	_, service, err := s.pool(req.GetPool())
+	if err != nil {
+		return nil, err
+	}
+
+	people := service.QuerySinglePeople(int(req.GetLimit()))
+
+	resp := &matchingpb.QueryPeopleResponse{People: make([]*matchingpb.Person, 0, len(people))}
+	for _, person := range people {
+		resp.People = append(resp.People, toPerson(person))
+	}
+	return resp, nil
+}
+
+func (s *Server) StreamMatches(req *matchingpb.StreamMatchesRequest, stream matchingpb.MatchingService_StreamMatchesServer) error {
+	if req.GetLimit() < 0 {
+		return toStatus(services.NewValidationError(services.FieldError{Field: "limit", Message: "must be a non-negative integer"}))
+	}
+	pool, service, err := s.pool(req.GetPool())
+	if err != nil {
+		return err
+	}
+
+	// subscribing first loses no match made while the history is read. The events published before
+	// it is read, up to last, are of matches the history holds or is too short for. Later ones can
+	// still repeat its newest matches, whose events were not published yet when it was read: they
+	// come first and in order, so pending holds the history only until the first later event.
+	sub := s.broker.Subscribe(streamBuffer)
+	defer sub.Close()
+	last := s.broker.LastID()
+
+	history := service.QueryMatches(req.GetPersonId(), int(req.GetLimit()))
+	pending := make([]matchKey, 0, len(history))
+	for _, match := range history {
+		if err := stream.Send(toMatch(match)); err != nil {
+			return err
+		}
+		pending = append(pending, keyOf(match))
+	}
+	// the stream can outlive the history by far
+	history = nil
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "the stream fell too far behind, open it again")
+			}
+			if event.Type != events.Matched || event.Pool != pool || event.Match == nil {
+				continue
+			}
+			if req.GetPersonId() != "" && !event.Involves(req.GetPersonId()) {
+				continue
+			}
+			if i := slices.Index(pending, keyOf(*event.Match)); i >= 0 {
+				// streamed with the history, which is newest first: the older ones cannot come any more
+				pending = pending[:i]
+				continue
+			}
+			if event.ID <= last {
+				continue
+			}
+			pending = nil
+			if err := stream.Send(toMatch(*event.Match)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			// the client cancelled, or the server is shutting down and the client should open it again
+			return status.Error(codes.Unavailable, "the stream was ended")
+		}
+	}
+}
+
+// matchKey tells a match apart from the others of the pool
+type matchKey struct {
+	person1, person2 string
+	matchedAt        int64
+}
+
+func keyOf(match models.Match) matchKey {
+	return matchKey{person1: match.Person1.ID, person2: match.Person2.ID, matchedAt: match.MatchedAt.UnixNano()}
+}
+
+var codeByErrorCode = map[services.ErrorCode]codes.Code{
+	services.CodeValidationFailed:       codes.InvalidArgument,
+	services.CodeMalformedRequest:       codes.InvalidArgument,
+	services.CodePersonNotFound:         codes.NotFound,
+	services.CodePoolNotFound:           codes.NotFound,
+	services.CodeVersionMismatch:        codes.FailedPrecondition,
+	services.CodeIdempotencyKeyMismatch: codes.InvalidArgument,
+	services.CodeReadOnly:               codes.Unavailable,
+	services.CodeNoLeader:               codes.Unavailable,
+	services.CodeShardUnavailable:       codes.Unavailable,
+}
+
+// toStatus maps a typed service error onto a gRPC status, with field violations as BadRequest details
+func toStatus(err error) error {
+	var serviceErr *services.Error
+	if !errors.As(err, &serviceErr) {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	code, ok := codeByErrorCode[serviceErr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, serviceErr.Message)
+
+	if len(serviceErr.Fields) > 0 {
+		badRequest := &errdetails.BadRequest{}
+		for _, field := range serviceErr.Fields {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field.Field,
+				Description: field.Message,
+			})
+		}
+		if detailed, detailErr := st.WithDetails(badRequest); detailErr == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+func fromGender(gender matchingpb.Gender) string {
+	switch gender {
+	case matchingpb.Gender_GENDER_MALE:
+		return "male"
+	case matchingpb.Gender_GENDER_FEMALE:
+		return "female"
+	default:
+		return ""
+	}
+}
+
+func toGender(gender string) matchingpb.Gender {
+	switch gender {
+	case "male":
+		return matchingpb.Gender_GENDER_MALE
+	case "female":
+		return matchingpb.Gender_GENDER_FEMALE
+	default:
+		return matchingpb.Gender_GENDER_UNSPECIFIED
+	}
+}
+
+func toPerson(person models.Person) *matchingpb.Person {
+	return &matchingpb.Person{
+		Id:          person.ID,
+		Name:        person.Name,
+		Height:      int32(person.Height),
+		Gender:      toGender(person.Gender),
+		WantedDates: int32(person.WantedDates),
+		Version:     person.Version,
+	}
+}
+
+func toMatch(match models.Match) *matchingpb.Match {
+	return &matchingpb.Match{
+		Person1:   toPerson(match.Person1),
+		Person2:   toPerson(match.Person2),
+		MatchedAt: timestamppb.New(match.MatchedAt),
+	}
+}
+
+func toMatches(matches []models.Match) []*matchingpb.Match {
+	result := make([]*matchingpb.Match, 0, len(matches))
+	for _, match := range matches {
+		result = append(result, toMatch(match))
+	}
+	return result
+}
@@ -0,0 +1,303 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"matching_system/internal/api/matchingpb"
+	"matching_system/internal/audit"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/pkg/logger"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupTestClient(t *testing.T, opts ...grpc.ServerOption) matchingpb.MatchingServiceClient {
+	client, _, _ := setupTestClientWithPools(t, opts...)
+	return client
+}
+
+// setupTestClientWithPools also returns the pools the server serves and the broker it streams
+// matches from
+func setupTestClientWithPools(t *testing.T, opts ...grpc.ServerOption) (matchingpb.MatchingServiceClient, *pools.Registry, *events.Broker) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithEvents(broker)))
+	require.NoError(t, err)
+	listener := bufconn.Listen(1024 * 1024)
+	server := Register(registry, broker, opts...)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return matchingpb.NewMatchingServiceClient(conn), registry, broker
+}
+
+func TestServer_AddPersonAndMatch(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	alice, err := client.AddPerson(ctx, &matchingpb.AddPersonRequest{
+		Name: "Alice", Height: 160, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 2,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, alice.GetPerson().GetId(), "the ID should be generated")
+	assert.Empty(t, alice.GetMatches(), "Alice should not match anyone yet")
+
+	bob, err := client.AddPerson(ctx, &matchingpb.AddPersonRequest{
+		Name: "Bob", Height: 180, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1,
+	})
+	require.NoError(t, err)
+	require.Len(t, bob.GetMatches(), 1, "Bob should match Alice")
+	assert.Equal(t, alice.GetPerson().GetId(), bob.GetMatches()[0].GetPerson2().GetId())
+
+	person, err := client.GetPerson(ctx, &matchingpb.GetPersonRequest{Id: alice.GetPerson().GetId()})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), person.GetWantedDates(), "Alice should have one date left")
+	assert.Equal(t, int64(2), person.GetVersion(), "the match should bump Alice's version")
+
+	people, err := client.QueryPeople(ctx, &matchingpb.QueryPeopleRequest{Limit: 0})
+	require.NoError(t, err)
+	assert.Len(t, people.GetPeople(), 1, "only Alice should remain")
+}
+
+func TestServer_AddPerson_Validation(t *testing.T) {
+	client := setupTestClient(t)
+
+	_, err := client.AddPerson(context.Background(), &matchingpb.AddPersonRequest{
+		Name: "Alice", Height: 50, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 2,
+	})
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	assert.Equal(t, "height", badRequest.GetFieldViolations()[0].GetField())
+}
+
+func TestServer_RemovePerson(t *testing.T) {
+	client := setupTestClient(t)
+	ctx := context.Background()
+
+	alice, err := client.AddPerson(ctx, &matchingpb.AddPersonRequest{
+		Name: "Alice", Height: 160, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 2,
+	})
+	require.NoError(t, err)
+
+	// stale version is rejected
+	_, err = client.RemovePerson(ctx, &matchingpb.RemovePersonRequest{Id: alice.GetPerson().GetId(), ExpectedVersion: 5})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.RemovePerson(ctx, &matchingpb.RemovePersonRequest{Id: alice.GetPerson().GetId()})
+	assert.NoError(t, err)
+
+	_, err = client.GetPerson(ctx, &matchingpb.GetPersonRequest{Id: alice.GetPerson().GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_StreamMatches(t *testing.T) {
+	client, _, broker := setupTestClientWithPools(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, req := range []*matchingpb.AddPersonRequest{
+		{Name: "Alice", Height: 160, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 2},
+		{Name: "Bob", Height: 180, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1},
+		{Name: "David", Height: 175, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1},
+	} {
+		_, err := client.AddPerson(ctx, req)
+		require.NoError(t, err)
+	}
+
+	stream, err := client.StreamMatches(ctx, &matchingpb.StreamMatchesRequest{})
+	require.NoError(t, err)
+
+	var names []string
+	for i := 0; i < 2; i++ {
+		match, err := stream.Recv()
+		require.NoError(t, err)
+		names = append(names, match.GetPerson1().GetName())
+	}
+	assert.Equal(t, []string{"David", "Bob"}, names, "the history should stream newest first")
+
+	// then the matches made while the stream is open, of this pool only
+	broker.Publish(events.Event{Type: events.Matched, Pool: "eu", Match: &models.Match{Person1: models.Person{Name: "Mallory"}}})
+	_, err = client.AddPerson(ctx, &matchingpb.AddPersonRequest{Name: "Carol", Height: 165, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 1})
+	require.NoError(t, err)
+	_, err = client.AddPerson(ctx, &matchingpb.AddPersonRequest{Name: "Eve", Height: 185, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1})
+	require.NoError(t, err)
+
+	match, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Eve", match.GetPerson1().GetName())
+	assert.Equal(t, "Carol", match.GetPerson2().GetName())
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestServer_StreamMatchesSkipsLateEventsOfTheHistory(t *testing.T) {
+	client, _, broker := setupTestClientWithPools(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, req := range []*matchingpb.AddPersonRequest{
+		{Name: "Alice", Height: 160, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 1},
+		{Name: "Bob", Height: 180, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1},
+	} {
+		_, err := client.AddPerson(ctx, req)
+		require.NoError(t, err)
+	}
+
+	stream, err := client.StreamMatches(ctx, &matchingpb.StreamMatchesRequest{})
+	require.NoError(t, err)
+	streamed, err := stream.Recv()
+	require.NoError(t, err)
+
+	// the event of a match the history held arrives once the stream is open, as when the match was
+	// made while the history was read
+	broker.Publish(events.Event{Type: events.Matched, Pool: "default", Match: &models.Match{
+		Person1:   models.Person{ID: streamed.GetPerson1().GetId()},
+		Person2:   models.Person{ID: streamed.GetPerson2().GetId()},
+		MatchedAt: streamed.GetMatchedAt().AsTime(),
+	}})
+	_, err = client.AddPerson(ctx, &matchingpb.AddPersonRequest{Name: "Carol", Height: 165, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 1})
+	require.NoError(t, err)
+	_, err = client.AddPerson(ctx, &matchingpb.AddPersonRequest{Name: "Eve", Height: 185, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1})
+	require.NoError(t, err)
+
+	match, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Eve", match.GetPerson1().GetName(), "the match streamed with the history is not sent again")
+}
+
+func TestServer_ShardUnavailable(t *testing.T) {
+	assert.Equal(t, codes.Unavailable, status.Code(toStatus(services.ErrShardUnavailable)))
+}
+
+func TestServer_Pools(t *testing.T) {
+	client, registry, _ := setupTestClientWithPools(t)
+	_, err := registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.StreamMatches(ctx, &matchingpb.StreamMatchesRequest{Pool: "eu"})
+	require.NoError(t, err)
+
+	_, err = client.AddPerson(ctx, &matchingpb.AddPersonRequest{Name: "Alice", Height: 160, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 1})
+	require.NoError(t, err)
+	added, err := client.AddPerson(ctx, &matchingpb.AddPersonRequest{Pool: "eu", Name: "Bob", Height: 180, Gender: matchingpb.Gender_GENDER_MALE, WantedDates: 1})
+	require.NoError(t, err)
+	assert.Empty(t, added.GetMatches(), "Alice is in the default pool")
+	_, err = client.AddPerson(ctx, &matchingpb.AddPersonRequest{Pool: "eu", Name: "Carol", Height: 165, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 1})
+	require.NoError(t, err)
+
+	match, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "Carol", match.GetPerson1().GetName())
+
+	people, err := client.QueryPeople(ctx, &matchingpb.QueryPeopleRequest{})
+	require.NoError(t, err)
+	require.Len(t, people.GetPeople(), 1)
+	assert.Equal(t, "Alice", people.GetPeople()[0].GetName())
+
+	_, err = client.QueryPeople(ctx, &matchingpb.QueryPeopleRequest{Pool: "us"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.GetPerson(ctx, &matchingpb.GetPersonRequest{Pool: "eu", Id: added.GetPerson().GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err), "Bob was matched away")
+}
+
+func TestEndStreams(t *testing.T) {
+	shutdown, endStreams := context.WithCancel(context.Background())
+	client := setupTestClient(t, grpc.StreamInterceptor(EndStreams(shutdown)))
+
+	stream, err := client.StreamMatches(context.Background(), &matchingpb.StreamMatchesRequest{})
+	require.NoError(t, err)
+	received := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		received <- err
+	}()
+
+	endStreams()
+	select {
+	case err := <-received:
+		assert.Equal(t, codes.Unavailable, status.Code(err), "the client should open the stream again")
+	case <-time.After(time.Second):
+		t.Fatal("the stream should end once shutdown starts")
+	}
+}
+
+func TestServer_WriteCheck(t *testing.T) {
+	client := setupTestClient(t, grpc.UnaryInterceptor(WriteCheck(func() error { return services.ErrReadOnly })))
+	ctx := context.Background()
+
+	_, err := client.AddPerson(ctx, &matchingpb.AddPersonRequest{
+		Name: "Alice", Height: 160, Gender: matchingpb.Gender_GENDER_FEMALE, WantedDates: 2,
+	})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = client.RemovePerson(ctx, &matchingpb.RemovePersonRequest{Id: "missing"})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	// reads are still served
+	resp, err := client.QueryPeople(ctx, &matchingpb.QueryPeopleRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetPeople())
+}
+
+func TestServer_AuditSource(t *testing.T) {
+	var sources []audit.Source
+	seeSource := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		sources = append(sources, audit.FromContext(ctx))
+		return handler(ctx, req)
+	}
+	client := setupTestClient(t, grpc.ChainUnaryInterceptor(AuditSource(), seeSource))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-actor", "alice", "x-request-id", "req-42")
+	_, err := client.RemovePerson(ctx, &matchingpb.RemovePersonRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = client.RemovePerson(context.Background(), &matchingpb.RemovePersonRequest{Id: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	require.Len(t, sources, 2)
+	assert.Equal(t, audit.Source{Actor: "alice", RequestID: "req-42"}, sources[0])
+	assert.Equal(t, audit.AnonymousActor, sources[1].Actor)
+	assert.NotEmpty(t, sources[1].RequestID, "calls without an ID get one")
+}
+
+func TestAuditSource_LogsWithTheRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	ctx := logger.NewContext(context.Background(), logger.New(logger.WithOutput(&logs)))
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-request-id", "req-42"))
+
+	_, err := AuditSource()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		logger.FromContext(ctx).Info("Handled")
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "request_id=req-42")
+}
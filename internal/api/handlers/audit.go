@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/services"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultAuditLimit and maxAuditLimit bound the pages of the audit log
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// AuditLog holds the writes made on request, see audit.Log
+type AuditLog interface {
+	Query(q audit.Query) ([]audit.Entry, uint64, error)
+}
+
+type AuditHandler struct {
+	log AuditLog
+}
+
+func NewAuditHandler(log AuditLog) *AuditHandler {
+	return &AuditHandler{
+		log: log,
+	}
+}
+
+// QueryAudit godoc
+// @Summary Query the audit log
+// @Description List the adds, updates, removals and imports made on request, newest first, with who asked for them and the person before and after. A full page ends with next, to pass as before for the following page.
+// @Tags admin
+// @Produce json
+// @Param pool query string false "Only writes to this pool"
+// @Param person_id query string false "Only writes to this person"
+// @Param actor query string false "Only writes by this actor"
+// @Param operation query string false "Only this kind of write" Enums(add, update, remove, import, delete_pool)
+// @Param request_id query string false "Only the writes of this request"
+// @Param since query string false "Only writes at or after this RFC 3339 time"
+// @Param until query string false "Only writes before this RFC 3339 time"
+// @Param before query int false "Only entries older than this ID, the next of the previous page"
+// @Param limit query int false "Page size, 100 by default and at most 1000"
+// @Success 200 {object} dto.QueryAuditResponse
+// @Failure 400 {object} dto.Problem
+// @Router /v1/admin/audit [get]
+func (h *AuditHandler) QueryAudit(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	entries, next, err := h.log.Query(query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	c.JSON(http.StatusOK, dto.QueryAuditResponse{
+		Entries: entries,
+		Next:    next,
+		Message: "audit entries queried successfully",
+	})
+}
+
+// parseAuditQuery reads the filters of a query, reporting every invalid one at once
+func parseAuditQuery(c *gin.Context) (audit.Query, error) {
+	query := audit.Query{
+		Pool:      c.Query("pool"),
+		PersonID:  c.Query("person_id"),
+		Actor:     c.Query("actor"),
+		Operation: audit.Op(c.Query("operation")),
+		RequestID: c.Query("request_id"),
+		Limit:     defaultAuditLimit,
+	}
+	var fields []services.FieldError
+	if query.Operation != "" && !slices.Contains(audit.Ops, query.Operation) {
+		fields = append(fields, services.FieldError{Field: "operation", Message: "must be one of add, update, remove, import, delete_pool"})
+	}
+	for _, bound := range []struct {
+		field string
+		time  *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		if value := c.Query(bound.field); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				fields = append(fields, services.FieldError{Field: bound.field, Message: "must be an RFC 3339 time"})
+			}
+			*bound.time = t
+		}
+	}
+	if value := c.Query("before"); value != "" {
+		before, err := strconv.ParseUint(value, 10, 64)
+		if err != nil || before == 0 {
+			fields = append(fields, services.FieldError{Field: "before", Message: "must be a positive integer"})
+		}
+		query.Before = before
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			fields = append(fields, services.FieldError{Field: "limit", Message: "must be an integer from 1 to " + strconv.Itoa(maxAuditLimit)})
+		}
+		query.Limit = limit
+	}
+
+	if len(fields) > 0 {
+		return audit.Query{}, services.NewValidationError(fields...)
+	}
+	return query, nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditLog struct {
+	mock.Mock
+}
+
+func (m *MockAuditLog) Query(q audit.Query) ([]audit.Entry, uint64, error) {
+	args := m.Called(q)
+	entries, _ := args.Get(0).([]audit.Entry)
+	return entries, args.Get(1).(uint64), args.Error(2)
+}
+
+func TestQueryAudit(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockLog := new(MockAuditLog)
+	handler := NewAuditHandler(mockLog)
+
+	router.GET("/admin/audit", handler.QueryAudit)
+
+	mockLog.On("Query", audit.Query{
+		Pool:      "eu",
+		Actor:     "alice",
+		Operation: audit.OpUpdate,
+		Since:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Before:    10,
+		Limit:     2,
+	}).Return([]audit.Entry{{ID: 9, Operation: audit.OpUpdate}, {ID: 7, Operation: audit.OpUpdate}}, uint64(7), nil)
+
+	// Execute request
+	req, _ := http.NewRequest("GET", "/admin/audit?pool=eu&actor=alice&operation=update&since=2024-01-01T00:00:00Z&before=10&limit=2", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.QueryAuditResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Len(t, response.Entries, 2)
+	assert.Equal(t, uint64(7), response.Next)
+
+	mockLog.AssertExpectations(t)
+}
+
+func TestQueryAudit_Defaults(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockLog := new(MockAuditLog)
+	handler := NewAuditHandler(mockLog)
+
+	router.GET("/admin/audit", handler.QueryAudit)
+
+	mockLog.On("Query", audit.Query{Limit: defaultAuditLimit}).Return(nil, uint64(0), nil)
+
+	// Execute request
+	req, _ := http.NewRequest("GET", "/admin/audit", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"entries":[],"message":"audit entries queried successfully"}`, w.Body.String())
+
+	mockLog.AssertExpectations(t)
+}
+
+func TestQueryAudit_InvalidFilters(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockLog := new(MockAuditLog)
+	handler := NewAuditHandler(mockLog)
+
+	router.GET("/admin/audit", handler.QueryAudit)
+
+	// Execute request
+	req, _ := http.NewRequest("GET", "/admin/audit?operation=delete&since=yesterday&before=0&limit=5000", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var problem dto.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	var fields []string
+	for _, field := range problem.Errors {
+		fields = append(fields, field.Field)
+	}
+	assert.Equal(t, []string{"operation", "since", "before", "limit"}, fields)
+
+	mockLog.AssertNotCalled(t, "Query")
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"matching_system/internal/cluster"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ClusterHandler struct {
+	node *cluster.Node
+}
+
+func NewClusterHandler(node *cluster.Node) *ClusterHandler {
+	return &ClusterHandler{
+		node: node,
+	}
+}
+
+// GetStatus godoc
+// @Summary Get the cluster status
+// @Description This node's raft state, the leader it knows of, and how far it has applied and
+// @Description compacted the log
+// @Tags cluster
+// @Produce json
+// @Success 200 {object} dto.ClusterStatus
+// @Router /v1/cluster [get]
+func (h *ClusterHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.node.Status())
+}
+
+// Apply godoc
+// @Summary Commit a forwarded write
+// @Description Used by the other nodes to forward writes to the leader. The write is committed only if
+// @Description this node leads; the result, including a failure to commit, is returned as 200.
+// @Tags cluster
+// @Accept json
+// @Produce json
+// @Param command body cluster.Command true "Command"
+// @Success 200 {object} cluster.Result
+// @Failure 400 {object} dto.Problem
+// @Router /v1/cluster/apply [post]
+func (h *ClusterHandler) Apply(c *gin.Context) {
+	var cmd cluster.Command
+	if err := c.ShouldBindJSON(&cmd); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+	c.JSON(http.StatusOK, h.node.Lead(cmd))
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		ok      bool
+	}{
+		{"", 0, true},
+		{"*", 0, true},
+		{`"3"`, 3, true},
+		{`W/"3"`, 0, false},
+		{`3`, 0, false},
+		{`"abc"`, 0, false},
+		{`"1", "2"`, 0, false},
+	}
+
+	for _, tt := range tests {
+		version, ok := parseIfMatch(tt.header)
+		assert.Equal(t, tt.version, version, "version for %q", tt.header)
+		assert.Equal(t, tt.ok, ok, "ok for %q", tt.header)
+	}
+}
+
+func TestEtag(t *testing.T) {
+	assert.Equal(t, `"7"`, etag(7))
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"matching_system/internal/events"
+	"matching_system/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// eventStreamBuffer is how many events a stream may fall behind before it is disconnected
+	eventStreamBuffer        = 256
+	defaultKeepAlivePeriod   = 15 * time.Second
+	eventStreamRetryInterval = 3 * time.Second
+)
+
+type EventStreamHandler struct {
+	broker *events.Broker
+	// keepAlivePeriod is how often a comment is sent so idle proxies keep the connection open
+	keepAlivePeriod time.Duration
+}
+
+func NewEventStreamHandler(broker *events.Broker) *EventStreamHandler {
+	return &EventStreamHandler{
+		broker:          broker,
+		keepAlivePeriod: defaultKeepAlivePeriod,
+	}
+}
+
+// StreamEvents godoc
+// @Summary Stream pool events
+// @Description Server-Sent Events stream of every person_added, person_updated, matched, person_removed
+// @Description and dates_exhausted event. Each event carries its ID, so a reconnecting client resumes
+// @Description with Last-Event-ID. Only recent events are kept; if the requested ID is too old, a
+// @Description reset event is sent before the oldest events still available.
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "Resume after this event ID"
+// @Param last_event_id query int false "Same as Last-Event-ID, for clients that cannot set headers"
+// @Param pool query string false "Only stream the events of this pool"
+// @Success 200 {object} events.Event
+// @Failure 400 {object} dto.Problem
+// @Router /events [get]
+func (h *EventStreamHandler) StreamEvents(c *gin.Context) {
+	lastID, resume, ok := parseLastEventID(c)
+	if !ok {
+		c.Error(services.NewMalformedRequestError("Last-Event-ID must be a non-negative integer"))
+		return
+	}
+	pool := c.Query("pool")
+
+	// a fresh client only gets new events, a resuming one first gets what it missed
+	var (
+		sub      *events.Subscription
+		missed   []events.Event
+		complete = true
+	)
+	if resume {
+		sub, missed, complete = h.broker.SubscribeFrom(lastID, eventStreamBuffer)
+	} else {
+		sub = h.broker.Subscribe(eventStreamBuffer)
+	}
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventStreamRetryInterval.Milliseconds())
+	if !complete {
+		fmt.Fprintf(c.Writer, "event: reset\ndata: {\"last_event_id\":%d}\n\n", lastID)
+	}
+	for _, event := range missed {
+		if pool != "" && event.Pool != pool {
+			continue
+		}
+		if writeEvent(c, event) != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(h.keepAlivePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// dropped for falling behind; the client reconnects and resumes with Last-Event-ID
+				return
+			}
+			if pool != "" && event.Pool != pool {
+				continue
+			}
+			if writeEvent(c, event) != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// parseLastEventID reads the resume position from the Last-Event-ID header, or the last_event_id
+// query parameter for clients that cannot set headers. resume is false when neither is given.
+func parseLastEventID(c *gin.Context) (id uint64, resume bool, ok bool) {
+	value := c.GetHeader("Last-Event-ID")
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false, true
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	return id, true, err == nil
+}
+
+func writeEvent(c *gin.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/events"
+	"matching_system/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sseEvent struct {
+	id, event, data string
+}
+
+func setupEventStreamServer(t *testing.T, handler *EventStreamHandler) string {
+	router := setupTestRouter()
+	router.GET("/events", handler.StreamEvents)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL + "/events"
+}
+
+// openEventStream connects and returns a channel of parsed events, skipping comments and retry hints
+func openEventStream(t *testing.T, url, lastEventID string) <-chan sseEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	stream := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(stream)
+
+		var current sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if current.event != "" {
+					stream <- current
+				}
+				current = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				current.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				current.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				current.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return stream
+}
+
+func nextEvent(t *testing.T, stream <-chan sseEvent) sseEvent {
+	select {
+	case event, ok := <-stream:
+		require.True(t, ok, "the stream ended early")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+		return sseEvent{}
+	}
+}
+
+func TestStreamEvents_LiveEvents(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	// events published before connecting are not replayed to a fresh client
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+
+	stream := openEventStream(t, setupEventStreamServer(t, NewEventStreamHandler(broker)), "")
+
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	event := nextEvent(t, stream)
+	assert.Equal(t, "person_added", event.event)
+	assert.Equal(t, "2", event.id)
+	assert.Contains(t, event.data, `"name":"Bob"`)
+
+	event = nextEvent(t, stream)
+	assert.Equal(t, "matched", event.event)
+	assert.Equal(t, "3", event.id)
+	assert.Equal(t, "dates_exhausted", nextEvent(t, stream).event)
+}
+
+func TestStreamEvents_ResumeWithLastEventID(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	matchService.RemoveSinglePerson(alice.ID)
+
+	stream := openEventStream(t, setupEventStreamServer(t, NewEventStreamHandler(broker)), "1")
+
+	event := nextEvent(t, stream)
+	assert.Equal(t, "person_removed", event.event)
+	assert.Equal(t, "2", event.id)
+}
+
+func TestStreamEvents_ResumeTooOld(t *testing.T) {
+	broker := events.NewBroker(1)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	matchService.RemoveSinglePerson(alice.ID)
+
+	stream := openEventStream(t, setupEventStreamServer(t, NewEventStreamHandler(broker)), "0")
+
+	assert.Equal(t, "reset", nextEvent(t, stream).event)
+	assert.Equal(t, "2", nextEvent(t, stream).id)
+}
+
+func TestStreamEvents_ResumeFromBeforeARestart(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+
+	// an ID this server never gave out
+	stream := openEventStream(t, setupEventStreamServer(t, NewEventStreamHandler(broker)), "99")
+
+	assert.Equal(t, "reset", nextEvent(t, stream).event)
+	assert.Equal(t, "1", nextEvent(t, stream).id)
+}
+
+func TestStreamEvents_InvalidLastEventID(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/events", NewEventStreamHandler(events.NewBroker(events.DefaultHistorySize)).StreamEvents)
+
+	req, _ := http.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"fmt"
 	"matching_system/internal/api/dto"
 	"matching_system/internal/services"
 	"net/http"
@@ -14,12 +13,22 @@ type MatchHandler struct {
 	matchService services.MatchService
 }
 
-func NewMatchHandler() *MatchHandler {
+// idempotencyKeyHeader lets clients safely retry add requests
+const idempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+func NewMatchHandler(matchService services.MatchService) *MatchHandler {
 	return &MatchHandler{
-		matchService: services.NewMatchService(),
+		matchService: matchService,
 	}
 }
 
+// service is the match service of the request's pool
+func (h *MatchHandler) service(c *gin.Context) services.MatchService {
+	return matchServiceFor(c, h.matchService)
+}
+
 // AddSinglePersonAndMatch godoc
 // @Summary Add a single person and match
 // @Description Add a single person and match
@@ -27,17 +36,36 @@ func NewMatchHandler() *MatchHandler {
 // @Accept json
 // @Produce json
 // @Param person body dto.AddPersonRequest true "Person"
+// @Param Idempotency-Key header string false "Key used to replay the original response on retries"
 // @Success 201 {object} dto.AddPersonResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 422 {object} dto.Problem
+// @Router /v1/people [post]
 // @Router /add-single-person-and-match [post]
 func (h *MatchHandler) AddSinglePersonAndMatch(c *gin.Context) {
 	var req dto.AddPersonRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(bindingError(err))
 		return
 	}
 
-	person, matches := h.matchService.AddSinglePersonAndMatch(req)
+	key := c.GetHeader(idempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		c.Error(services.NewValidationError(services.FieldError{
+			Field:   idempotencyKeyHeader,
+			Message: "must be at most " + strconv.Itoa(maxIdempotencyKeyLength) + " characters",
+		}))
+		return
+	}
+
+	// without a key this is a plain add, which a clustered pool can still fail
+	person, matches, err := h.service(c).AddSinglePersonAndMatchIdempotent(c.Request.Context(), key, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
 
+	c.Header(etagHeader, etag(person.Version))
 	c.JSON(http.StatusCreated, dto.AddPersonResponse{
 		Person:  *person,
 		Matches: matches,
@@ -52,22 +80,27 @@ func (h *MatchHandler) AddSinglePersonAndMatch(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Person ID"
+// @Param If-Match header string false "Only remove the person if their ETag matches"
 // @Success 200 {object} dto.RemovePersonResponse
+// @Failure 404 {object} dto.Problem
+// @Failure 412 {object} dto.Problem
+// @Router /v1/people/{id} [delete]
 // @Router /remove-single-person/{id} [delete]
 func (h *MatchHandler) RemoveSinglePerson(c *gin.Context) {
 	personID := c.Param("id")
-	fmt.Println("personID:", personID)
 	if personID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "person ID is required"})
+		c.Error(services.NewValidationError(services.FieldError{Field: "id", Message: "is required"}))
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c.GetHeader(ifMatchHeader))
+	if !ok {
+		c.Error(services.ErrVersionMismatch)
 		return
 	}
 
-	success := h.matchService.RemoveSinglePerson(personID)
-	if !success {
-		c.JSON(http.StatusNotFound, dto.RemovePersonResponse{
-			Success: false,
-			Message: "person not found",
-		})
+	if err := h.service(c).RemoveSinglePersonIfMatch(c.Request.Context(), personID, expectedVersion); err != nil {
+		c.Error(err)
 		return
 	}
 
@@ -77,26 +110,140 @@ func (h *MatchHandler) RemoveSinglePerson(c *gin.Context) {
 	})
 }
 
+// GetSinglePerson godoc
+// @Summary Get a single person
+// @Description Get a single active person, with their version as the ETag
+// @Tags match
+// @Accept json
+// @Produce json
+// @Param id path string true "Person ID"
+// @Success 200 {object} dto.GetPersonResponse
+// @Failure 404 {object} dto.Problem
+// @Router /v1/people/{id} [get]
+// @Router /single-person/{id} [get]
+func (h *MatchHandler) GetSinglePerson(c *gin.Context) {
+	person, ok := h.service(c).GetSinglePerson(c.Param("id"))
+	if !ok {
+		c.Error(services.ErrPersonNotFound)
+		return
+	}
+
+	c.Header(etagHeader, etag(person.Version))
+	c.JSON(http.StatusOK, dto.GetPersonResponse{
+		Person:  *person,
+		Message: "person found successfully",
+	})
+}
+
+// UpdateSinglePerson godoc
+// @Summary Update a single person
+// @Description Replace a single person's attributes and match them again
+// @Tags match
+// @Accept json
+// @Produce json
+// @Param id path string true "Person ID"
+// @Param person body dto.UpdatePersonRequest true "Person"
+// @Param If-Match header string false "Only update the person if their ETag matches"
+// @Success 200 {object} dto.UpdatePersonResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Failure 412 {object} dto.Problem
+// @Router /v1/people/{id} [put]
+// @Router /update-single-person/{id} [put]
+func (h *MatchHandler) UpdateSinglePerson(c *gin.Context) {
+	var req dto.UpdatePersonRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	expectedVersion, ok := parseIfMatch(c.GetHeader(ifMatchHeader))
+	if !ok {
+		c.Error(services.ErrVersionMismatch)
+		return
+	}
+
+	person, matches, err := h.service(c).UpdateSinglePerson(c.Request.Context(), c.Param("id"), req, expectedVersion)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header(etagHeader, etag(person.Version))
+	c.JSON(http.StatusOK, dto.UpdatePersonResponse{
+		Person:  *person,
+		Matches: matches,
+		Message: "person updated successfully",
+	})
+}
+
 // QuerySinglePeople godoc
 // @Summary Query single people
-// @Description Query single people
+// @Description Query single people. Deprecated, use GET /v1/people instead.
+// @Deprecated
 // @Tags match
 // @Accept json
 // @Produce json
 // @Param limit query int true "Limit"
 // @Success 200 {object} dto.QueryPeopleResponse
+// @Failure 400 {object} dto.Problem
 // @Router /query-single-people [get]
 func (h *MatchHandler) QuerySinglePeople(c *gin.Context) {
 	limit, err := strconv.Atoi(c.Query("limit"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, dto.QueryPeopleResponse{
-			Message: "limit is required",
-		})
+		c.Error(services.NewValidationError(services.FieldError{Field: "limit", Message: "is required and must be an integer"}))
 		return
 	}
-	people := h.matchService.QuerySinglePeople(limit)
+	people := h.service(c).QuerySinglePeople(limit)
 	c.JSON(http.StatusOK, dto.QueryPeopleResponse{
 		People:  people,
 		Message: "people queried successfully",
 	})
 }
+
+// ListPeople godoc
+// @Summary List single people
+// @Description List active people in query order, optionally limited to the top N
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param limit query int false "Limit, 0 or omitted returns everyone"
+// @Success 200 {object} dto.QueryPeopleResponse
+// @Failure 400 {object} dto.Problem
+// @Router /v1/people [get]
+func (h *MatchHandler) ListPeople(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.Error(services.NewValidationError(services.FieldError{Field: "limit", Message: "must be a non-negative integer"}))
+		return
+	}
+	people := h.service(c).QuerySinglePeople(limit)
+	c.JSON(http.StatusOK, dto.QueryPeopleResponse{
+		People:  people,
+		Message: "people queried successfully",
+	})
+}
+
+// QueryMatches godoc
+// @Summary List matches
+// @Description List the match history, newest first
+// @Tags matches
+// @Accept json
+// @Produce json
+// @Param person_id query string false "Only matches involving this person"
+// @Param limit query int false "Limit, 0 or omitted returns every match"
+// @Success 200 {object} dto.QueryMatchesResponse
+// @Failure 400 {object} dto.Problem
+// @Router /v1/matches [get]
+func (h *MatchHandler) QueryMatches(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.Error(services.NewValidationError(services.FieldError{Field: "limit", Message: "must be a non-negative integer"}))
+		return
+	}
+	matches := h.service(c).QueryMatches(c.Query("person_id"), limit)
+	c.JSON(http.StatusOK, dto.QueryMatchesResponse{
+		Matches: matches,
+		Message: "matches queried successfully",
+	})
+}
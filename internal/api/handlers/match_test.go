@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"matching_system/internal/api/dto"
+	"matching_system/internal/api/middleware"
 	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/wal"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -25,11 +30,88 @@ func (m *MockMatchService) AddSinglePersonAndMatch(req dto.AddPersonRequest) (*m
 	return args.Get(0).(*models.Person), args.Get(1).([]models.Match)
 }
 
+func (m *MockMatchService) AddSinglePersonAndMatchIdempotent(ctx context.Context, key string, req dto.AddPersonRequest) (*models.Person, []models.Match, error) {
+	args := m.Called(key, req)
+	person, _ := args.Get(0).(*models.Person)
+	matches, _ := args.Get(1).([]models.Match)
+	return person, matches, args.Error(2)
+}
+
 func (m *MockMatchService) RemoveSinglePerson(personID string) bool {
 	args := m.Called(personID)
 	return args.Bool(0)
 }
 
+func (m *MockMatchService) RemoveSinglePersonIfMatch(ctx context.Context, personID string, expectedVersion int64) error {
+	args := m.Called(personID, expectedVersion)
+	return args.Error(0)
+}
+
+func (m *MockMatchService) GetSinglePerson(personID string) (*models.Person, bool) {
+	args := m.Called(personID)
+	person, _ := args.Get(0).(*models.Person)
+	return person, args.Bool(1)
+}
+
+func (m *MockMatchService) UpdateSinglePerson(ctx context.Context, personID string, req dto.UpdatePersonRequest, expectedVersion int64) (*models.Person, []models.Match, error) {
+	args := m.Called(personID, req, expectedVersion)
+	person, _ := args.Get(0).(*models.Person)
+	matches, _ := args.Get(1).([]models.Match)
+	return person, matches, args.Error(2)
+}
+
+func (m *MockMatchService) ImportPeople(ctx context.Context, people []dto.ImportPerson, runMatching bool, dryRun bool) ([]models.Person, []models.Match, error) {
+	args := m.Called(people, runMatching, dryRun)
+	imported, _ := args.Get(0).([]models.Person)
+	matches, _ := args.Get(1).([]models.Match)
+	return imported, matches, args.Error(2)
+}
+
+func (m *MockMatchService) Follow(buffer int) (snapshot.State, *services.Feed) {
+	args := m.Called(buffer)
+	feed, _ := args.Get(1).(*services.Feed)
+	return args.Get(0).(snapshot.State), feed
+}
+
+func (m *MockMatchService) Reset(state snapshot.State) {
+	m.Called(state)
+}
+
+func (m *MockMatchService) IdempotencyKeys() []services.IdempotencyKey {
+	args := m.Called()
+	keys, _ := args.Get(0).([]services.IdempotencyKey)
+	return keys
+}
+
+func (m *MockMatchService) RestoreIdempotencyKeys(keys []services.IdempotencyKey) {
+	m.Called(keys)
+}
+
+func (m *MockMatchService) Apply(records []wal.Record) (uint64, error) {
+	args := m.Called(records)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *MockMatchService) QueryMatches(personID string, limit int) []models.Match {
+	args := m.Called(personID, limit)
+	return args.Get(0).([]models.Match)
+}
+
+func (m *MockMatchService) FindCandidates(gender string, height int, limit int) []models.Person {
+	args := m.Called(gender, height, limit)
+	return args.Get(0).([]models.Person)
+}
+
+func (m *MockMatchService) CountByGender() map[string]int {
+	args := m.Called()
+	return args.Get(0).(map[string]int)
+}
+
+func (m *MockMatchService) Snapshot() snapshot.State {
+	args := m.Called()
+	return args.Get(0).(snapshot.State)
+}
+
 func (m *MockMatchService) QuerySinglePeople(limit int) []models.Person {
 	args := m.Called(limit)
 	return args.Get(0).([]models.Person)
@@ -37,11 +119,13 @@ func (m *MockMatchService) QuerySinglePeople(limit int) []models.Person {
 
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
-	return gin.New()
+	router := gin.New()
+	router.Use(middleware.Problems())
+	return router
 }
 
 func TestNewMatchHandler(t *testing.T) {
-	handler := NewMatchHandler()
+	handler := NewMatchHandler(services.NewMatchService())
 	assert.NotNil(t, handler)
 	assert.NotNil(t, handler.matchService)
 }
@@ -78,7 +162,7 @@ func TestAddSinglePersonAndMatch_Success(t *testing.T) {
 	}
 
 	// Mock expectations
-	mockService.On("AddSinglePersonAndMatch", requestBody).Return(expectedPerson, expectedMatches)
+	mockService.On("AddSinglePersonAndMatchIdempotent", "", requestBody).Return(expectedPerson, expectedMatches, nil)
 
 	// Create request
 	jsonBody, _ := json.Marshal(requestBody)
@@ -120,14 +204,44 @@ func TestAddSinglePersonAndMatch_InvalidJSON(t *testing.T) {
 
 	// Assertions
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
 
-	var response map[string]interface{}
+	var response dto.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Contains(t, response, "error")
+	assert.Equal(t, "validation_failed", response.Code)
+	assert.Equal(t, []dto.ProblemFieldError{{Field: "height", Message: "must be a number"}}, response.Errors)
 
 	// Verify service was not called
-	mockService.AssertNotCalled(t, "AddSinglePersonAndMatch")
+	mockService.AssertNotCalled(t, "AddSinglePersonAndMatchIdempotent")
+}
+
+func TestAddSinglePersonAndMatch_MalformedJSON(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.POST("/add", handler.AddSinglePersonAndMatch)
+
+	// Create request with a truncated body
+	req, _ := http.NewRequest("POST", "/add", bytes.NewBufferString(`{"name": "Alice"`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response dto.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "malformed_request", response.Code)
+
+	// Verify service was not called
+	mockService.AssertNotCalled(t, "AddSinglePersonAndMatchIdempotent")
 }
 
 func TestAddSinglePersonAndMatch_ValidationError(t *testing.T) {
@@ -158,13 +272,95 @@ func TestAddSinglePersonAndMatch_ValidationError(t *testing.T) {
 	// Assertions
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	var response map[string]interface{}
+	var response dto.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Contains(t, response, "error")
+	assert.Equal(t, http.StatusBadRequest, response.Status)
+	assert.Equal(t, "validation_failed", response.Code)
+	assert.Equal(t, []dto.ProblemFieldError{{Field: "height", Message: "must be at least 100"}}, response.Errors)
 
 	// Verify service was not called
-	mockService.AssertNotCalled(t, "AddSinglePersonAndMatch")
+	mockService.AssertNotCalled(t, "AddSinglePersonAndMatchIdempotent")
+}
+
+func TestAddSinglePersonAndMatch_IdempotencyKey(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.POST("/add", handler.AddSinglePersonAndMatch)
+
+	requestBody := dto.AddPersonRequest{
+		Name:        "Alice",
+		Height:      165,
+		Gender:      "female",
+		WantedDates: 3,
+	}
+	expectedPerson := &models.Person{ID: "test-id-1", Name: "Alice", Height: 165, Gender: "female", WantedDates: 3}
+
+	// Mock expectations
+	mockService.On("AddSinglePersonAndMatchIdempotent", "retry-1", requestBody).Return(expectedPerson, []models.Match{}, nil)
+
+	// Create request
+	jsonBody, _ := json.Marshal(requestBody)
+	req, _ := http.NewRequest("POST", "/add", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "retry-1")
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response dto.AddPersonResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, *expectedPerson, response.Person)
+
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "AddSinglePersonAndMatchIdempotent")
+}
+
+func TestAddSinglePersonAndMatch_IdempotencyKeyMismatch(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.POST("/add", handler.AddSinglePersonAndMatch)
+
+	requestBody := dto.AddPersonRequest{
+		Name:        "Alice",
+		Height:      165,
+		Gender:      "female",
+		WantedDates: 3,
+	}
+
+	// Mock expectations
+	mockService.On("AddSinglePersonAndMatchIdempotent", "retry-1", requestBody).Return(nil, nil, services.ErrIdempotencyKeyMismatch)
+
+	// Create request
+	jsonBody, _ := json.Marshal(requestBody)
+	req, _ := http.NewRequest("POST", "/add", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "retry-1")
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var response dto.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "idempotency_key_mismatch", response.Code)
+
+	mockService.AssertExpectations(t)
 }
 
 func TestRemoveSinglePerson_Success(t *testing.T) {
@@ -178,7 +374,7 @@ func TestRemoveSinglePerson_Success(t *testing.T) {
 	personID := "test-id-1"
 
 	// Mock expectations
-	mockService.On("RemoveSinglePerson", personID).Return(true)
+	mockService.On("RemoveSinglePersonIfMatch", personID, int64(0)).Return(nil)
 
 	// Create request
 	req, _ := http.NewRequest("DELETE", "/remove/"+personID, nil)
@@ -210,7 +406,7 @@ func TestRemoveSinglePerson_NotFound(t *testing.T) {
 	personID := "non-existent-id"
 
 	// Mock expectations
-	mockService.On("RemoveSinglePerson", personID).Return(false)
+	mockService.On("RemoveSinglePersonIfMatch", personID, int64(0)).Return(services.ErrPersonNotFound)
 
 	// Create request
 	req, _ := http.NewRequest("DELETE", "/remove/"+personID, nil)
@@ -222,11 +418,12 @@ func TestRemoveSinglePerson_NotFound(t *testing.T) {
 	// Assertions
 	assert.Equal(t, http.StatusNotFound, w.Code)
 
-	var response dto.RemovePersonResponse
+	var response dto.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.False(t, response.Success)
-	assert.Equal(t, "person not found", response.Message)
+	assert.Equal(t, "person_not_found", response.Code)
+	assert.Equal(t, "person not found", response.Detail)
+	assert.Equal(t, "/remove/"+personID, response.Instance)
 
 	mockService.AssertExpectations(t)
 }
@@ -250,7 +447,182 @@ func TestRemoveSinglePerson_MissingID(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 
 	// Verify service was not called
-	mockService.AssertNotCalled(t, "RemoveSinglePerson")
+	mockService.AssertNotCalled(t, "RemoveSinglePersonIfMatch")
+}
+
+func TestRemoveSinglePerson_IfMatchStale(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.DELETE("/remove/:id", handler.RemoveSinglePerson)
+
+	personID := "test-id-1"
+
+	// Mock expectations
+	mockService.On("RemoveSinglePersonIfMatch", personID, int64(1)).Return(services.ErrVersionMismatch)
+
+	// Create request
+	req, _ := http.NewRequest("DELETE", "/remove/"+personID, nil)
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "RemoveSinglePersonIfMatch")
+}
+
+func TestRemoveSinglePerson_IfMatchMalformed(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.DELETE("/remove/:id", handler.RemoveSinglePerson)
+
+	for _, ifMatch := range []string{`1`, `W/"1"`, `"abc"`, `"0"`} {
+		// Create request
+		req, _ := http.NewRequest("DELETE", "/remove/test-id-1", nil)
+		req.Header.Set("If-Match", ifMatch)
+		w := httptest.NewRecorder()
+
+		// Execute request
+		router.ServeHTTP(w, req)
+
+		// Assertions
+		assert.Equal(t, http.StatusPreconditionFailed, w.Code, ifMatch)
+	}
+
+	// Verify service was not called
+	mockService.AssertNotCalled(t, "RemoveSinglePersonIfMatch")
+}
+
+func TestGetSinglePerson_Success(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.GET("/person/:id", handler.GetSinglePerson)
+
+	expectedPerson := &models.Person{ID: "test-id-1", Name: "Alice", Height: 165, Gender: "female", WantedDates: 3, Version: 2}
+
+	// Mock expectations
+	mockService.On("GetSinglePerson", "test-id-1").Return(expectedPerson, true)
+
+	// Create request
+	req, _ := http.NewRequest("GET", "/person/test-id-1", nil)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	var response dto.GetPersonResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, *expectedPerson, response.Person)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetSinglePerson_NotFound(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.GET("/person/:id", handler.GetSinglePerson)
+
+	// Mock expectations
+	mockService.On("GetSinglePerson", "non-existent-id").Return(nil, false)
+
+	// Create request
+	req, _ := http.NewRequest("GET", "/person/non-existent-id", nil)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateSinglePerson_Success(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.PUT("/update/:id", handler.UpdateSinglePerson)
+
+	requestBody := dto.UpdatePersonRequest{Name: "Alice", Height: 170, Gender: "female", WantedDates: 2}
+	updatedPerson := &models.Person{ID: "test-id-1", Name: "Alice", Height: 170, Gender: "female", WantedDates: 2, Version: 3}
+
+	// Mock expectations
+	mockService.On("UpdateSinglePerson", "test-id-1", requestBody, int64(2)).Return(updatedPerson, []models.Match{}, nil)
+
+	// Create request
+	jsonBody, _ := json.Marshal(requestBody)
+	req, _ := http.NewRequest("PUT", "/update/test-id-1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+
+	var response dto.UpdatePersonResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, *updatedPerson, response.Person)
+	assert.Equal(t, "person updated successfully", response.Message)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateSinglePerson_IfMatchStale(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.PUT("/update/:id", handler.UpdateSinglePerson)
+
+	requestBody := dto.UpdatePersonRequest{Name: "Alice", Height: 170, Gender: "female", WantedDates: 2}
+
+	// Mock expectations
+	mockService.On("UpdateSinglePerson", "test-id-1", requestBody, int64(1)).Return(nil, nil, services.ErrVersionMismatch)
+
+	// Create request
+	jsonBody, _ := json.Marshal(requestBody)
+	req, _ := http.NewRequest("PUT", "/update/test-id-1", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"1"`)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	mockService.AssertExpectations(t)
 }
 
 func TestQuerySinglePeople_Success(t *testing.T) {
@@ -308,10 +680,11 @@ func TestQuerySinglePeople_MissingLimit(t *testing.T) {
 	// Assertions
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	var response dto.QueryPeopleResponse
+	var response dto.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "limit is required", response.Message)
+	assert.Equal(t, "validation_failed", response.Code)
+	assert.Equal(t, "limit", response.Errors[0].Field)
 
 	// Verify service was not called
 	mockService.AssertNotCalled(t, "QuerySinglePeople")
@@ -335,10 +708,11 @@ func TestQuerySinglePeople_InvalidLimit(t *testing.T) {
 	// Assertions
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 
-	var response dto.QueryPeopleResponse
+	var response dto.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "limit is required", response.Message)
+	assert.Equal(t, "validation_failed", response.Code)
+	assert.Equal(t, "limit", response.Errors[0].Field)
 
 	// Verify service was not called
 	mockService.AssertNotCalled(t, "QuerySinglePeople")
@@ -376,3 +750,94 @@ func TestQuerySinglePeople_ZeroLimit(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestListPeople_DefaultLimit(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.GET("/people", handler.ListPeople)
+
+	expectedPeople := []models.Person{
+		{ID: "1", Name: "Alice", Height: 165, Gender: "female", WantedDates: 3},
+	}
+
+	// Mock expectations
+	mockService.On("QuerySinglePeople", 0).Return(expectedPeople)
+
+	// Create request without limit parameter
+	req, _ := http.NewRequest("GET", "/people", nil)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.QueryPeopleResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedPeople, response.People)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestListPeople_InvalidLimit(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.GET("/people", handler.ListPeople)
+
+	// Create request with negative limit
+	req, _ := http.NewRequest("GET", "/people?limit=-1", nil)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.AssertNotCalled(t, "QuerySinglePeople")
+}
+
+func TestQueryMatches_Success(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := &MatchHandler{matchService: mockService}
+
+	router.GET("/matches", handler.QueryMatches)
+
+	expectedMatches := []models.Match{
+		{
+			Person1: models.Person{ID: "1", Name: "Alice", Height: 165, Gender: "female", WantedDates: 2},
+			Person2: models.Person{ID: "2", Name: "Bob", Height: 175, Gender: "male", WantedDates: 0},
+		},
+	}
+
+	// Mock expectations
+	mockService.On("QueryMatches", "1", 10).Return(expectedMatches)
+
+	// Create request
+	req, _ := http.NewRequest("GET", "/matches?person_id=1&limit=10", nil)
+	w := httptest.NewRecorder()
+
+	// Execute request
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.QueryMatchesResponse
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedMatches, response.Matches)
+	assert.Equal(t, "matches queried successfully", response.Message)
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"matching_system/internal/events"
+	"matching_system/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// notificationBuffer is how many events a subscriber may fall behind before it is disconnected
+	notificationBuffer = 64
+	defaultWriteWait   = 10 * time.Second
+	defaultPongWait    = 60 * time.Second
+)
+
+type NotificationHandler struct {
+	matchService services.MatchService
+	broker       *events.Broker
+	upgrader     websocket.Upgrader
+	// pingPeriod must be shorter than pongWait so a healthy client always answers in time
+	pingPeriod time.Duration
+	pongWait   time.Duration
+	writeWait  time.Duration
+}
+
+func NewNotificationHandler(matchService services.MatchService, broker *events.Broker) *NotificationHandler {
+	return &NotificationHandler{
+		matchService: matchService,
+		broker:       broker,
+		pingPeriod:   defaultPongWait * 9 / 10,
+		pongWait:     defaultPongWait,
+		writeWait:    defaultWriteWait,
+	}
+}
+
+// service is the match service of the request's pool
+func (h *NotificationHandler) service(c *gin.Context) services.MatchService {
+	return matchServiceFor(c, h.matchService)
+}
+
+// SubscribePerson godoc
+// @Summary Subscribe to a person's notifications
+// @Description Upgrade to a WebSocket that pushes match, dates_exhausted and person_removed events
+// @Description involving the person. The server pings periodically and disconnects clients that
+// @Description stop answering or fall too far behind.
+// @Tags notifications
+// @Param id path string true "Person ID"
+// @Success 101 {object} events.Event
+// @Failure 404 {object} dto.Problem
+// @Router /v1/people/{id}/notifications [get]
+func (h *NotificationHandler) SubscribePerson(c *gin.Context) {
+	personID := c.Param("id")
+	pool := poolFor(c)
+	// subscribe before checking the person exists, so no event can slip in between
+	sub := h.broker.Subscribe(notificationBuffer)
+	defer sub.Close()
+
+	if _, ok := h.service(c).GetSinglePerson(personID); !ok {
+		c.Error(services.ErrPersonNotFound)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go h.readPump(conn, closed)
+
+	ticker := time.NewTicker(h.pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// the broker dropped us for falling behind
+				h.closeWith(conn, websocket.CloseTryAgainLater, "slow consumer")
+				return
+			}
+			if !event.Involves(personID) || (pool != "" && event.Pool != pool) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(h.writeWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+			if event.PersonID == personID && (event.Type == events.PersonRemoved || event.Type == events.DatesExhausted) {
+				h.closeWith(conn, websocket.CloseNormalClosure, "person left the pool")
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.writeWait)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-c.Request.Context().Done():
+			// the server is shutting down; the client reconnects to another instance
+			h.closeWith(conn, websocket.CloseGoingAway, "server shutting down")
+			return
+		}
+	}
+}
+
+// readPump keeps pong handling alive and notices when the client goes away
+func (h *NotificationHandler) readPump(conn *websocket.Conn, closed chan<- struct{}) {
+	defer close(closed)
+
+	conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.pongWait))
+	})
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			return
+		}
+	}
+}
+
+func (h *NotificationHandler) closeWith(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(h.writeWait))
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/events"
+	"matching_system/internal/services"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNotificationServer(t *testing.T, handler *NotificationHandler) string {
+	router := setupTestRouter()
+	router.GET("/people/:id/notifications", handler.SubscribePerson)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestSubscribePerson_ReceivesMatchAndRemoval(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	url := setupNotificationServer(t, NewNotificationHandler(matchService, broker))
+
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/people/"+alice.ID+"/notifications", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Bob joins and is matched with Alice, who only learns about it through the socket
+	bob, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	var event events.Event
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, events.Matched, event.Type)
+	assert.Equal(t, bob.ID, event.Match.Person1.ID)
+	assert.Equal(t, alice.ID, event.Match.Person2.ID)
+
+	// Bob's dates_exhausted does not concern Alice, her removal does and ends the stream
+	matchService.RemoveSinglePerson(alice.ID)
+
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, events.PersonRemoved, event.Type)
+	assert.Equal(t, alice.ID, event.PersonID)
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "the server should close normally")
+}
+
+func TestSubscribePerson_Heartbeat(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	handler := NewNotificationHandler(matchService, broker)
+	handler.pingPeriod = 10 * time.Millisecond
+	url := setupNotificationServer(t, handler)
+
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"/people/"+alice.ID+"/notifications", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	pinged := make(chan struct{}, 1)
+	conn.SetPingHandler(func(string) error {
+		select {
+		case pinged <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	// pings are only processed while reading
+	go conn.ReadMessage()
+
+	select {
+	case <-pinged:
+	case <-time.After(time.Second):
+		t.Fatal("expected a heartbeat ping")
+	}
+}
+
+func TestSubscribePerson_NotFound(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	url := setupNotificationServer(t, NewNotificationHandler(services.NewMatchService(services.WithEvents(broker)), broker))
+
+	_, resp, err := websocket.DefaultDialer.Dial(url+"/people/non-existent-id/notifications", nil)
+	assert.Error(t, err)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestSubscribePerson_ClosedOnShutdown(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	matchService := services.NewMatchService(services.WithEvents(broker))
+	router := setupTestRouter()
+	router.GET("/people/:id/notifications", NewNotificationHandler(matchService, broker).SubscribePerson)
+
+	// like main, request contexts end when the server starts shutting down
+	streams, endStreams := context.WithCancel(context.Background())
+	server := httptest.NewUnstartedServer(router)
+	server.Config.BaseContext = func(net.Listener) context.Context { return streams }
+	server.Config.RegisterOnShutdown(endStreams)
+	server.Start()
+	t.Cleanup(server.Close)
+
+	alice, _ := matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/people/"+alice.ID+"/notifications", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, server.Config.Shutdown(context.Background()))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "the server should say it is going away, got %v", err)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keys under which Scope leaves the pool of a request in the gin context
+const (
+	poolNameKey    = "pool.name"
+	poolServiceKey = "pool.service"
+)
+
+// PoolRegistry holds the pools: pools.Registry keeps them on this server, a cluster node replicates
+// them to the whole cluster
+type PoolRegistry interface {
+	Create(name string, rules models.MatchRules) (models.Pool, error)
+	// Get returns a pool and its match service, or services.ErrPoolNotFound
+	Get(name string) (models.Pool, services.MatchService, error)
+	Default() services.MatchService
+	List() []models.Pool
+	// Delete deletes a pool, audited as made by the audit source in ctx
+	Delete(ctx context.Context, name string) error
+}
+
+type PoolHandler struct {
+	registry PoolRegistry
+}
+
+func NewPoolHandler(registry PoolRegistry) *PoolHandler {
+	return &PoolHandler{
+		registry: registry,
+	}
+}
+
+// Scope resolves the pool named in the path, so the handlers after it work on that pool only
+func (h *PoolHandler) Scope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.scope(c, c.Param("pool"))
+	}
+}
+
+// DefaultScope puts the routes outside /v1/pools on the default pool
+func (h *PoolHandler) DefaultScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h.scope(c, pools.DefaultPool)
+	}
+}
+
+func (h *PoolHandler) scope(c *gin.Context, name string) {
+	_, service, err := h.registry.Get(name)
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+		return
+	}
+	c.Set(poolNameKey, name)
+	c.Set(poolServiceKey, service)
+	c.Next()
+}
+
+// matchServiceFor returns the service of the pool the request was scoped to, or fallback when the
+// handler is used without a scope
+func matchServiceFor(c *gin.Context, fallback services.MatchService) services.MatchService {
+	if service, ok := c.Get(poolServiceKey); ok {
+		return service.(services.MatchService)
+	}
+	return fallback
+}
+
+// ScopedMatchService returns the service of the pool the request was scoped to by Scope or
+// DefaultScope, for handlers outside this package
+func ScopedMatchService(c *gin.Context) (services.MatchService, bool) {
+	service, ok := c.Get(poolServiceKey)
+	if !ok {
+		return nil, false
+	}
+	return service.(services.MatchService), true
+}
+
+// poolFor returns the pool the request was scoped to, empty without a scope
+func poolFor(c *gin.Context) string {
+	return c.GetString(poolNameKey)
+}
+
+// CreatePool godoc
+// @Summary Create a pool
+// @Description Create an empty pool. People are only matched with people of the same pool, by the
+// @Description pool's rules. Rules are fixed once the pool exists.
+// @Tags pools
+// @Accept json
+// @Produce json
+// @Param pool body dto.CreatePoolRequest true "Pool"
+// @Success 201 {object} dto.PoolResponse
+// @Failure 400 {object} dto.Problem
+// @Failure 409 {object} dto.Problem
+// @Router /v1/pools [post]
+func (h *PoolHandler) CreatePool(c *gin.Context) {
+	var req dto.CreatePoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	rules := services.DefaultRules
+	if req.Rules != nil {
+		rules = *req.Rules
+	}
+	pool, err := h.registry.Create(req.Name, rules)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, dto.PoolResponse{
+		Pool:    pool,
+		Message: "pool created successfully",
+	})
+}
+
+// ListPools godoc
+// @Summary List pools
+// @Description List every pool, sorted by name
+// @Tags pools
+// @Produce json
+// @Success 200 {object} dto.QueryPoolsResponse
+// @Router /v1/pools [get]
+func (h *PoolHandler) ListPools(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.QueryPoolsResponse{
+		Pools:   h.registry.List(),
+		Message: "pools queried successfully",
+	})
+}
+
+// GetPool godoc
+// @Summary Get a pool
+// @Description Get a pool and its match rules
+// @Tags pools
+// @Produce json
+// @Param pool path string true "Pool name"
+// @Success 200 {object} dto.PoolResponse
+// @Failure 404 {object} dto.Problem
+// @Router /v1/pools/{pool} [get]
+func (h *PoolHandler) GetPool(c *gin.Context) {
+	pool, _, err := h.registry.Get(c.Param("pool"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.PoolResponse{
+		Pool:    pool,
+		Message: "pool found",
+	})
+}
+
+// DeletePool godoc
+// @Summary Delete a pool
+// @Description Delete a pool with its people and match history. The default pool cannot be deleted.
+// @Tags pools
+// @Param pool path string true "Pool name"
+// @Success 204
+// @Failure 400 {object} dto.Problem
+// @Failure 404 {object} dto.Problem
+// @Router /v1/pools/{pool} [delete]
+func (h *PoolHandler) DeletePool(c *gin.Context) {
+	if err := h.registry.Delete(c.Request.Context(), c.Param("pool")); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
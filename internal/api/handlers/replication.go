@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"matching_system/internal/replication"
+	"matching_system/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// replicationBuffer is how many batches a follower may fall behind before it is disconnected
+	replicationBuffer      = 1024
+	defaultHeartbeatPeriod = 15 * time.Second
+	replicationContentType = "application/x-ndjson"
+)
+
+type ReplicationHandler struct {
+	matchService services.MatchService
+	follower     *replication.Follower
+	// heartbeatPeriod is how often an empty message is sent, so followers can tell a quiet pool from
+	// a lost leader
+	heartbeatPeriod time.Duration
+}
+
+// NewReplicationHandler serves the replication stream of matchService's pool; follower is nil on a
+// leader
+func NewReplicationHandler(matchService services.MatchService, follower *replication.Follower) *ReplicationHandler {
+	return &ReplicationHandler{
+		matchService:    matchService,
+		follower:        follower,
+		heartbeatPeriod: defaultHeartbeatPeriod,
+	}
+}
+
+// StreamOperations godoc
+// @Summary Stream a pool's operations
+// @Description Newline-delimited JSON stream for followers. The first message carries the state of the
+// @Description pool, every later one the write-ahead log records of a batch applied after it, and
+// @Description empty messages are heartbeats. A follower that falls too far behind is disconnected and
+// @Description starts over with a new state.
+// @Tags replication
+// @Produce application/x-ndjson
+// @Success 200 {object} replication.Message
+// @Router /v1/replication/stream [get]
+func (h *ReplicationHandler) StreamOperations(c *gin.Context) {
+	state, feed := matchServiceFor(c, h.matchService).Follow(replicationBuffer)
+	defer feed.Close()
+
+	c.Header("Content-Type", replicationContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	if encoder.Encode(replication.Message{State: &state}) != nil {
+		return
+	}
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(h.heartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		var message replication.Message
+		select {
+		case records, ok := <-feed.Records():
+			if !ok {
+				// fell behind or the pool was reset; the follower reconnects and starts over
+				return
+			}
+			message.Records = records
+		case <-ticker.C:
+		case <-c.Request.Context().Done():
+			return
+		}
+		if encoder.Encode(message) != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// GetStatus godoc
+// @Summary Get the replication status
+// @Description Whether this server is a leader or a read-only follower and, on a follower, whether
+// @Description each pool is connected to the leader and how many operations it has applied
+// @Tags replication
+// @Produce json
+// @Success 200 {object} dto.ReplicationStatus
+// @Router /v1/replication [get]
+func (h *ReplicationHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.follower.Status())
+}
+
+// Promote godoc
+// @Summary Promote a follower to leader
+// @Description Stop following the leader and accept writes, on top of what was replicated so far. The
+// @Description old leader must no longer take writes, or the two diverge. Promoting a leader does nothing.
+// @Tags replication
+// @Produce json
+// @Success 200 {object} dto.ReplicationStatus
+// @Router /v1/replication/promote [post]
+func (h *ReplicationHandler) Promote(c *gin.Context) {
+	h.follower.Promote()
+	c.JSON(http.StatusOK, h.follower.Status())
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/replication"
+	"matching_system/internal/services"
+	"matching_system/internal/wal"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationHandler_StreamOperations(t *testing.T) {
+	matchService := services.NewMatchService()
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	handler := NewReplicationHandler(matchService, nil)
+	handler.heartbeatPeriod = 10 * time.Millisecond
+
+	router := setupTestRouter()
+	router.GET("/v1/replication/stream", handler.StreamOperations)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/v1/replication/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	decoder := json.NewDecoder(resp.Body)
+
+	var message replication.Message
+	require.NoError(t, decoder.Decode(&message))
+	require.NotNil(t, message.State, "the stream starts with the state of the pool")
+	require.Len(t, message.State.People, 1)
+	assert.Equal(t, uint64(1), message.State.Revision)
+
+	matchService.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	// heartbeats may come before the batch
+	var records []wal.Record
+	for records == nil {
+		message = replication.Message{}
+		require.NoError(t, decoder.Decode(&message))
+		assert.Nil(t, message.State)
+		records = message.Records
+	}
+	require.Len(t, records, 1)
+	assert.Equal(t, wal.OpAdd, records[0].Op)
+	assert.Equal(t, uint64(1), records[0].Revision)
+	assert.Equal(t, "Bob", records[0].Person.Name)
+}
+
+func TestReplicationHandler_Status(t *testing.T) {
+	router := setupTestRouter()
+	handler := NewReplicationHandler(services.NewMatchService(), nil)
+	router.GET("/v1/replication", handler.GetStatus)
+	router.POST("/v1/replication/promote", handler.Promote)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest("GET", "/v1/replication", nil),
+		httptest.NewRequest("POST", "/v1/replication/promote", nil),
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"role":"leader"}`, w.Body.String(), "a server without a follower is a leader")
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/services"
+	"matching_system/internal/transfer"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize bounds import request bodies
+const maxImportSize = 32 << 20
+
+type TransferHandler struct {
+	matchService services.MatchService
+}
+
+func NewTransferHandler(matchService services.MatchService) *TransferHandler {
+	return &TransferHandler{
+		matchService: matchService,
+	}
+}
+
+// service is the match service of the request's pool
+func (h *TransferHandler) service(c *gin.Context) services.MatchService {
+	return matchServiceFor(c, h.matchService)
+}
+
+// ExportPeople godoc
+// @Summary Export people
+// @Description Download every active person as JSON Lines or CSV
+// @Tags admin
+// @Produce json
+// @Produce plain
+// @Param format query string false "File format, jsonl (default) or csv" Enums(jsonl, csv)
+// @Success 200 {file} file
+// @Failure 400 {object} dto.Problem
+// @Router /v1/admin/export/people [get]
+func (h *TransferHandler) ExportPeople(c *gin.Context) {
+	format, err := transfer.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	people := h.service(c).Snapshot().People
+	writeAttachment(c, "people", format)
+	if err := transfer.WritePeople(c.Writer, format, people); err != nil {
+		c.Error(err)
+	}
+}
+
+// ExportMatches godoc
+// @Summary Export matches
+// @Description Download the match history, oldest first, as JSON Lines or CSV
+// @Tags admin
+// @Produce json
+// @Produce plain
+// @Param format query string false "File format, jsonl (default) or csv" Enums(jsonl, csv)
+// @Success 200 {file} file
+// @Failure 400 {object} dto.Problem
+// @Router /v1/admin/export/matches [get]
+func (h *TransferHandler) ExportMatches(c *gin.Context) {
+	format, err := transfer.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	matches := h.service(c).Snapshot().Matches
+	writeAttachment(c, "matches", format)
+	if err := transfer.WriteMatches(c.Writer, format, matches); err != nil {
+		c.Error(err)
+	}
+}
+
+func writeAttachment(c *gin.Context, name string, format transfer.Format) {
+	c.Header("Content-Type", format.ContentType())
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + string(format)}))
+	c.Status(http.StatusOK)
+}
+
+// ImportPeople godoc
+// @Summary Import people
+// @Description Add the people of a JSON Lines or CSV file, as written by the export. The whole file is
+// @Description validated first and nothing is imported if any line is invalid.
+// @Tags admin
+// @Accept plain
+// @Produce json
+// @Param format query string false "File format, defaults to csv for a text/csv body and jsonl otherwise" Enums(jsonl, csv)
+// @Param dry_run query bool false "Only validate the file"
+// @Param match query bool false "Match each imported person, in file order"
+// @Param file body string true "People, one per line or row"
+// @Success 200 {object} dto.ImportPeopleResponse
+// @Failure 400 {object} dto.Problem
+// @Router /v1/admin/import/people [post]
+func (h *TransferHandler) ImportPeople(c *gin.Context) {
+	format, err := importFormat(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	dryRun, err := boolQuery(c, "dry_run")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	runMatching, err := boolQuery(c, "match")
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	people, err := transfer.ReadPeople(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize), format)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err = services.NewMalformedRequestError("file is larger than " + strconv.Itoa(maxImportSize>>20) + " MiB")
+		}
+		c.Error(err)
+		return
+	}
+
+	imported, matches, err := h.service(c).ImportPeople(c.Request.Context(), people, runMatching, dryRun)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	message := "people imported successfully"
+	if dryRun {
+		message = "people are valid, nothing was imported"
+	}
+	c.JSON(http.StatusOK, dto.ImportPeopleResponse{
+		DryRun:   dryRun,
+		Imported: len(imported),
+		Matches:  matches,
+		Message:  message,
+	})
+}
+
+func importFormat(c *gin.Context) (transfer.Format, error) {
+	if format := c.Query("format"); format != "" {
+		return transfer.ParseFormat(format)
+	}
+	if c.ContentType() == "text/csv" {
+		return transfer.CSV, nil
+	}
+	return transfer.JSONL, nil
+}
+
+func boolQuery(c *gin.Context, name string) (bool, error) {
+	value := c.Query(name)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, services.NewValidationError(services.FieldError{Field: name, Message: "must be true or false"})
+	}
+	return b, nil
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/snapshot"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestExportPeople_CSV(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := NewTransferHandler(mockService)
+
+	router.GET("/export/people", handler.ExportPeople)
+
+	mockService.On("Snapshot").Return(snapshot.State{People: []models.Person{
+		{ID: "1", Name: "Alice", Height: 160, Gender: "female", WantedDates: 2, Version: 1},
+	}})
+
+	// Execute request
+	req, _ := http.NewRequest("GET", "/export/people?format=csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=people.csv`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,name,height,gender,wanted_dates,version\n1,Alice,160,female,2,1\n", w.Body.String())
+
+	mockService.AssertExpectations(t)
+}
+
+func TestExportMatches_UnknownFormat(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := NewTransferHandler(mockService)
+
+	router.GET("/export/matches", handler.ExportMatches)
+
+	// Execute request
+	req, _ := http.NewRequest("GET", "/export/matches?format=xml", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var problem dto.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, []dto.ProblemFieldError{{Field: "format", Message: "must be one of: jsonl, csv"}}, problem.Errors)
+
+	mockService.AssertNotCalled(t, "Snapshot")
+}
+
+func TestImportPeople_DryRun(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := NewTransferHandler(mockService)
+
+	router.POST("/import/people", handler.ImportPeople)
+
+	people := []dto.ImportPerson{{Line: 2, Name: "Alice", Height: 160, Gender: "female", WantedDates: 1}}
+	mockService.On("ImportPeople", people, false, true).Return([]models.Person{{ID: "1", Name: "Alice"}}, nil, nil)
+
+	// a text/csv body is read as CSV without a format parameter
+	req, _ := http.NewRequest("POST", "/import/people?dry_run=true", strings.NewReader("name,height,gender,wanted_dates\nAlice,160,female,1\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response dto.ImportPeopleResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.DryRun)
+	assert.Equal(t, 1, response.Imported)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestImportPeople_Malformed(t *testing.T) {
+	// Setup
+	router := setupTestRouter()
+	mockService := new(MockMatchService)
+	handler := NewTransferHandler(mockService)
+
+	router.POST("/import/people", handler.ImportPeople)
+
+	// Execute request
+	req, _ := http.NewRequest("POST", "/import/people?match=true", strings.NewReader("{\"name\":\"Alice\"}\nnot json\n"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Assertions
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var problem dto.Problem
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+	assert.Equal(t, "malformed_request", problem.Code)
+	assert.Contains(t, problem.Detail, "line 2:")
+
+	mockService.AssertNotCalled(t, "ImportPeople", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"matching_system/internal/api/dto"
+	"matching_system/internal/webhooks"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	store *webhooks.Store
+}
+
+func NewWebhookHandler(store *webhooks.Store) *WebhookHandler {
+	return &WebhookHandler{
+		store: store,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Subscribe a webhook
+// @Description Subscribe a URL to pool events. Each event is POSTed as JSON, signed with the secret
+// @Description in the X-Webhook-Signature header, and retried with exponential backoff on failure.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body dto.CreateWebhookRequest true "Webhook"
+// @Success 201 {object} dto.WebhookResponse
+// @Failure 400 {object} dto.Problem
+// @Router /v1/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req dto.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(bindingError(err))
+		return
+	}
+
+	subscription := h.store.Create(req.URL, req.EventTypes, req.Secret)
+	c.JSON(http.StatusCreated, dto.WebhookResponse{
+		Webhook: subscription,
+		Message: "webhook created successfully",
+	})
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description List webhook subscriptions, oldest first. Secrets are never returned.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} dto.QueryWebhooksResponse
+// @Router /v1/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.QueryWebhooksResponse{
+		Webhooks: h.store.List(),
+		Message:  "webhooks queried successfully",
+	})
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 404 {object} dto.Problem
+// @Router /v1/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	subscription, err := h.store.Get(c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.WebhookResponse{
+		Webhook: subscription,
+		Message: "webhook found",
+	})
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook subscription and its delivery log. Pending retries are abandoned.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 204
+// @Failure 404 {object} dto.Problem
+// @Router /v1/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.store.Delete(c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the recent deliveries of a webhook with every attempt, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.QueryDeliveriesResponse
+// @Failure 404 {object} dto.Problem
+// @Router /v1/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	deliveries, err := h.store.Deliveries(c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.QueryDeliveriesResponse{
+		Deliveries: deliveries,
+		Message:    "deliveries queried successfully",
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupWebhookRouter(store *webhooks.Store) *gin.Engine {
+	router := setupTestRouter()
+	handler := NewWebhookHandler(store)
+	router.POST("/webhooks", handler.CreateWebhook)
+	router.GET("/webhooks", handler.ListWebhooks)
+	router.GET("/webhooks/:id", handler.GetWebhook)
+	router.DELETE("/webhooks/:id", handler.DeleteWebhook)
+	router.GET("/webhooks/:id/deliveries", handler.ListDeliveries)
+	return router
+}
+
+func TestCreateWebhook(t *testing.T) {
+	store := webhooks.NewStore()
+	router := setupWebhookRouter(store)
+
+	body := `{"url":"https://partner.example/hooks","event_types":["matched"],"secret":"0123456789abcdef"}`
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "0123456789abcdef", "the secret should never be returned")
+
+	var response dto.WebhookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.Webhook.ID)
+	assert.Equal(t, []string{"matched"}, response.Webhook.EventTypes)
+
+	stored, err := store.Get(response.Webhook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef", stored.Secret)
+}
+
+func TestCreateWebhook_Validation(t *testing.T) {
+	router := setupWebhookRouter(webhooks.NewStore())
+
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{name: "invalid url", body: `{"url":"not a url","event_types":["matched"],"secret":"0123456789abcdef"}`, field: "url"},
+		{name: "no event types", body: `{"url":"https://a.example","event_types":[],"secret":"0123456789abcdef"}`, field: "event_types"},
+		{name: "unknown event type", body: `{"url":"https://a.example","event_types":["exploded"],"secret":"0123456789abcdef"}`, field: "event_types[0]"},
+		{name: "short secret", body: `{"url":"https://a.example","event_types":["matched"],"secret":"short"}`, field: "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			var problem dto.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+			require.NotEmpty(t, problem.Errors)
+			assert.Equal(t, tt.field, problem.Errors[0].Field)
+		})
+	}
+}
+
+func TestWebhookLifecycle(t *testing.T) {
+	store := webhooks.NewStore()
+	router := setupWebhookRouter(store)
+	webhook := store.Create("https://partner.example/hooks", []string{"matched"}, "0123456789abcdef")
+
+	for _, path := range []string{"/webhooks", "/webhooks/" + webhook.ID, "/webhooks/" + webhook.ID + "/deliveries"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+
+	req, _ := http.NewRequest("DELETE", "/webhooks/"+webhook.ID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	for _, path := range []string{"/webhooks/" + webhook.ID, "/webhooks/" + webhook.ID + "/deliveries"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Contains(t, w.Body.String(), "webhook_not_found")
+	}
+}
@@ -0,0 +1,346 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: matching/v1/matching.proto
+
+package matchingpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	MatchingService_AddPerson_FullMethodName     = "/matching.v1.MatchingService/AddPerson"
+	MatchingService_RemovePerson_FullMethodName  = "/matching.v1.MatchingService/RemovePerson"
+	MatchingService_UpdatePerson_FullMethodName  = "/matching.v1.MatchingService/UpdatePerson"
+	MatchingService_GetPerson_FullMethodName     = "/matching.v1.MatchingService/GetPerson"
+	MatchingService_QueryPeople_FullMethodName   = "/matching.v1.MatchingService/QueryPeople"
+	MatchingService_StreamMatches_FullMethodName = "/matching.v1.MatchingService/StreamMatches"
+)
+
+// MatchingServiceClient is the client API for MatchingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MatchingService mirrors services.MatchService for backend callers.
+type MatchingServiceClient interface {
+	// AddPerson adds a single person and matches them against the pool.
+	AddPerson(ctx context.Context, in *AddPersonRequest, opts ...grpc.CallOption) (*AddPersonResponse, error)
+	// RemovePerson removes a single person so they cannot be matched anymore.
+	RemovePerson(ctx context.Context, in *RemovePersonRequest, opts ...grpc.CallOption) (*RemovePersonResponse, error)
+	// UpdatePerson replaces a single person's attributes and matches them again.
+	UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*UpdatePersonResponse, error)
+	// GetPerson returns a single active person.
+	GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error)
+	// QueryPeople returns the most N possible matched single people.
+	QueryPeople(ctx context.Context, in *QueryPeopleRequest, opts ...grpc.CallOption) (*QueryPeopleResponse, error)
+	// StreamMatches streams the match history, newest first, then every match made
+	// afterwards until the call is cancelled.
+	StreamMatches(ctx context.Context, in *StreamMatchesRequest, opts ...grpc.CallOption) (MatchingService_StreamMatchesClient, error)
+}
+
+type matchingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMatchingServiceClient(cc grpc.ClientConnInterface) MatchingServiceClient {
+	return &matchingServiceClient{cc}
+}
+
+func (c *matchingServiceClient) AddPerson(ctx context.Context, in *AddPersonRequest, opts ...grpc.CallOption) (*AddPersonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddPersonResponse)
+	err := c.cc.Invoke(ctx, MatchingService_AddPerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingServiceClient) RemovePerson(ctx context.Context, in *RemovePersonRequest, opts ...grpc.CallOption) (*RemovePersonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemovePersonResponse)
+	err := c.cc.Invoke(ctx, MatchingService_RemovePerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingServiceClient) UpdatePerson(ctx context.Context, in *UpdatePersonRequest, opts ...grpc.CallOption) (*UpdatePersonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdatePersonResponse)
+	err := c.cc.Invoke(ctx, MatchingService_UpdatePerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingServiceClient) GetPerson(ctx context.Context, in *GetPersonRequest, opts ...grpc.CallOption) (*Person, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Person)
+	err := c.cc.Invoke(ctx, MatchingService_GetPerson_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingServiceClient) QueryPeople(ctx context.Context, in *QueryPeopleRequest, opts ...grpc.CallOption) (*QueryPeopleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryPeopleResponse)
+	err := c.cc.Invoke(ctx, MatchingService_QueryPeople_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matchingServiceClient) StreamMatches(ctx context.Context, in *StreamMatchesRequest, opts ...grpc.CallOption) (MatchingService_StreamMatchesClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MatchingService_ServiceDesc.Streams[0], MatchingService_StreamMatches_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matchingServiceStreamMatchesClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatchingService_StreamMatchesClient interface {
+	Recv() (*Match, error)
+	grpc.ClientStream
+}
+
+type matchingServiceStreamMatchesClient struct {
+	grpc.ClientStream
+}
+
+func (x *matchingServiceStreamMatchesClient) Recv() (*Match, error) {
+	m := new(Match)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MatchingServiceServer is the server API for MatchingService service.
+// All implementations must embed UnimplementedMatchingServiceServer
+// for forward compatibility
+//
+// MatchingService mirrors services.MatchService for backend callers.
+type MatchingServiceServer interface {
+	// AddPerson adds a single person and matches them against the pool.
+	AddPerson(context.Context, *AddPersonRequest) (*AddPersonResponse, error)
+	// RemovePerson removes a single person so they cannot be matched anymore.
+	RemovePerson(context.Context, *RemovePersonRequest) (*RemovePersonResponse, error)
+	// UpdatePerson replaces a single person's attributes and matches them again.
+	UpdatePerson(context.Context, *UpdatePersonRequest) (*UpdatePersonResponse, error)
+	// GetPerson returns a single active person.
+	GetPerson(context.Context, *GetPersonRequest) (*Person, error)
+	// QueryPeople returns the most N possible matched single people.
+	QueryPeople(context.Context, *QueryPeopleRequest) (*QueryPeopleResponse, error)
+	// StreamMatches streams the match history, newest first, then every match made
+	// afterwards until the call is cancelled.
+	StreamMatches(*StreamMatchesRequest, MatchingService_StreamMatchesServer) error
+	mustEmbedUnimplementedMatchingServiceServer()
+}
+
+// UnimplementedMatchingServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMatchingServiceServer struct {
+}
+
+func (UnimplementedMatchingServiceServer) AddPerson(context.Context, *AddPersonRequest) (*AddPersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddPerson not implemented")
+}
+func (UnimplementedMatchingServiceServer) RemovePerson(context.Context, *RemovePersonRequest) (*RemovePersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemovePerson not implemented")
+}
+func (UnimplementedMatchingServiceServer) UpdatePerson(context.Context, *UpdatePersonRequest) (*UpdatePersonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePerson not implemented")
+}
+func (UnimplementedMatchingServiceServer) GetPerson(context.Context, *GetPersonRequest) (*Person, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPerson not implemented")
+}
+func (UnimplementedMatchingServiceServer) QueryPeople(context.Context, *QueryPeopleRequest) (*QueryPeopleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryPeople not implemented")
+}
+func (UnimplementedMatchingServiceServer) StreamMatches(*StreamMatchesRequest, MatchingService_StreamMatchesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMatches not implemented")
+}
+func (UnimplementedMatchingServiceServer) mustEmbedUnimplementedMatchingServiceServer() {}
+
+// UnsafeMatchingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MatchingServiceServer will
+// result in compilation errors.
+type UnsafeMatchingServiceServer interface {
+	mustEmbedUnimplementedMatchingServiceServer()
+}
+
+func RegisterMatchingServiceServer(s grpc.ServiceRegistrar, srv MatchingServiceServer) {
+	s.RegisterService(&MatchingService_ServiceDesc, srv)
+}
+
+func _MatchingService_AddPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingServiceServer).AddPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingService_AddPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingServiceServer).AddPerson(ctx, req.(*AddPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingService_RemovePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemovePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingServiceServer).RemovePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingService_RemovePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingServiceServer).RemovePerson(ctx, req.(*RemovePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingService_UpdatePerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingServiceServer).UpdatePerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingService_UpdatePerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingServiceServer).UpdatePerson(ctx, req.(*UpdatePersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingService_GetPerson_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPersonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingServiceServer).GetPerson(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingService_GetPerson_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingServiceServer).GetPerson(ctx, req.(*GetPersonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingService_QueryPeople_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryPeopleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatchingServiceServer).QueryPeople(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatchingService_QueryPeople_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatchingServiceServer).QueryPeople(ctx, req.(*QueryPeopleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatchingService_StreamMatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMatchesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatchingServiceServer).StreamMatches(m, &matchingServiceStreamMatchesServer{ServerStream: stream})
+}
+
+type MatchingService_StreamMatchesServer interface {
+	Send(*Match) error
+	grpc.ServerStream
+}
+
+type matchingServiceStreamMatchesServer struct {
+	grpc.ServerStream
+}
+
+func (x *matchingServiceStreamMatchesServer) Send(m *Match) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// MatchingService_ServiceDesc is the grpc.ServiceDesc for MatchingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MatchingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "matching.v1.MatchingService",
+	HandlerType: (*MatchingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddPerson",
+			Handler:    _MatchingService_AddPerson_Handler,
+		},
+		{
+			MethodName: "RemovePerson",
+			Handler:    _MatchingService_RemovePerson_Handler,
+		},
+		{
+			MethodName: "UpdatePerson",
+			Handler:    _MatchingService_UpdatePerson_Handler,
+		},
+		{
+			MethodName: "GetPerson",
+			Handler:    _MatchingService_GetPerson_Handler,
+		},
+		{
+			MethodName: "QueryPeople",
+			Handler:    _MatchingService_QueryPeople_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamMatches",
+			Handler:       _MatchingService_StreamMatches_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "matching/v1/matching.proto",
+}
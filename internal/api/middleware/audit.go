@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"matching_system/internal/audit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditSource has the writes of a request audited as made by the actor in its X-Actor header, with
+// the ID given by RequestID, which must run first
+func AuditSource() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		source := audit.NewSource(c.GetHeader(audit.ActorHeader), c.GetHeader(RequestIDHeader))
+		c.Request = c.Request.WithContext(audit.NewContext(c.Request.Context(), source))
+		c.Next()
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deprecated marks responses of a legacy route as deprecated and points clients to its successor.
+// successor is a route pattern like the legacy route's, its ":name" segments are filled in with the
+// request's path parameters of the same name.
+func Deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+expandPath(successor, c.Params)+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+func expandPath(pattern string, params gin.Params) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = url.PathEscape(params.ByName(name))
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/services"
+	"matching_system/pkg/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+var statusByCode = map[services.ErrorCode]int{
+	services.CodeValidationFailed:       http.StatusBadRequest,
+	services.CodeMalformedRequest:       http.StatusBadRequest,
+	services.CodePersonNotFound:         http.StatusNotFound,
+	services.CodeRouteNotFound:          http.StatusNotFound,
+	services.CodeMethodNotAllowed:       http.StatusMethodNotAllowed,
+	services.CodeVersionMismatch:        http.StatusPreconditionFailed,
+	services.CodeIdempotencyKeyMismatch: http.StatusUnprocessableEntity,
+	services.CodeWebhookNotFound:        http.StatusNotFound,
+	services.CodePoolNotFound:           http.StatusNotFound,
+	services.CodePoolExists:             http.StatusConflict,
+	services.CodeReadOnly:               http.StatusServiceUnavailable,
+	services.CodeNoLeader:               http.StatusServiceUnavailable,
+	services.CodeShardUnavailable:       http.StatusBadGateway,
+	services.CodeInternal:               http.StatusInternalServerError,
+}
+
+// Problems renders the last error attached to the context with c.Error as application/problem+json
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		WriteProblem(c, c.Errors.Last().Err)
+	}
+}
+
+// WriteProblem maps err onto a problem details response
+func WriteProblem(c *gin.Context, err error) {
+	var serviceErr *services.Error
+	if !errors.As(err, &serviceErr) {
+		serviceErr = &services.Error{Code: services.CodeInternal, Message: "internal server error"}
+	}
+
+	status, ok := statusByCode[serviceErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	// untyped errors are not shown to the client, the log keeps the cause
+	if status >= http.StatusInternalServerError {
+		logger.FromContext(c.Request.Context()).Error("Request failed", "code", serviceErr.Code, "error", err)
+	}
+
+	problem := dto.Problem{
+		Type:     "/problems/" + string(serviceErr.Code),
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   serviceErr.Message,
+		Instance: c.Request.URL.Path,
+		Code:     string(serviceErr.Code),
+	}
+	for _, field := range serviceErr.Fields {
+		problem.Errors = append(problem.Errors, dto.ProblemFieldError{
+			Field:   field.Field,
+			Message: field.Message,
+		})
+	}
+
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(status, problem)
+}
+
+// RouteNotFound answers unknown routes with a problem instead of gin's plain text 404
+func RouteNotFound(c *gin.Context) {
+	WriteProblem(c, &services.Error{Code: services.CodeRouteNotFound, Message: "route not found"})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"matching_system/pkg/logger"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID correlating a request's log lines, in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern keeps IDs from callers short and safe to log
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID gives every request an ID: the caller's X-Request-ID if it sent a usable one, a new one
+// otherwise. The ID is sent back in the response, and set on the request so it is passed on when the
+// request is proxied. The request's context carries l with the ID, and with the trace ID when the
+// request is traced, for handlers and services to log with, see logger.FromContext.
+func RequestID(l *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Request.Header.Set(RequestIDHeader, id)
+		c.Header(RequestIDHeader, id)
+
+		ctx := c.Request.Context()
+		requestLogger := l.With("request_id", id)
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			requestLogger = requestLogger.With("trace_id", span.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(logger.NewContext(ctx, requestLogger))
+		c.Next()
+	}
+}
+
+// AccessLog logs every request once it is answered, with the logger of RequestID
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		logger.FromContext(c.Request.Context()).Info("Request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"duration", time.Since(start),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"matching_system/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		kept   bool
+	}{
+		{"none", "", false},
+		{"caller's", "req-42", true},
+		{"unsafe", "a b\nc", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			var out bytes.Buffer
+			router := gin.New()
+			router.Use(RequestID(logger.New(logger.WithFormat(logger.JSON), logger.WithOutput(&out))))
+			var seen string
+			router.GET("/people", func(c *gin.Context) {
+				seen = c.GetHeader(RequestIDHeader)
+				logger.FromContext(c.Request.Context()).Info("Handled")
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/people", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			require.NotEmpty(t, id)
+			if tt.kept {
+				assert.Equal(t, tt.header, id)
+			} else {
+				assert.NotEqual(t, tt.header, id)
+			}
+			assert.Equal(t, id, seen, "proxied requests pass the ID on")
+
+			var line map[string]any
+			require.NoError(t, json.Unmarshal(out.Bytes(), &line))
+			assert.Equal(t, "Handled", line["msg"])
+			assert.Equal(t, id, line["request_id"])
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var out bytes.Buffer
+	router := gin.New()
+	router.Use(RequestID(logger.New(logger.WithFormat(logger.JSON), logger.WithOutput(&out))), AccessLog())
+	router.GET("/people/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/people/42", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	router.ServeHTTP(w, req)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "Request", line["msg"])
+	assert.Equal(t, "req-42", line["request_id"])
+	assert.Equal(t, "GET", line["method"])
+	assert.Equal(t, "/people/42", line["path"])
+	assert.Equal(t, "/people/:id", line["route"])
+	assert.Equal(t, float64(http.StatusNotFound), line["status"])
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Writable rejects requests that may change something with the error of check, if any, such as on a
+// read-only follower. GET, HEAD and OPTIONS requests always pass.
+func Writable(check func() error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if err := check(); err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
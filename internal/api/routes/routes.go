@@ -1,24 +1,119 @@
 package routes
 
 import (
+	"matching_system/internal/api/graphqlapi"
 	"matching_system/internal/api/handlers"
+	"matching_system/internal/api/middleware"
+	"matching_system/internal/audit"
+	"matching_system/internal/cluster"
+	"matching_system/internal/events"
+	"matching_system/internal/metrics"
+	"matching_system/internal/replication"
+	"matching_system/internal/tracing"
+	"matching_system/internal/webhooks"
+	"matching_system/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func Setup() *gin.Engine {
-	router := gin.Default()
+// Setup builds the router; follower is nil on a leader, and makes the server read-only until promoted.
+// node is nil outside a cluster; in one, registry is the node. With m, requests are measured and the
+// metrics served at /metrics. Requests are logged with l, or the default logger when it is nil. With
+// auditLog, the log the match services record writes to is served at /v1/admin/audit.
+func Setup(registry handlers.PoolRegistry, broker *events.Broker, webhookStore *webhooks.Store, follower *replication.Follower, node *cluster.Node, m *metrics.Metrics, l *logger.Logger, auditLog *audit.Log) *gin.Engine {
+	if l == nil {
+		l = logger.Default()
+	}
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(tracing.Middleware())
+	router.Use(middleware.RequestID(l), middleware.AccessLog(), middleware.AuditSource())
+	if m != nil {
+		// ahead of Problems, so it sees the status of rendered problems
+		router.Use(m.Middleware())
+		router.GET("/metrics", gin.WrapH(m.Handler()))
+	}
+	router.Use(middleware.Problems())
+	router.NoRoute(middleware.RouteNotFound)
 
 	// Health check
 	router.GET("/health", handlers.HealthCheck)
 
-	matchHandler := handlers.NewMatchHandler()
+	matchService := registry.Default()
+	matchHandler := handlers.NewMatchHandler(matchService)
+	notificationHandler := handlers.NewNotificationHandler(matchService, broker)
+	webhookHandler := handlers.NewWebhookHandler(webhookStore)
+	transferHandler := handlers.NewTransferHandler(matchService)
+	poolHandler := handlers.NewPoolHandler(registry)
+	replicationHandler := handlers.NewReplicationHandler(matchService, follower)
+	graphqlHandler := graphqlapi.NewHandler(matchService, graphqlapi.WithWriteCheck(follower.CheckWritable))
+	// a follower only takes reads until it is promoted; webhook subscriptions are its own, not the leader's
+	writable := middleware.Writable(follower.CheckWritable)
 
-	router.POST("/add-single-person-and-match", matchHandler.AddSinglePersonAndMatch)
-	router.DELETE("/remove-single-person/:id", matchHandler.RemoveSinglePerson)
-	router.GET("/query-single-people", matchHandler.QuerySinglePeople)
+	// the pool routes work on one pool: the default one at /v1, any other under /v1/pools/{pool}
+	poolRoutes := func(group *gin.RouterGroup) {
+		group.POST("/people", matchHandler.AddSinglePersonAndMatch)
+		group.GET("/people", matchHandler.ListPeople)
+		group.GET("/people/:id", matchHandler.GetSinglePerson)
+		group.PUT("/people/:id", matchHandler.UpdateSinglePerson)
+		group.DELETE("/people/:id", matchHandler.RemoveSinglePerson)
+		group.GET("/people/:id/notifications", notificationHandler.SubscribePerson)
+		group.GET("/matches", matchHandler.QueryMatches)
+		group.GET("/admin/export/people", transferHandler.ExportPeople)
+		group.GET("/admin/export/matches", transferHandler.ExportMatches)
+		group.POST("/admin/import/people", transferHandler.ImportPeople)
+		group.GET("/replication/stream", replicationHandler.StreamOperations)
+	}
+
+	v1 := router.Group("/v1")
+	{
+		poolRoutes(v1.Group("", writable, poolHandler.DefaultScope()))
+
+		v1.POST("/webhooks", webhookHandler.CreateWebhook)
+		v1.GET("/webhooks", webhookHandler.ListWebhooks)
+		v1.GET("/webhooks/:id", webhookHandler.GetWebhook)
+		v1.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+		v1.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
+
+		v1.POST("/pools", writable, poolHandler.CreatePool)
+		v1.GET("/pools", poolHandler.ListPools)
+		v1.GET("/pools/:pool", poolHandler.GetPool)
+		v1.DELETE("/pools/:pool", writable, poolHandler.DeletePool)
+		poolRoutes(v1.Group("/pools/:pool", writable, poolHandler.Scope()))
+		// GraphQL queries are POSTed too, its mutations are checked for writability one by one
+		v1.POST("/pools/:pool/graphql", poolHandler.Scope(), graphqlHandler.Serve)
+		v1.GET("/pools/:pool/graphql", poolHandler.Scope(), graphqlHandler.Serve)
+
+		v1.GET("/replication", replicationHandler.GetStatus)
+		v1.POST("/replication/promote", replicationHandler.Promote)
+
+		if auditLog != nil {
+			auditHandler := handlers.NewAuditHandler(auditLog)
+			v1.GET("/admin/audit", auditHandler.QueryAudit)
+		}
+
+		if node != nil {
+			clusterHandler := handlers.NewClusterHandler(node)
+			v1.GET("/cluster", clusterHandler.GetStatus)
+			v1.POST("/cluster/apply", clusterHandler.Apply)
+		}
+	}
+
+	// Legacy RPC-style routes, kept as deprecated aliases of /v1
+	legacy := router.Group("", writable, poolHandler.DefaultScope())
+	legacy.POST("/add-single-person-and-match", middleware.Deprecated("/v1/people"), matchHandler.AddSinglePersonAndMatch)
+	legacy.DELETE("/remove-single-person/:id", middleware.Deprecated("/v1/people/:id"), matchHandler.RemoveSinglePerson)
+	legacy.GET("/query-single-people", middleware.Deprecated("/v1/people"), matchHandler.QuerySinglePeople)
+	legacy.GET("/single-person/:id", middleware.Deprecated("/v1/people/:id"), matchHandler.GetSinglePerson)
+	legacy.PUT("/update-single-person/:id", middleware.Deprecated("/v1/people/:id"), matchHandler.UpdateSinglePerson)
+
+	eventStreamHandler := handlers.NewEventStreamHandler(broker)
+	router.GET("/events", eventStreamHandler.StreamEvents)
+
+	router.POST("/graphql", graphqlHandler.Serve)
+	router.GET("/graphql", graphqlHandler.Serve)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	return router
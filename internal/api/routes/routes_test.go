@@ -0,0 +1,178 @@
+package routes
+
+import (
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/events"
+	"matching_system/internal/metrics"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/internal/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	broker := events.NewBroker(events.DefaultHistorySize)
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithEvents(broker)))
+	require.NoError(t, err)
+	return Setup(registry, broker, webhooks.NewStore(), nil, nil, nil, nil, nil)
+}
+
+func serve(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSetup_LegacyRoutesAreDeprecated(t *testing.T) {
+	router := setupRouter(t)
+
+	w := serve(router, "GET", "/query-single-people?limit=1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "true", w.Header().Get("Deprecation"))
+
+	w = serve(router, "GET", "/single-person/42", "")
+	assert.Equal(t, `</v1/people/42>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = serve(router, "GET", "/v1/people", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Deprecation"))
+}
+
+func TestSetup_PoolsAreIsolated(t *testing.T) {
+	router := setupRouter(t)
+
+	w := serve(router, "POST", "/v1/pools", `{"name":"eu","rules":{"min_height_gap":5,"max_height_gap":20}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = serve(router, "POST", "/v1/pools", `{"name":"eu"}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// a woman in the default pool is invisible to a man in eu
+	serve(router, "POST", "/v1/people", `{"name":"Alice","height":160,"gender":"female","wanted_dates":1}`)
+	w = serve(router, "POST", "/v1/pools/eu/people", `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var added dto.AddPersonResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	assert.Empty(t, added.Matches)
+
+	// eu's rules want a gap of 5 to 20cm: 176cm is too close, 165cm is fine
+	serve(router, "POST", "/v1/pools/eu/people", `{"name":"Carol","height":176,"gender":"female","wanted_dates":1}`)
+	w = serve(router, "POST", "/v1/pools/eu/people", `{"name":"Eve","height":165,"gender":"female","wanted_dates":1}`)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	require.Len(t, added.Matches, 1)
+	assert.Equal(t, "Bob", added.Matches[0].Person2.Name)
+
+	var people dto.QueryPeopleResponse
+	w = serve(router, "GET", "/v1/people", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &people))
+	assert.Len(t, people.People, 1, "only Alice is in the default pool")
+	w = serve(router, "GET", "/v1/pools/default/people", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &people))
+	assert.Len(t, people.People, 1, "/v1/pools/default is the same pool as /v1")
+
+	// deleting eu takes its people with it
+	assert.Equal(t, http.StatusNoContent, serve(router, "DELETE", "/v1/pools/eu", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(router, "GET", "/v1/pools/eu/people", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, "DELETE", "/v1/pools/default", "").Code)
+}
+
+func TestSetup_GraphQLIsScopedToPools(t *testing.T) {
+	router := setupRouter(t)
+	require.Equal(t, http.StatusCreated, serve(router, "POST", "/v1/pools", `{"name":"eu"}`).Code)
+
+	w := serve(router, "POST", "/v1/pools/eu/graphql", `{"query":"mutation { addPerson(input: {name: \"Bob\", height: 180, gender: MALE, wantedDates: 1}) { person { id } } }"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "errors")
+
+	var people dto.QueryPeopleResponse
+	w = serve(router, "GET", "/v1/pools/eu/people", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &people))
+	assert.Len(t, people.People, 1)
+
+	w = serve(router, "POST", "/graphql", `{"query":"{ people { name } }"}`)
+	assert.JSONEq(t, `{"data":{"people":[]}}`, w.Body.String(), "/graphql serves the default pool")
+	w = serve(router, "POST", "/v1/pools/eu/graphql", `{"query":"{ people { name } }"}`)
+	assert.JSONEq(t, `{"data":{"people":[{"name":"Bob"}]}}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, serve(router, "POST", "/v1/pools/us/graphql", `{"query":"{ people { name } }"}`).Code)
+}
+
+func TestSetup_ServesMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	broker := events.NewBroker(events.DefaultHistorySize)
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithEvents(broker)))
+	require.NoError(t, err)
+	m := metrics.New()
+	m.WatchPools(registry)
+	router := Setup(registry, broker, webhooks.NewStore(), nil, nil, m, nil, nil)
+
+	serve(router, "POST", "/v1/people", `{"name":"Alice","height":160,"gender":"female","wanted_dates":1}`)
+	serve(router, "POST", "/v1/people", `{"name":"Bob"}`)
+
+	w := serve(router, "GET", "/metrics", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `matching_people_active{gender="female",pool="default"} 1`)
+	assert.Contains(t, w.Body.String(), `matching_http_request_duration_seconds_count{method="POST",route="/v1/people",status="201"} 1`)
+	assert.Contains(t, w.Body.String(), `matching_validation_failures_total{method="POST",route="/v1/people"} 1`)
+}
+
+func TestSetup_AuditsWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.log"))
+	require.NoError(t, err)
+	defer auditLog.Close()
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithAudit(auditLog.Record)))
+	require.NoError(t, err)
+	router := Setup(registry, events.NewBroker(events.DefaultHistorySize), webhooks.NewStore(), nil, nil, nil, nil, auditLog)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/v1/people", strings.NewReader(`{"name":"Alice","height":160,"gender":"female","wanted_dates":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(audit.ActorHeader, "alice@example.com")
+	req.Header.Set("X-Request-ID", "req-42")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var added dto.AddPersonResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &added))
+	w = serve(router, "DELETE", "/v1/people/"+added.Person.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = serve(router, "GET", "/v1/admin/audit?person_id="+added.Person.ID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response dto.QueryAuditResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Entries, 2)
+
+	remove, add := response.Entries[0], response.Entries[1]
+	assert.Equal(t, audit.OpRemove, remove.Operation)
+	assert.Equal(t, audit.AnonymousActor, remove.Actor)
+	assert.NotEmpty(t, remove.RequestID)
+	assert.Equal(t, audit.OpAdd, add.Operation)
+	assert.Equal(t, pools.DefaultPool, add.Pool)
+	assert.Equal(t, "alice@example.com", add.Actor)
+	assert.Equal(t, "req-42", add.RequestID)
+	assert.Equal(t, added.Person, *add.After)
+
+	w = serve(router, "GET", "/v1/admin/audit?limit=0", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSetup_AuditLogIsOptional(t *testing.T) {
+	router := setupRouter(t)
+
+	w := serve(router, "GET", "/v1/admin/audit", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"context"
+	"matching_system/internal/models"
+	"time"
+	"unicode/utf8"
+)
+
+// Op is the kind of write an entry records
+type Op string
+
+const (
+	OpAdd    Op = "add"
+	OpUpdate Op = "update"
+	OpRemove Op = "remove"
+	// OpImport adds a person from an import file
+	OpImport Op = "import"
+	// OpDeletePool deletes a whole pool, its entry names no person
+	OpDeletePool Op = "delete_pool"
+)
+
+// Ops lists every Op, in the order they are documented
+var Ops = []Op{OpAdd, OpUpdate, OpRemove, OpImport, OpDeletePool}
+
+const (
+	// ActorHeader names the caller of an HTTP request or gRPC call, as set by the proxy that
+	// authenticated it
+	ActorHeader = "X-Actor"
+	// AnonymousActor made a request that named no one
+	AnonymousActor = "anonymous"
+	// SystemActor made the writes outside of any request
+	SystemActor = "system"
+	// maxActorLength keeps actors from callers short enough to store and show
+	maxActorLength = 256
+)
+
+// Entry records a write made on request: who asked for it, when, and the person before and after.
+// A write that matches people has an entry for each partner it changed too.
+type Entry struct {
+	// ID increases with every entry of a log, newer entries have higher IDs
+	ID        uint64    `json:"id"`
+	At        time.Time `json:"at"`
+	Pool      string    `json:"pool"`
+	Operation Op        `json:"operation"`
+	PersonID  string    `json:"person_id"`
+	Actor     string    `json:"actor"`
+	RequestID string    `json:"request_id,omitempty"`
+	// Before is nil for adds and imports, After for removes. After is the person once matched.
+	Before *models.Person `json:"before,omitempty"`
+	After  *models.Person `json:"after,omitempty"`
+	// LogIndex is the cluster log entry of the write, in a cluster
+	LogIndex uint64 `json:"log_index,omitempty"`
+}
+
+// Source is who a write is made for, carried from the request to the match service in its context
+type Source struct {
+	Actor     string
+	RequestID string
+	// LogIndex is the cluster log entry being applied, see Entry
+	LogIndex uint64
+}
+
+// NewSource returns the source of a request by actor, AnonymousActor when actor is empty
+func NewSource(actor, requestID string) Source {
+	if actor == "" {
+		actor = AnonymousActor
+	}
+	if len(actor) > maxActorLength {
+		// cut on a rune boundary, so the actor stays valid UTF-8
+		cut := maxActorLength
+		for cut > 0 && !utf8.RuneStart(actor[cut]) {
+			cut--
+		}
+		actor = actor[:cut]
+	}
+	return Source{Actor: actor, RequestID: requestID}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx whose writes are made for source
+func NewContext(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, contextKey{}, source)
+}
+
+// FromContext returns the source ctx carries. Writes without one are made by SystemActor.
+func FromContext(ctx context.Context) Source {
+	source, _ := ctx.Value(contextKey{}).(Source)
+	if source.Actor == "" {
+		source.Actor = SystemActor
+	}
+	return source
+}
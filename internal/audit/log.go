@@ -0,0 +1,368 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize is the size at which the log file is rotated
+	DefaultMaxSize = 100 << 20
+	// DefaultMaxFiles is how many files are kept, the current one included
+	DefaultMaxFiles = 5
+	// maxEntrySize guards the reader against a garbage line
+	maxEntrySize = 1 << 20
+	// scanChunkSize is how much of a file a query reads at a time
+	scanChunkSize = 64 << 10
+)
+
+// Query selects entries of a log, newest first. Empty fields match every entry.
+type Query struct {
+	Pool      string
+	PersonID  string
+	Actor     string
+	Operation Op
+	RequestID string
+	// Since and Until bound the entries' times; Since is included, Until is not
+	Since time.Time
+	Until time.Time
+	// Before only matches entries older than the entry with that ID, to read the next page
+	Before uint64
+	Limit  int
+}
+
+func (q Query) matches(entry Entry) bool {
+	return (q.Pool == "" || entry.Pool == q.Pool) &&
+		(q.PersonID == "" || entry.PersonID == q.PersonID) &&
+		(q.Actor == "" || entry.Actor == q.Actor) &&
+		(q.Operation == "" || entry.Operation == q.Operation) &&
+		(q.RequestID == "" || entry.RequestID == q.RequestID) &&
+		(q.Since.IsZero() || !entry.At.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.At.Before(q.Until)) &&
+		(q.Before == 0 || entry.ID < q.Before)
+}
+
+// Log is an append-only file of JSON entries, one per line. When the file grows past its maximum
+// size it is renamed to path.1, the older files to path.2 and so on, and the oldest is deleted.
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	// mu guards the file being written
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	lastID uint64
+	// recordedIndex is the last cluster log entry recorded before the log was opened, see Record
+	recordedIndex uint64
+	// files is held by rotations, and by queries while they open the files
+	files sync.RWMutex
+}
+
+// Option configures a Log
+type Option func(*Log)
+
+// WithMaxSize rotates the file once it would grow past size bytes, DefaultMaxSize by default
+func WithMaxSize(size int64) Option {
+	return func(l *Log) {
+		l.maxSize = size
+	}
+}
+
+// WithMaxFiles keeps n files, the current one included, DefaultMaxFiles by default
+func WithMaxFiles(n int) Option {
+	return func(l *Log) {
+		l.maxFiles = max(n, 1)
+	}
+}
+
+// Open opens the log at path for appending, creating it if needed. IDs carry on from the entries
+// already in the log.
+func Open(path string, opts ...Option) (*Log, error) {
+	l := &Log{path: path, maxSize: DefaultMaxSize, maxFiles: DefaultMaxFiles}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// the newest entry is in the newest file holding any
+	for i := 0; i < l.maxFiles && l.lastID == 0; i++ {
+		entries, err := readEntries(l.name(i))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			l.lastID = max(l.lastID, entry.ID)
+			l.recordedIndex = max(l.recordedIndex, entry.LogIndex)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	l.file, l.size = file, info.Size()
+	// a line torn by a crash is skipped by readers, the next entry starts on a line of its own
+	if l.size > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, l.size-1); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if last[0] != '\n' {
+			if _, err := file.Write([]byte{'\n'}); err != nil {
+				file.Close()
+				return nil, err
+			}
+			l.size++
+		}
+	}
+	return l, nil
+}
+
+// name returns the path of the ith file, the current one being 0
+func (l *Log) name(i int) string {
+	if i == 0 {
+		return l.path
+	}
+	return l.path + "." + strconv.Itoa(i)
+}
+
+// Record appends entries with a single fsync, giving each the next ID. In a cluster every node
+// records the writes it applies, and applies again on restart the log entries it recorded before,
+// so entries of cluster log entries recorded before the log was opened are dropped.
+func (l *Log) Record(entries ...Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		if entry.LogIndex != 0 && entry.LogIndex <= l.recordedIndex {
+			continue
+		}
+		l.lastID++
+		entry.ID = l.lastID
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	if l.size > 0 && l.size+int64(buf.Len()) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(buf.Bytes())
+	l.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// rotate moves the current file aside and starts a new one. The files are shifted up to
+// path.<maxFiles>, beyond those kept, which is deleted once the new file is open: the current file
+// is written until then, so a rotation that fails leaves the log as it was.
+func (l *Log) rotate() error {
+	l.files.Lock()
+	defer l.files.Unlock()
+
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(l.name(i), l.name(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	// the open file follows the rename
+	if err := os.Rename(l.path, l.name(1)); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		if restoreErr := os.Rename(l.name(1), l.path); restoreErr != nil {
+			return errors.Join(err, restoreErr)
+		}
+		return err
+	}
+	// every entry of the old file is synced already; a file left beyond those kept is replaced by
+	// the next rotation
+	l.file.Close()
+	os.Remove(l.name(l.maxFiles))
+	l.file, l.size = file, 0
+	return nil
+}
+
+// Query returns the entries matching q, newest first, and at most q.Limit of them when it is set.
+// next is the Before of the next page, 0 when there is none. The files are read from their end, up
+// to the size they had when the query started, and only until the page is filled.
+func (l *Log) Query(q Query) (page []Entry, next uint64, err error) {
+	files, err := l.snapshot()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() {
+		for _, f := range files {
+			f.file.Close()
+		}
+	}()
+
+	for _, f := range files {
+		err := scanBackwards(f.file, f.size, func(entry Entry) bool {
+			if !q.matches(entry) {
+				return true
+			}
+			if q.Limit > 0 && len(page) == q.Limit {
+				next = page[len(page)-1].ID
+				return false
+			}
+			page = append(page, entry)
+			return true
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		if next != 0 {
+			break
+		}
+	}
+	return page, next, nil
+}
+
+// openFile is a file of the log opened by a query, with the size it had then
+type openFile struct {
+	file *os.File
+	size int64
+}
+
+// snapshot opens the files of the log, newest first. A rotation cannot move them while they are
+// opened, and the handles keep reading the same files after one.
+func (l *Log) snapshot() ([]openFile, error) {
+	l.files.RLock()
+	defer l.files.RUnlock()
+
+	var files []openFile
+	for i := 0; i < l.maxFiles; i++ {
+		file, err := os.Open(l.name(i))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			var info os.FileInfo
+			if info, err = file.Stat(); err == nil {
+				files = append(files, openFile{file: file, size: info.Size()})
+				continue
+			}
+			file.Close()
+		}
+		for _, f := range files {
+			f.file.Close()
+		}
+		return nil, err
+	}
+	return files, nil
+}
+
+// Close closes the file; the log must not be used afterwards
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// scanBackwards calls fn with the entries in the first size bytes of file, newest first, until it
+// returns false. Lines that do not decode, such as one being written, are skipped.
+func scanBackwards(file *os.File, size int64, fn func(Entry) bool) error {
+	// tail is the end of a line whose start is not read yet, skip is set once it grows too long to
+	// be an entry and is dropped up to its start
+	var tail []byte
+	skip := false
+	for pos := size; pos > 0; {
+		n := min(pos, scanChunkSize)
+		pos -= n
+		data := make([]byte, n, n+int64(len(tail)))
+		if _, err := file.ReadAt(data, pos); err != nil {
+			return err
+		}
+		data = append(data, tail...)
+		for {
+			i := bytes.LastIndexByte(data, '\n')
+			if i < 0 {
+				break
+			}
+			if !skip && !decodeLine(data[i+1:], fn) {
+				return nil
+			}
+			skip = false
+			data = data[:i]
+		}
+		tail = data
+		if len(tail) > maxEntrySize {
+			tail, skip = nil, true
+		}
+	}
+	if !skip {
+		decodeLine(tail, fn)
+	}
+	return nil
+}
+
+// decodeLine calls fn with the entry on line and returns its result, or true when there is none
+func decodeLine(line []byte, fn func(Entry) bool) bool {
+	if len(line) == 0 || len(line) > maxEntrySize {
+		return true
+	}
+	var entry Entry
+	if json.Unmarshal(line, &entry) != nil {
+		return true
+	}
+	return fn(entry)
+}
+
+// readEntries reads the entries of the file at path, oldest first, skipping lines that do not
+// decode, such as one being written
+func readEntries(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && len(line) <= maxEntrySize {
+			var entry Entry
+			if json.Unmarshal(line, &entry) == nil {
+				entries = append(entries, entry)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
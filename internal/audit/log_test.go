@@ -0,0 +1,215 @@
+package audit
+
+import (
+	"matching_system/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func entry(minute int, pool string, op Op, personID, actor string) Entry {
+	return Entry{
+		At:        start.Add(time.Duration(minute) * time.Minute),
+		Pool:      pool,
+		Operation: op,
+		PersonID:  personID,
+		Actor:     actor,
+		After:     &models.Person{ID: personID, Name: "Alice", Version: 1},
+	}
+}
+
+func ids(entries []Entry) []uint64 {
+	var ids []uint64
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+func TestLog_Query(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.log"))
+	require.NoError(t, err)
+	defer l.Close()
+	require.NoError(t, l.Record(
+		entry(0, "eu", OpAdd, "p1", "alice"),
+		entry(1, "eu", OpUpdate, "p1", "bob"),
+		entry(2, "us", OpAdd, "p2", "alice"),
+	))
+	require.NoError(t, l.Record(entry(3, "eu", OpRemove, "p1", "alice")))
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []uint64
+	}{
+		{"all, newest first", Query{}, []uint64{4, 3, 2, 1}},
+		{"pool", Query{Pool: "eu"}, []uint64{4, 2, 1}},
+		{"person", Query{PersonID: "p2"}, []uint64{3}},
+		{"actor", Query{Actor: "alice"}, []uint64{4, 3, 1}},
+		{"operation", Query{Operation: OpUpdate}, []uint64{2}},
+		{"since is included", Query{Since: start.Add(2 * time.Minute)}, []uint64{4, 3}},
+		{"until is not", Query{Until: start.Add(2 * time.Minute)}, []uint64{2, 1}},
+		{"before", Query{Before: 3}, []uint64{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, next, err := l.Query(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ids(entries))
+			assert.Zero(t, next)
+		})
+	}
+}
+
+func TestLog_QueryPages(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "audit.log"))
+	require.NoError(t, err)
+	defer l.Close()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, l.Record(entry(i, "eu", OpAdd, "p", "alice")))
+	}
+
+	var pages [][]uint64
+	query := Query{Limit: 2}
+	for {
+		entries, next, err := l.Query(query)
+		require.NoError(t, err)
+		pages = append(pages, ids(entries))
+		if next == 0 {
+			break
+		}
+		query.Before = next
+	}
+	assert.Equal(t, [][]uint64{{5, 4}, {3, 2}, {1}}, pages)
+}
+
+func TestLog_QueryReadsFilesBackwards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	require.NoError(t, err)
+	defer l.Close()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, l.Record(entry(i, "eu", OpAdd, "p", "alice")))
+	}
+	// a garbage line longer than any entry, spanning several reads
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(strings.Repeat("x", 2*maxEntrySize) + "\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, l.Record(entry(1000, "eu", OpAdd, "p", "alice")))
+
+	entries, next, err := l.Query(Query{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1001)
+	assert.Equal(t, uint64(1001), entries[0].ID)
+	assert.Equal(t, uint64(1), entries[1000].ID)
+	assert.Zero(t, next)
+
+	entries, next, err = l.Query(Query{Before: 500, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{499, 498, 497}, ids(entries))
+	assert.Equal(t, uint64(497), next)
+}
+
+func TestLog_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, WithMaxSize(300), WithMaxFiles(3))
+	require.NoError(t, err)
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, l.Record(entry(i, "eu", OpAdd, "p", "alice")))
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(300))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "only 3 files are kept")
+
+	// the query reads across the files, the oldest entries are gone with the oldest file
+	entries, _, err := l.Query(Query{})
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+	assert.Equal(t, uint64(10), entries[0].ID)
+	assert.Equal(t, int(10-entries[len(entries)-1].ID+1), len(entries), "entries are contiguous")
+	assert.Less(t, len(entries), 10)
+}
+
+func TestLog_FailedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path, WithMaxSize(300), WithMaxFiles(2))
+	require.NoError(t, err)
+	defer l.Close()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Record(entry(i, "eu", OpAdd, "p", "alice")))
+	}
+	require.FileExists(t, path+".1")
+
+	// path.1 cannot be moved aside
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".2", "blocked"), 0o755))
+	require.Error(t, l.Record(entry(3, "eu", OpAdd, "p", "alice")))
+
+	require.NoError(t, os.RemoveAll(path+".2"))
+	require.NoError(t, l.Record(entry(4, "eu", OpAdd, "p", "alice")))
+	entries, _, err := l.Query(Query{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), entries[0].ID, "the log goes on once the rotation succeeds")
+	assert.NoFileExists(t, path+".2", "only 2 files are kept")
+}
+
+func TestLog_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	require.NoError(t, err)
+	replicated := entry(0, "eu", OpAdd, "p1", "alice")
+	replicated.LogIndex = 7
+	require.NoError(t, l.Record(entry(0, "eu", OpAdd, "p0", "alice"), replicated))
+	require.NoError(t, l.Close())
+
+	// a crash left half a line behind
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id":3,"pool":"eu`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	l, err = Open(path)
+	require.NoError(t, err)
+	defer l.Close()
+	// a cluster node applying its log again on restart records nothing twice
+	again := replicated
+	next := entry(1, "eu", OpAdd, "p2", "alice")
+	next.LogIndex = 8
+	require.NoError(t, l.Record(again, next))
+
+	entries, _, err := l.Query(Query{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 2, 1}, ids(entries), "IDs carry on")
+	assert.Equal(t, "p2", entries[0].PersonID)
+	assert.Equal(t, uint64(8), entries[0].LogIndex)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "\n"), "the torn line is ended, not continued")
+}
+
+func TestNewSource(t *testing.T) {
+	assert.Equal(t, Source{Actor: AnonymousActor, RequestID: "req-1"}, NewSource("", "req-1"))
+	assert.Len(t, NewSource(strings.Repeat("a", 300), "").Actor, maxActorLength)
+
+	actor := NewSource("a"+strings.Repeat("é", 200), "").Actor
+	assert.True(t, utf8.ValidString(actor), "a rune is not cut in half")
+	assert.Len(t, actor, maxActorLength-1)
+}
@@ -0,0 +1,246 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/internal/snapshot"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hashicorp/raft"
+)
+
+// Op is the kind of change a command makes
+type Op string
+
+const (
+	OpCreatePool Op = "create_pool"
+	OpDeletePool Op = "delete_pool"
+	OpAdd        Op = "add"
+	OpUpdate     Op = "update"
+	OpRemove     Op = "remove"
+	OpImport     Op = "import"
+)
+
+// Command is a write to the pools, as it is kept in the raft log
+type Command struct {
+	Op   Op     `json:"op"`
+	Pool string `json:"pool"`
+	// At is stamped by the leader; every node applies the command as of that time
+	At              time.Time                `json:"at"`
+	Rules           models.MatchRules        `json:"rules,omitempty"`
+	PersonID        string                   `json:"person_id,omitempty"`
+	ExpectedVersion int64                    `json:"expected_version,omitempty"`
+	IdempotencyKey  string                   `json:"idempotency_key,omitempty"`
+	Add             *dto.AddPersonRequest    `json:"add,omitempty"`
+	Update          *dto.UpdatePersonRequest `json:"update,omitempty"`
+	People          []dto.ImportPerson       `json:"people,omitempty"`
+	// Lines are where People were read, which their JSON leaves out
+	Lines       []int `json:"lines,omitempty"`
+	RunMatching bool  `json:"run_matching,omitempty"`
+	DryRun      bool  `json:"dry_run,omitempty"`
+	// Actor and RequestID are the audit source of the write, which every node records as it applies it
+	Actor     string `json:"actor,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Result is what applying a command returned, as the leader hands it back to the node that proposed it
+type Result struct {
+	Pool    *models.Pool    `json:"pool,omitempty"`
+	Person  *models.Person  `json:"person,omitempty"`
+	People  []models.Person `json:"people,omitempty"`
+	Matches []models.Match  `json:"matches,omitempty"`
+	Err     *services.Error `json:"error,omitempty"`
+	// Index is the log entry of the command, which the proposing node waits to apply
+	Index uint64 `json:"index,omitempty"`
+}
+
+func failed(err error) Result {
+	var serviceErr *services.Error
+	if !errors.As(err, &serviceErr) {
+		serviceErr = &services.Error{Code: services.CodeInternal, Message: err.Error()}
+	}
+	return Result{Err: serviceErr}
+}
+
+// err returns Err as an error, nil without one
+func (r Result) err() error {
+	if r.Err == nil {
+		return nil
+	}
+	return r.Err
+}
+
+// idSpace names the people added through the log, see fsm.newID
+var idSpace = uuid.MustParse("6f1c64a4-3b0e-4d55-9a57-2f4f0c5f8e21")
+
+// fsm applies the raft log to the pools of a registry. Raft calls Apply, Snapshot and Restore one at
+// a time, and they are the only writers of the pools, so the entry being applied is all the pools'
+// clock and ID source.
+type fsm struct {
+	registry *pools.Registry
+	index    uint64
+	at       time.Time
+	ids      int
+}
+
+func (f *fsm) now() time.Time {
+	return f.at
+}
+
+// newID derives the IDs of new people from the log entry that adds them, so all nodes agree on them
+func (f *fsm) newID() string {
+	f.ids++
+	return uuid.NewSHA1(idSpace, []byte(strconv.FormatUint(f.index, 10)+"/"+strconv.Itoa(f.ids))).String()
+}
+
+func (f *fsm) Apply(entry *raft.Log) interface{} {
+	var cmd Command
+	if err := json.Unmarshal(entry.Data, &cmd); err != nil {
+		// every node fails the same way on the same entry, so they stay in step
+		return failed(fmt.Errorf("undecodable log entry %d: %w", entry.Index, err))
+	}
+	f.index, f.at, f.ids = entry.Index, cmd.At, 0
+	return f.apply(cmd)
+}
+
+func (f *fsm) apply(cmd Command) Result {
+	ctx := audit.NewContext(context.Background(), audit.Source{Actor: cmd.Actor, RequestID: cmd.RequestID, LogIndex: f.index})
+	switch cmd.Op {
+	case OpCreatePool:
+		pool, err := f.registry.CreatePool(models.Pool{Name: cmd.Pool, Rules: cmd.Rules, CreatedAt: cmd.At})
+		if err != nil {
+			return failed(err)
+		}
+		return Result{Pool: &pool}
+	case OpDeletePool:
+		if err := f.registry.Delete(ctx, cmd.Pool); err != nil {
+			return failed(err)
+		}
+		return Result{}
+	}
+
+	_, service, err := f.registry.Get(cmd.Pool)
+	if err != nil {
+		return failed(err)
+	}
+	switch cmd.Op {
+	case OpAdd:
+		if cmd.Add == nil {
+			break
+		}
+		person, matches, err := service.AddSinglePersonAndMatchIdempotent(ctx, cmd.IdempotencyKey, *cmd.Add)
+		if err != nil {
+			return failed(err)
+		}
+		return Result{Person: person, Matches: matches}
+	case OpUpdate:
+		if cmd.Update == nil {
+			break
+		}
+		person, matches, err := service.UpdateSinglePerson(ctx, cmd.PersonID, *cmd.Update, cmd.ExpectedVersion)
+		if err != nil {
+			return failed(err)
+		}
+		return Result{Person: person, Matches: matches}
+	case OpRemove:
+		if err := service.RemoveSinglePersonIfMatch(ctx, cmd.PersonID, cmd.ExpectedVersion); err != nil {
+			return failed(err)
+		}
+		return Result{}
+	case OpImport:
+		for i := range cmd.People {
+			if i < len(cmd.Lines) {
+				cmd.People[i].Line = cmd.Lines[i]
+			}
+		}
+		people, matches, err := service.ImportPeople(ctx, cmd.People, cmd.RunMatching, cmd.DryRun)
+		if err != nil {
+			return failed(err)
+		}
+		return Result{People: people, Matches: matches}
+	}
+	return failed(fmt.Errorf("malformed %q command", cmd.Op))
+}
+
+// fsmSnapshot is every pool with its state, from which Restore rebuilds the registry
+type fsmSnapshot struct {
+	Pools []poolState `json:"pools"`
+}
+
+// poolState keeps the pool's idempotency keys along with its state: a node restored from the
+// snapshot has to replay a retried add like the nodes that remember its key
+type poolState struct {
+	Pool            models.Pool               `json:"pool"`
+	State           snapshot.State            `json:"state"`
+	IdempotencyKeys []services.IdempotencyKey `json:"idempotency_keys,omitempty"`
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	s := &fsmSnapshot{}
+	for _, pool := range f.registry.List() {
+		_, service, err := f.registry.Get(pool.Name)
+		if err != nil {
+			return nil, err
+		}
+		s.Pools = append(s.Pools, poolState{Pool: pool, State: service.Snapshot(), IdempotencyKeys: service.IdempotencyKeys()})
+	}
+	return s, nil
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
+
+// Restore replaces the pools with those of a snapshot, dropping the pools it does not have
+func (f *fsm) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+
+	var s fsmSnapshot
+	if err := json.NewDecoder(rc).Decode(&s); err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(s.Pools))
+	for _, saved := range s.Pools {
+		kept[saved.Pool.Name] = true
+		pool, service, err := f.registry.Get(saved.Pool.Name)
+		if err == nil && pool.Rules != saved.Pool.Rules {
+			if err := f.registry.Delete(context.Background(), pool.Name); err != nil {
+				return err
+			}
+			err = services.ErrPoolNotFound
+		}
+		if err != nil {
+			if _, err := f.registry.CreatePool(saved.Pool); err != nil {
+				return err
+			}
+			_, service, _ = f.registry.Get(saved.Pool.Name)
+		}
+		service.Reset(saved.State)
+		service.RestoreIdempotencyKeys(saved.IdempotencyKeys)
+	}
+	for _, pool := range f.registry.List() {
+		if !kept[pool.Name] && pool.Name != pools.DefaultPool {
+			if err := f.registry.Delete(context.Background(), pool.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFSM(t *testing.T) *fsm {
+	f := &fsm{}
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithClock(f.now), services.WithIDs(f.newID)), pools.WithClock(f.now))
+	require.NoError(t, err)
+	f.registry = registry
+	return f
+}
+
+func apply(t *testing.T, f *fsm, index uint64, cmd Command) Result {
+	data, err := json.Marshal(cmd)
+	require.NoError(t, err)
+	return f.Apply(&raft.Log{Index: index, Data: data}).(Result)
+}
+
+// sink collects a snapshot in memory
+type sink struct {
+	bytes.Buffer
+	cancelled bool
+}
+
+func (s *sink) ID() string    { return "test" }
+func (s *sink) Cancel() error { s.cancelled = true; return nil }
+func (s *sink) Close() error  { return nil }
+
+func TestFSM_AppliesTheSameOnEveryNode(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	log := []Command{
+		{Op: OpCreatePool, Pool: "tall", At: at, Rules: models.MatchRules{MinHeightGap: 5}},
+		{Op: OpAdd, Pool: "tall", At: at, Add: &dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1}},
+		{Op: OpAdd, Pool: "tall", At: at.Add(time.Second), Add: &dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1}},
+		{Op: OpImport, Pool: "default", At: at, People: []dto.ImportPerson{{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1}}, Lines: []int{2}},
+	}
+
+	var results [2][]Result
+	for node := range results {
+		f := newFSM(t)
+		for i, cmd := range log {
+			results[node] = append(results[node], apply(t, f, uint64(i+1), cmd))
+		}
+	}
+	assert.Equal(t, results[0], results[1], "nodes applying the same log should agree on IDs and times")
+	require.Nil(t, results[0][2].Err)
+	require.Len(t, results[0][2].Matches, 1)
+	assert.Equal(t, at.Add(time.Second), results[0][2].Matches[0].MatchedAt)
+
+	f := newFSM(t)
+	result := apply(t, f, 1, Command{Op: OpRemove, Pool: "default", PersonID: "missing"})
+	require.NotNil(t, result.Err)
+	assert.Equal(t, services.CodePersonNotFound, result.Err.Code)
+	result = f.Apply(&raft.Log{Index: 2, Data: []byte("not json")}).(Result)
+	assert.NotNil(t, result.Err)
+}
+
+func TestFSM_AuditsWritesAsProposed(t *testing.T) {
+	var entries []audit.Entry
+	record := func(recorded ...audit.Entry) error {
+		entries = append(entries, recorded...)
+		return nil
+	}
+	f := &fsm{}
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(
+		services.WithClock(f.now),
+		services.WithIDs(f.newID),
+		services.WithAudit(record),
+	), pools.WithClock(f.now), pools.WithAudit(record))
+	require.NoError(t, err)
+	f.registry = registry
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	add := Command{Op: OpAdd, Pool: "default", At: at, Actor: "alice", RequestID: "req-42", Add: &dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1}}
+	result := apply(t, f, 7, add)
+	require.Nil(t, result.Err)
+
+	require.Len(t, entries, 1)
+	assert.Equal(t, audit.OpAdd, entries[0].Operation)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Equal(t, "req-42", entries[0].RequestID)
+	assert.Equal(t, uint64(7), entries[0].LogIndex)
+	assert.Equal(t, at, entries[0].At, "the entry is dated by the leader")
+
+	require.Nil(t, apply(t, f, 8, Command{Op: OpCreatePool, Pool: "eu", At: at, Rules: services.DefaultRules}).Err)
+	require.Nil(t, apply(t, f, 9, Command{Op: OpDeletePool, Pool: "eu", At: at, Actor: "bob", RequestID: "req-43"}).Err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, audit.Entry{At: at, Pool: "eu", Operation: audit.OpDeletePool, Actor: "bob", RequestID: "req-43", LogIndex: 9}, entries[1])
+}
+
+func TestFSM_SnapshotAndRestore(t *testing.T) {
+	f := newFSM(t)
+	apply(t, f, 1, Command{Op: OpCreatePool, Pool: "tall", Rules: models.MatchRules{MinHeightGap: 5}})
+	apply(t, f, 2, Command{Op: OpAdd, Pool: "tall", Add: &dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2}})
+	apply(t, f, 3, Command{Op: OpAdd, Pool: "default", Add: &dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1}})
+
+	snapshot, err := f.Snapshot()
+	require.NoError(t, err)
+	s := &sink{}
+	require.NoError(t, snapshot.Persist(s))
+	assert.False(t, s.cancelled)
+
+	// a node that went its own way is brought back in line: tall is restored, other pools dropped
+	restored := newFSM(t)
+	apply(t, restored, 1, Command{Op: OpCreatePool, Pool: "tall", Rules: models.MatchRules{MinHeightGap: 1}})
+	apply(t, restored, 2, Command{Op: OpCreatePool, Pool: "short"})
+	require.NoError(t, restored.Restore(io.NopCloser(&s.Buffer)))
+
+	assert.Equal(t, f.registry.List(), restored.registry.List())
+	for _, pool := range f.registry.List() {
+		_, want, err := f.registry.Get(pool.Name)
+		require.NoError(t, err)
+		_, got, err := restored.registry.Get(pool.Name)
+		require.NoError(t, err)
+		assert.Equal(t, want.QuerySinglePeople(0), got.QuerySinglePeople(0), pool.Name)
+	}
+}
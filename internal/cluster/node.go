@@ -0,0 +1,377 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/pkg/logger"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+)
+
+const (
+	// ForwardPath is where a leader takes the commands other nodes forward to it
+	ForwardPath = "/v1/cluster/apply"
+
+	defaultApplyTimeout   = 10 * time.Second
+	defaultForwardTimeout = 15 * time.Second
+	retainedSnapshots     = 2
+	transportPoolSize     = 3
+	transportTimeout      = 10 * time.Second
+	appliedPollInterval   = time.Millisecond
+)
+
+// Peer is a voting node of the cluster
+type Peer struct {
+	ID string
+	// RaftAddr is where the node's raft transport listens, host:port
+	RaftAddr string
+	// HTTPAddr is the base URL of the node's API, where writes are forwarded to it when it leads
+	HTTPAddr string
+}
+
+// ParsePeers reads peers written as id=raftAddr=httpURL, separated by commas
+func ParsePeers(value string) ([]Peer, error) {
+	var peers []Peer
+	for _, entry := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("peer %q is not id=raftAddr=httpURL", entry)
+		}
+		peers = append(peers, Peer{ID: parts[0], RaftAddr: parts[1], HTTPAddr: strings.TrimSuffix(parts[2], "/")})
+	}
+	return peers, nil
+}
+
+// Config describes a node and the cluster it belongs to
+type Config struct {
+	// NodeID names this node among Peers
+	NodeID string
+	// Peers are all the voting nodes, this one included. A node without raft state bootstraps the
+	// cluster with them; every node must be given the same peers.
+	Peers []Peer
+	// Dir keeps the raft log and snapshots. Empty keeps them in memory, so a restarted node starts
+	// empty and catches up from the others.
+	Dir string
+	// SnapshotThreshold is how many new log entries trigger a snapshot, after which the log is
+	// compacted; zero keeps the raft default
+	SnapshotThreshold uint64
+}
+
+// Node is one server of a cluster that replicates the pools through a raft log. Reads are served
+// from the node's own pools; writes are committed by the leader, forwarded to it by the other nodes,
+// and applied by every node in log order.
+type Node struct {
+	config       Config
+	peers        map[string]Peer
+	logger       *logger.Logger
+	raft         *raft.Raft
+	fsm          *fsm
+	registry     *pools.Registry
+	closers      []func() error
+	transport    raft.Transport
+	raftConfig   *raft.Config
+	serviceOpts  []services.Option
+	registryOpts []pools.Option
+	client       *http.Client
+	applyTimeout time.Duration
+}
+
+// Option configures a Node
+type Option func(*Node)
+
+// WithTransport connects the node to the others with transport instead of TCP on its RaftAddr
+func WithTransport(transport raft.Transport) Option {
+	return func(n *Node) {
+		n.transport = transport
+	}
+}
+
+// WithRaftConfig starts from config instead of raft.DefaultConfig, such as for shorter timeouts
+func WithRaftConfig(config *raft.Config) Option {
+	return func(n *Node) {
+		n.raftConfig = config
+	}
+}
+
+// WithServiceOptions builds every pool's service with options, such as the shared event bus
+func WithServiceOptions(options ...services.Option) Option {
+	return func(n *Node) {
+		n.serviceOpts = options
+	}
+}
+
+// WithRegistryOptions builds the node's registry of pools with options, such as the audit log
+func WithRegistryOptions(options ...pools.Option) Option {
+	return func(n *Node) {
+		n.registryOpts = options
+	}
+}
+
+// WithHTTPClient forwards writes to the leader with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(n *Node) {
+		n.client = client
+	}
+}
+
+// WithApplyTimeout sets how long a write may wait for the cluster to commit it
+func WithApplyTimeout(timeout time.Duration) Option {
+	return func(n *Node) {
+		n.applyTimeout = timeout
+	}
+}
+
+// NewNode starts a node, restoring it from its raft state if it has any and bootstrapping the
+// cluster otherwise
+func NewNode(config Config, l *logger.Logger, opts ...Option) (*Node, error) {
+	n := &Node{
+		config:       config,
+		peers:        make(map[string]Peer, len(config.Peers)),
+		logger:       l,
+		fsm:          &fsm{},
+		client:       &http.Client{Timeout: defaultForwardTimeout},
+		applyTimeout: defaultApplyTimeout,
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	for _, peer := range config.Peers {
+		n.peers[peer.ID] = peer
+	}
+	self, ok := n.peers[config.NodeID]
+	if !ok {
+		return nil, fmt.Errorf("node %q is not one of the peers", config.NodeID)
+	}
+
+	// the pools only ever change by applying the log, at the time and with the IDs it says. The default
+	// pool exists before any entry, so every node dates it to the zero time.
+	serviceOpts := append(append([]services.Option(nil), n.serviceOpts...), services.WithClock(n.fsm.now), services.WithIDs(n.fsm.newID))
+	registryOpts := append(append([]pools.Option(nil), n.registryOpts...), pools.WithClock(n.fsm.now))
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(serviceOpts...), registryOpts...)
+	if err != nil {
+		return nil, err
+	}
+	n.registry = registry
+	n.fsm.registry = registry
+
+	raftConfig := raft.DefaultConfig()
+	if n.raftConfig != nil {
+		copied := *n.raftConfig
+		raftConfig = &copied
+	}
+	raftConfig.LocalID = raft.ServerID(config.NodeID)
+	if config.SnapshotThreshold > 0 {
+		raftConfig.SnapshotThreshold = config.SnapshotThreshold
+	}
+	raftConfig.Logger = hclog.New(&hclog.LoggerOptions{Name: "raft", Level: hclog.Warn, Output: os.Stderr})
+
+	logs, stable, snapshots, err := n.openStores()
+	if err != nil {
+		n.close()
+		return nil, err
+	}
+	if n.transport == nil {
+		transport, err := raft.NewTCPTransport(self.RaftAddr, nil, transportPoolSize, transportTimeout, os.Stderr)
+		if err != nil {
+			n.close()
+			return nil, fmt.Errorf("listen for raft: %w", err)
+		}
+		n.transport = transport
+		n.closers = append(n.closers, transport.Close)
+	}
+
+	hasState, err := raft.HasExistingState(logs, stable, snapshots)
+	if err != nil {
+		n.close()
+		return nil, err
+	}
+	n.raft, err = raft.NewRaft(raftConfig, n.fsm, logs, stable, snapshots, n.transport)
+	if err != nil {
+		n.close()
+		return nil, err
+	}
+	if !hasState {
+		servers := make([]raft.Server, 0, len(config.Peers))
+		for _, peer := range config.Peers {
+			servers = append(servers, raft.Server{ID: raft.ServerID(peer.ID), Address: raft.ServerAddress(peer.RaftAddr)})
+		}
+		// every node bootstraps with the same peers, so it does not matter which one wins
+		n.logger.Info("Bootstrapping cluster", "nodes", len(servers))
+		if err := n.raft.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+			n.Shutdown()
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (n *Node) openStores() (raft.LogStore, raft.StableStore, raft.SnapshotStore, error) {
+	if n.config.Dir == "" {
+		store := raft.NewInmemStore()
+		return store, store, raft.NewInmemSnapshotStore(), nil
+	}
+
+	if err := os.MkdirAll(n.config.Dir, 0o755); err != nil {
+		return nil, nil, nil, err
+	}
+	store, err := raftboltdb.NewBoltStore(filepath.Join(n.config.Dir, "raft.db"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("open raft log: %w", err)
+	}
+	n.closers = append(n.closers, store.Close)
+	snapshots, err := raft.NewFileSnapshotStore(n.config.Dir, retainedSnapshots, os.Stderr)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("open raft snapshots: %w", err)
+	}
+	return store, store, snapshots, nil
+}
+
+// Shutdown leaves the cluster running without this node and closes its raft state
+func (n *Node) Shutdown() error {
+	err := n.raft.Shutdown().Error()
+	if closeErr := n.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (n *Node) close() error {
+	var err error
+	for i := len(n.closers) - 1; i >= 0; i-- {
+		if closeErr := n.closers[i](); err == nil {
+			err = closeErr
+		}
+	}
+	n.closers = nil
+	return err
+}
+
+// Propose commits cmd to the log, through the leader, and returns what applying it returned once
+// this node has applied it too, so whoever wrote through the node reads their write back from it
+func (n *Node) Propose(cmd Command) Result {
+	if n.raft.State() == raft.Leader {
+		return n.Lead(cmd)
+	}
+	result := n.forward(cmd)
+	if result.Index > 0 {
+		n.waitApplied(result.Index)
+	}
+	return result
+}
+
+// waitApplied waits until this node applied the log up to index, or gives up after the apply timeout
+func (n *Node) waitApplied(index uint64) {
+	deadline := time.Now().Add(n.applyTimeout)
+	for n.raft.AppliedIndex() < index && time.Now().Before(deadline) {
+		time.Sleep(appliedPollInterval)
+	}
+}
+
+// Lead commits cmd if this node is the leader, and never forwards it, so forwarded commands cannot
+// bounce between nodes that disagree on who leads
+func (n *Node) Lead(cmd Command) Result {
+	cmd.At = time.Now().UTC()
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return failed(err)
+	}
+	future := n.raft.Apply(data, n.applyTimeout)
+	if err := future.Error(); err != nil {
+		// with leadership lost on the way the command may still be committed by the next leader
+		return Result{Err: &services.Error{Code: services.CodeNoLeader, Message: "write not committed: " + err.Error()}}
+	}
+	result := future.Response().(Result)
+	result.Index = future.Index()
+	return result
+}
+
+func (n *Node) forward(cmd Command) Result {
+	_, leaderID := n.raft.LeaderWithID()
+	leader, ok := n.peers[string(leaderID)]
+	if !ok {
+		return Result{Err: services.ErrNoLeader}
+	}
+
+	body, err := json.Marshal(cmd)
+	if err != nil {
+		return failed(err)
+	}
+	resp, err := n.client.Post(leader.HTTPAddr+ForwardPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return Result{Err: &services.Error{Code: services.CodeNoLeader, Message: "forward to leader " + leader.ID + ": " + err.Error()}}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Result{Err: &services.Error{Code: services.CodeNoLeader, Message: "forward to leader " + leader.ID + ": " + resp.Status}}
+	}
+
+	var result Result
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return failed(fmt.Errorf("forward to leader %s: %w", leader.ID, err))
+	}
+	return result
+}
+
+// Status reports this node's view of the cluster
+func (n *Node) Status() dto.ClusterStatus {
+	_, leaderID := n.raft.LeaderWithID()
+	snapshotIndex, _ := strconv.ParseUint(n.raft.Stats()["last_snapshot_index"], 10, 64)
+	status := dto.ClusterStatus{
+		NodeID:        n.config.NodeID,
+		State:         strings.ToLower(n.raft.State().String()),
+		Leader:        string(leaderID),
+		AppliedIndex:  n.raft.AppliedIndex(),
+		SnapshotIndex: snapshotIndex,
+	}
+	for _, peer := range n.config.Peers {
+		status.Peers = append(status.Peers, dto.ClusterPeer{ID: peer.ID, RaftAddr: peer.RaftAddr, HTTPAddr: peer.HTTPAddr})
+	}
+	return status
+}
+
+// Create creates a pool on every node
+func (n *Node) Create(name string, rules models.MatchRules) (models.Pool, error) {
+	result := n.Propose(Command{Op: OpCreatePool, Pool: name, Rules: rules})
+	if err := result.err(); err != nil {
+		return models.Pool{}, err
+	}
+	return *result.Pool, nil
+}
+
+// Get returns a pool with a match service that reads from this node and writes through the log
+func (n *Node) Get(name string) (models.Pool, services.MatchService, error) {
+	pool, local, err := n.registry.Get(name)
+	if err != nil {
+		return models.Pool{}, nil, err
+	}
+	return pool, &service{MatchService: local, node: n, pool: name}, nil
+}
+
+func (n *Node) Default() services.MatchService {
+	_, service, _ := n.Get(pools.DefaultPool)
+	return service
+}
+
+func (n *Node) List() []models.Pool {
+	return n.registry.List()
+}
+
+// Delete deletes a pool on every node, each of which audits it as made by the source in ctx
+func (n *Node) Delete(ctx context.Context, name string) error {
+	return n.Propose(withSource(ctx, Command{Op: OpDeletePool, Pool: name})).err()
+}
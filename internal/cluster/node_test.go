@@ -0,0 +1,316 @@
+package cluster_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/api/routes"
+	"matching_system/internal/cluster"
+	"matching_system/internal/events"
+	"matching_system/internal/webhooks"
+	"matching_system/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const waitFor = 10 * time.Second
+
+// testCluster is a cluster of nodes running in process, connected by in-memory raft transports, each
+// with its own API server
+type testCluster struct {
+	t          *testing.T
+	peers      []cluster.Peer
+	dirs       []string
+	threshold  uint64
+	nodes      []*cluster.Node
+	transports []*raft.InmemTransport
+	servers    []*httptest.Server
+	handlers   []*atomic.Value
+}
+
+func testRaftConfig() *raft.Config {
+	config := raft.DefaultConfig()
+	config.HeartbeatTimeout = 100 * time.Millisecond
+	config.ElectionTimeout = 100 * time.Millisecond
+	config.LeaderLeaseTimeout = 100 * time.Millisecond
+	config.CommitTimeout = 5 * time.Millisecond
+	config.SnapshotInterval = 50 * time.Millisecond
+	config.TrailingLogs = 2
+	return config
+}
+
+// startCluster starts size nodes; with dirs they keep their raft state on disk
+func startCluster(t *testing.T, size int, dirs bool, threshold uint64) *testCluster {
+	gin.SetMode(gin.TestMode)
+	c := &testCluster{t: t, threshold: threshold}
+	for i := 0; i < size; i++ {
+		// the servers start before the nodes, which need their URLs, and are handed the router after
+		handler := &atomic.Value{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.Load().(http.Handler).ServeHTTP(w, r)
+		}))
+		t.Cleanup(server.Close)
+		id := fmt.Sprintf("node%d", i+1)
+		c.peers = append(c.peers, cluster.Peer{ID: id, RaftAddr: id, HTTPAddr: server.URL})
+		c.servers = append(c.servers, server)
+		c.handlers = append(c.handlers, handler)
+		c.dirs = append(c.dirs, "")
+		if dirs {
+			c.dirs[i] = t.TempDir()
+		}
+	}
+	c.nodes = make([]*cluster.Node, size)
+	c.transports = make([]*raft.InmemTransport, size)
+	for i := range c.peers {
+		c.start(i)
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			if node != nil {
+				node.Shutdown()
+			}
+		}
+	})
+	return c
+}
+
+// start starts node i, again if it was stopped, connecting it to the other nodes
+func (c *testCluster) start(i int) {
+	_, transport := raft.NewInmemTransport(raft.ServerAddress(c.peers[i].RaftAddr))
+	for j, other := range c.transports {
+		if other != nil && j != i {
+			transport.Connect(raft.ServerAddress(c.peers[j].RaftAddr), other)
+			other.Connect(raft.ServerAddress(c.peers[i].RaftAddr), transport)
+		}
+	}
+	c.transports[i] = transport
+
+	node, err := cluster.NewNode(cluster.Config{
+		NodeID:            c.peers[i].ID,
+		Peers:             c.peers,
+		Dir:               c.dirs[i],
+		SnapshotThreshold: c.threshold,
+	}, logger.New(), cluster.WithTransport(transport), cluster.WithRaftConfig(testRaftConfig()))
+	require.NoError(c.t, err)
+	c.nodes[i] = node
+	c.handlers[i].Store(http.Handler(routes.Setup(node, events.NewBroker(events.DefaultHistorySize), webhooks.NewStore(), nil, node, nil, nil, nil)))
+}
+
+// stop shuts node i down and cuts it off from the others
+func (c *testCluster) stop(i int) {
+	require.NoError(c.t, c.nodes[i].Shutdown())
+	c.nodes[i] = nil
+	for j, other := range c.transports {
+		if other != nil && j != i {
+			other.Disconnect(raft.ServerAddress(c.peers[i].RaftAddr))
+		}
+	}
+	c.transports[i].DisconnectAll()
+	c.transports[i] = nil
+}
+
+// leader waits until the running nodes agree on a leader and returns its index
+func (c *testCluster) leader() int {
+	leader := -1
+	require.Eventually(c.t, func() bool {
+		leader = -1
+		for i, node := range c.nodes {
+			if node == nil {
+				continue
+			}
+			status := node.Status()
+			if status.Leader == "" || (leader >= 0 && c.peers[leader].ID != status.Leader) {
+				return false
+			}
+			for j, peer := range c.peers {
+				if peer.ID == status.Leader {
+					leader = j
+				}
+			}
+			if status.State == "leader" && c.peers[i].ID != status.Leader {
+				return false
+			}
+		}
+		return leader >= 0 && c.nodes[leader] != nil && c.nodes[leader].Status().State == "leader"
+	}, waitFor, 10*time.Millisecond, "the nodes should elect a leader")
+	return leader
+}
+
+// follower returns a running node other than the leader
+func (c *testCluster) follower(leader int) int {
+	for i, node := range c.nodes {
+		if node != nil && i != leader {
+			return i
+		}
+	}
+	c.t.Fatal("no follower is running")
+	return -1
+}
+
+func (c *testCluster) request(i int, method, path, body string) (int, string) {
+	req, err := http.NewRequest(method, c.servers[i].URL+path, strings.NewReader(body))
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	return resp.StatusCode, string(data)
+}
+
+// assertConverged waits until every running node answers path like node i
+func (c *testCluster) assertConverged(i int, path string) {
+	_, want := c.request(i, "GET", path, "")
+	for j, node := range c.nodes {
+		if node == nil || j == i {
+			continue
+		}
+		assert.Eventually(c.t, func() bool {
+			_, got := c.request(j, "GET", path, "")
+			return got == want
+		}, waitFor, 10*time.Millisecond, "%s on %s should match %s", path, c.peers[j].ID, c.peers[i].ID)
+	}
+}
+
+func addPerson(t *testing.T, c *testCluster, i int, person string) dto.AddPersonResponse {
+	code, body := c.request(i, "POST", "/v1/people", person)
+	require.Equal(t, http.StatusCreated, code, body)
+	var resp dto.AddPersonResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	return resp
+}
+
+func TestCluster_ForwardsWritesToTheLeader(t *testing.T) {
+	c := startCluster(t, 3, false, 0)
+	leader := c.leader()
+	follower := c.follower(leader)
+
+	alice := addPerson(t, c, follower, `{"name":"Alice","height":160,"gender":"female","wanted_dates":2}`)
+	bob := addPerson(t, c, leader, `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	require.Len(t, bob.Matches, 1, "Bob should match Alice, added through another node")
+	assert.ElementsMatch(t, []string{alice.Person.ID, bob.Person.ID}, []string{bob.Matches[0].Person1.ID, bob.Matches[0].Person2.ID})
+
+	// a write is applied by the node that took it before it returns, and by the others soon after
+	code, _ := c.request(follower, "GET", "/v1/people/"+alice.Person.ID, "")
+	assert.Equal(t, http.StatusOK, code)
+	c.assertConverged(leader, "/v1/people")
+	c.assertConverged(leader, "/v1/matches")
+
+	code, _ = c.request(follower, "DELETE", "/v1/people/"+alice.Person.ID, "")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = c.request(follower, "DELETE", "/v1/people/"+alice.Person.ID, "")
+	assert.Equal(t, http.StatusNotFound, code, "the second remove should fail like on a single server")
+	c.assertConverged(leader, "/v1/people")
+
+	code, body := c.request(follower, "GET", "/v1/cluster", "")
+	require.Equal(t, http.StatusOK, code)
+	var status dto.ClusterStatus
+	require.NoError(t, json.Unmarshal([]byte(body), &status))
+	assert.Equal(t, c.peers[follower].ID, status.NodeID)
+	assert.Equal(t, "follower", status.State)
+	assert.Equal(t, c.peers[leader].ID, status.Leader)
+	assert.Len(t, status.Peers, 3)
+}
+
+func TestCluster_ReplicatesPools(t *testing.T) {
+	c := startCluster(t, 3, false, 0)
+	leader := c.leader()
+	follower := c.follower(leader)
+
+	code, body := c.request(follower, "POST", "/v1/pools", `{"name":"tall","rules":{"min_height_gap":5,"max_height_gap":0}}`)
+	require.Equal(t, http.StatusCreated, code, body)
+	code, _ = c.request(follower, "POST", "/v1/pools", `{"name":"tall"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = c.request(leader, "POST", "/v1/pools/tall/people", `{"name":"Dan","height":190,"gender":"male","wanted_dates":3}`)
+	require.Equal(t, http.StatusCreated, code)
+	c.assertConverged(leader, "/v1/pools")
+	c.assertConverged(leader, "/v1/pools/tall/people")
+
+	code, _ = c.request(follower, "DELETE", "/v1/pools/tall", "")
+	require.Equal(t, http.StatusNoContent, code)
+	code, _ = c.request(follower, "GET", "/v1/pools/tall", "")
+	assert.Equal(t, http.StatusNotFound, code, "the node that took the delete should have applied it")
+	c.assertConverged(follower, "/v1/pools")
+}
+
+func TestCluster_ElectsANewLeader(t *testing.T) {
+	c := startCluster(t, 3, false, 0)
+	leader := c.leader()
+	addPerson(t, c, leader, `{"name":"Alice","height":160,"gender":"female","wanted_dates":2}`)
+
+	c.stop(leader)
+	next := c.leader()
+	assert.NotEqual(t, leader, next)
+
+	// the remaining majority takes writes, on top of what the old leader committed
+	bob := addPerson(t, c, c.follower(next), `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	assert.Len(t, bob.Matches, 1)
+	c.assertConverged(next, "/v1/people")
+}
+
+func TestCluster_CompactsTheLogAndRecovers(t *testing.T) {
+	c := startCluster(t, 3, true, 4)
+	leader := c.leader()
+	for i := 0; i < 12; i++ {
+		addPerson(t, c, leader, fmt.Sprintf(`{"name":"Person %d","height":%d,"gender":"female","wanted_dates":1}`, i, 150+i))
+	}
+	follower := c.follower(leader)
+	require.Eventually(t, func() bool {
+		return c.nodes[follower].Status().SnapshotIndex > 0
+	}, waitFor, 10*time.Millisecond, "the follower should snapshot its pools")
+
+	// a restarted node recovers from its snapshot and log, then catches up on what it missed
+	c.stop(follower)
+	addPerson(t, c, leader, `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	c.start(follower)
+	c.assertConverged(leader, "/v1/people")
+	c.assertConverged(leader, "/v1/matches")
+}
+
+func TestCluster_RestoredNodeReplaysIdempotentAdds(t *testing.T) {
+	c := startCluster(t, 3, true, 4)
+	leader := c.leader()
+	follower := c.follower(leader)
+	addWithKey := func(i int) dto.AddPersonResponse {
+		req, err := http.NewRequest("POST", c.servers[i].URL+"/v1/people", strings.NewReader(`{"name":"Alice","height":160,"gender":"female","wanted_dates":1}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "alice-1")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var body dto.AddPersonResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		return body
+	}
+
+	alice := addWithKey(leader)
+	added := c.nodes[leader].Status().AppliedIndex
+	// enough later writes for the follower to snapshot past the keyed add
+	for i := 0; i < 8; i++ {
+		addPerson(t, c, leader, fmt.Sprintf(`{"name":"Person %d","height":%d,"gender":"female","wanted_dates":1}`, i, 150+i))
+	}
+	require.Eventually(t, func() bool {
+		return c.nodes[follower].Status().SnapshotIndex >= added
+	}, waitFor, 10*time.Millisecond, "the follower should snapshot the pool with the key")
+
+	// the restarted node has its pool, and its keys, from the snapshot alone
+	c.stop(follower)
+	c.start(follower)
+	c.leader()
+
+	again := addWithKey(follower)
+	assert.Equal(t, alice.Person.ID, again.Person.ID, "the retry is replayed")
+	c.assertConverged(leader, "/v1/people")
+}
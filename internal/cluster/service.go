@@ -0,0 +1,73 @@
+package cluster
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/wal"
+)
+
+// service is a pool of a cluster: reads come from the node's own copy of the pool, writes are
+// proposed to the log and return once the cluster has committed and applied them
+type service struct {
+	services.MatchService
+	node *Node
+	pool string
+}
+
+// AddSinglePersonAndMatch adds no one when the write cannot be committed; callers that need to
+// know why use AddSinglePersonAndMatchIdempotent
+func (s *service) AddSinglePersonAndMatch(req dto.AddPersonRequest) (*models.Person, []models.Match) {
+	person, matches, _ := s.AddSinglePersonAndMatchIdempotent(context.Background(), "", req)
+	return person, matches
+}
+
+// AddSinglePersonAndMatchIdempotent, like every write, is applied by each node from the log, outside
+// of the trace in ctx. The audit source in ctx goes into the log with the write.
+func (s *service) AddSinglePersonAndMatchIdempotent(ctx context.Context, key string, req dto.AddPersonRequest) (*models.Person, []models.Match, error) {
+	result := s.node.Propose(withSource(ctx, Command{Op: OpAdd, Pool: s.pool, IdempotencyKey: key, Add: &req}))
+	return result.Person, result.Matches, result.err()
+}
+
+func (s *service) UpdateSinglePerson(ctx context.Context, personID string, req dto.UpdatePersonRequest, expectedVersion int64) (*models.Person, []models.Match, error) {
+	result := s.node.Propose(withSource(ctx, Command{Op: OpUpdate, Pool: s.pool, PersonID: personID, ExpectedVersion: expectedVersion, Update: &req}))
+	return result.Person, result.Matches, result.err()
+}
+
+func (s *service) RemoveSinglePerson(personID string) bool {
+	return s.RemoveSinglePersonIfMatch(context.Background(), personID, 0) == nil
+}
+
+func (s *service) RemoveSinglePersonIfMatch(ctx context.Context, personID string, expectedVersion int64) error {
+	return s.node.Propose(withSource(ctx, Command{Op: OpRemove, Pool: s.pool, PersonID: personID, ExpectedVersion: expectedVersion})).err()
+}
+
+// ImportPeople goes through the log even as a dry run, so it is validated against the committed pool
+func (s *service) ImportPeople(ctx context.Context, people []dto.ImportPerson, runMatching bool, dryRun bool) ([]models.Person, []models.Match, error) {
+	lines := make([]int, len(people))
+	for i, person := range people {
+		lines[i] = person.Line
+	}
+	result := s.node.Propose(withSource(ctx, Command{Op: OpImport, Pool: s.pool, People: people, Lines: lines, RunMatching: runMatching, DryRun: dryRun}))
+	return result.People, result.Matches, result.err()
+}
+
+// withSource sets the audit source of cmd from ctx
+func withSource(ctx context.Context, cmd Command) Command {
+	source := audit.FromContext(ctx)
+	cmd.Actor, cmd.RequestID = source.Actor, source.RequestID
+	return cmd
+}
+
+// Reset is refused: a clustered pool only changes by applying the log
+func (s *service) Reset(snapshot.State) {
+	panic("cluster: a clustered pool cannot be reset")
+}
+
+// Apply is refused: a clustered pool only changes by applying the log
+func (s *service) Apply([]wal.Record) (uint64, error) {
+	return 0, &services.Error{Code: services.CodeReadOnly, Message: "a clustered pool cannot apply another server's operations"}
+}
@@ -2,13 +2,50 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	Port        string
+	GRPCPort    string
 	Environment string
+	// LogLevel and LogFormat configure the log, see pkg/logger
+	LogLevel  string
+	LogFormat string
+	// ShutdownTimeout bounds the draining of requests and background work on SIGTERM
+	ShutdownTimeout time.Duration
+	// TracingExporter is where spans go, "otlp" or "stdout"; empty turns tracing off
+	TracingExporter     string
+	IdempotencyTTL      time.Duration
+	IdempotencyCapacity int
+	EventHistorySize    int
+	MatchHistorySize    int
+	WebhookMaxAttempts  int
+	WebhookTimeout      time.Duration
+	SnapshotDir         string
+	SnapshotInterval    time.Duration
+	SnapshotRetain      int
+	WALPath             string
+	PeopleStore         string
+	// ReplicationLeader is the base URL of the leader to follow; empty on a leader
+	ReplicationLeader       string
+	ReplicationPollInterval time.Duration
+	// ClusterNodeID runs the server as that node of a raft cluster; empty runs it alone
+	ClusterNodeID            string
+	ClusterPeers             string
+	ClusterDir               string
+	ClusterSnapshotThreshold int
+	// AuditLogPath is the file writes made on request are recorded to; empty turns the audit log off
+	AuditLogPath     string
+	AuditLogMaxSize  int
+	AuditLogMaxFiles int
+	// RouterPort and Shards configure the shard router, see cmd/router
+	RouterPort        string
+	Shards            string
+	ShardVirtualNodes int
 }
 
 func Load() *Config {
@@ -16,8 +53,36 @@ func Load() *Config {
 	godotenv.Load()
 
 	return &Config{
-		Port:        getEnv("PORT", "8080"),
-		Environment: getEnv("ENVIRONMENT", "development"),
+		Port:                     getEnv("PORT", "8080"),
+		GRPCPort:                 getEnv("GRPC_PORT", "9090"),
+		Environment:              getEnv("ENVIRONMENT", "development"),
+		LogLevel:                 getEnv("LOG_LEVEL", "info"),
+		LogFormat:                getEnv("LOG_FORMAT", "text"),
+		ShutdownTimeout:          getEnvDuration("SHUTDOWN_TIMEOUT", 25*time.Second),
+		TracingExporter:          getEnv("TRACING_EXPORTER", ""),
+		IdempotencyTTL:           getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyCapacity:      getEnvInt("IDEMPOTENCY_CAPACITY", 10000),
+		EventHistorySize:         getEnvInt("EVENT_HISTORY_SIZE", 1024),
+		MatchHistorySize:         getEnvInt("MATCH_HISTORY_SIZE", 10000),
+		WebhookMaxAttempts:       getEnvInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookTimeout:           getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		SnapshotDir:              getEnv("SNAPSHOT_DIR", ""),
+		SnapshotInterval:         getEnvDuration("SNAPSHOT_INTERVAL", time.Minute),
+		SnapshotRetain:           getEnvInt("SNAPSHOT_RETAIN", 3),
+		WALPath:                  getEnv("WAL_PATH", ""),
+		PeopleStore:              getEnv("PEOPLE_STORE", "indexed"),
+		ReplicationLeader:        getEnv("REPLICATION_LEADER", ""),
+		ReplicationPollInterval:  getEnvDuration("REPLICATION_POLL_INTERVAL", 5*time.Second),
+		ClusterNodeID:            getEnv("CLUSTER_NODE_ID", ""),
+		ClusterPeers:             getEnv("CLUSTER_PEERS", ""),
+		ClusterDir:               getEnv("CLUSTER_DIR", ""),
+		ClusterSnapshotThreshold: getEnvInt("CLUSTER_SNAPSHOT_THRESHOLD", 8192),
+		AuditLogPath:             getEnv("AUDIT_LOG_PATH", ""),
+		AuditLogMaxSize:          getEnvInt("AUDIT_LOG_MAX_SIZE_MB", 100),
+		AuditLogMaxFiles:         getEnvInt("AUDIT_LOG_MAX_FILES", 5),
+		RouterPort:               getEnv("ROUTER_PORT", "8000"),
+		Shards:                   getEnv("SHARDS", ""),
+		ShardVirtualNodes:        getEnvInt("SHARD_VIRTUAL_NODES", 128),
 	}
 }
 
@@ -27,3 +92,17 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
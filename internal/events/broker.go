@@ -0,0 +1,124 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultHistorySize is how many recent events a broker keeps for resuming subscribers
+const DefaultHistorySize = 1024
+
+// Broker fans events out to subscribers without ever blocking the publisher. A subscriber that
+// lets its buffer fill up is considered too slow: it is dropped and its channel is closed.
+// The most recent events are kept in a ring buffer so subscribers can resume where they left off.
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     *ring
+	subscribers map[*Subscription]struct{}
+}
+
+// NewBroker creates a broker remembering the last historySize events, at least one
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		history:     newRing(historySize),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives published events until it is closed or falls behind
+type Subscription struct {
+	broker     *Broker
+	ch         chan Event
+	overflowed atomic.Bool
+}
+
+// Subscribe registers a subscriber with room for buffer pending events
+func (b *Broker) Subscribe(buffer int) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.subscribe(buffer)
+}
+
+// SubscribeFrom registers a subscriber and returns the buffered events published after lastID.
+// Both happen atomically, so the subscriber sees every later event exactly once. complete is
+// false when some events after lastID are no longer buffered, or when lastID was never published
+// here: an ID from before a restart, see Bus, or from another server. missed then holds every
+// buffered event.
+func (b *Broker) SubscribeFrom(lastID uint64, buffer int) (sub *Subscription, missed []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lastID > b.lastID {
+		missed, _ = b.history.since(0)
+		return b.subscribe(buffer), missed, false
+	}
+	missed, complete = b.history.since(lastID)
+	return b.subscribe(buffer), missed, complete
+}
+
+func (b *Broker) subscribe(buffer int) *Subscription {
+	sub := &Subscription{
+		broker: b,
+		ch:     make(chan Event, buffer),
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Publish records event and delivers it to every subscriber that has room. Events that already
+// carry an ID, such as those forwarded by a Bus, keep it; others get the next one, counting from 1.
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event.ID == 0 {
+		event.ID = b.lastID + 1
+	}
+	b.lastID = event.ID
+	b.history.push(event)
+
+	for sub := range b.subscribers {
+		select {
+		case sub.ch <- event:
+		default:
+			sub.overflowed.Store(true)
+			b.remove(sub)
+		}
+	}
+}
+
+// LastID returns the ID of the latest event published, zero before the first
+func (b *Broker) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.lastID
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.ch)
+}
+
+// Events is closed when the subscription is closed or dropped for being too slow
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Overflowed reports whether the subscription was dropped because its buffer filled up
+func (s *Subscription) Overflowed() bool {
+	return s.overflowed.Load()
+}
+
+// Close unsubscribes; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	s.broker.remove(s)
+}
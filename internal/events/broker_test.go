@@ -0,0 +1,116 @@
+package events
+
+import (
+	"matching_system/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroker_PublishSubscribe(t *testing.T) {
+	broker := NewBroker(DefaultHistorySize)
+	first := broker.Subscribe(1)
+	second := broker.Subscribe(1)
+
+	broker.Publish(Event{Type: PersonAdded, PersonID: "1"})
+
+	assert.Equal(t, "1", (<-first.Events()).PersonID)
+	assert.Equal(t, "1", (<-second.Events()).PersonID)
+}
+
+func TestBroker_SlowSubscriberIsDropped(t *testing.T) {
+	broker := NewBroker(DefaultHistorySize)
+	slow := broker.Subscribe(1)
+	fast := broker.Subscribe(2)
+
+	broker.Publish(Event{Type: PersonAdded, PersonID: "1"})
+	broker.Publish(Event{Type: PersonAdded, PersonID: "2"})
+
+	// the slow subscriber keeps what it buffered, then its channel is closed
+	event, ok := <-slow.Events()
+	assert.True(t, ok)
+	assert.Equal(t, "1", event.PersonID)
+	_, ok = <-slow.Events()
+	assert.False(t, ok, "the slow subscriber should be closed")
+	assert.True(t, slow.Overflowed())
+
+	// other subscribers are not affected
+	assert.Equal(t, "1", (<-fast.Events()).PersonID)
+	assert.Equal(t, "2", (<-fast.Events()).PersonID)
+	assert.False(t, fast.Overflowed())
+}
+
+func TestSubscription_Close(t *testing.T) {
+	broker := NewBroker(DefaultHistorySize)
+	sub := broker.Subscribe(1)
+
+	sub.Close()
+	sub.Close()
+	broker.Publish(Event{Type: PersonAdded, PersonID: "1"})
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok, "a closed subscription should not receive events")
+	assert.False(t, sub.Overflowed())
+}
+
+func TestEvent_Involves(t *testing.T) {
+	match := Event{Type: Matched, PersonID: "1", Match: &models.Match{
+		Person1: models.Person{ID: "1"},
+		Person2: models.Person{ID: "2"},
+	}}
+	assert.True(t, match.Involves("1"))
+	assert.True(t, match.Involves("2"))
+	assert.False(t, match.Involves("3"))
+}
+
+func TestBroker_SubscribeFrom(t *testing.T) {
+	broker := NewBroker(2)
+	for _, id := range []string{"1", "2", "3"} {
+		broker.Publish(Event{Type: PersonAdded, PersonID: id})
+	}
+
+	// event 1 was overwritten, so resuming after it is complete but resuming from 0 is not
+	sub, missed, complete := broker.SubscribeFrom(1, 1)
+	defer sub.Close()
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{2, 3}, ids(missed))
+
+	_, missed, complete = broker.SubscribeFrom(0, 1)
+	assert.False(t, complete)
+	assert.Equal(t, []uint64{2, 3}, ids(missed))
+
+	// an ID this broker never gave out comes from before a restart, everything buffered is missed
+	_, missed, complete = broker.SubscribeFrom(7, 1)
+	assert.False(t, complete)
+	assert.Equal(t, []uint64{2, 3}, ids(missed))
+
+	// later events arrive live with the next ID
+	broker.Publish(Event{Type: PersonAdded, PersonID: "4"})
+	assert.Equal(t, uint64(4), (<-sub.Events()).ID)
+}
+
+func TestBroker_ResumingAfterARestart(t *testing.T) {
+	bus := NewBus()
+	broker := NewBroker(DefaultHistorySize)
+	bus.Subscribe(broker.Publish)
+	bus.Publish(Event{Type: PersonAdded})
+	stale := broker.LastID()
+	time.Sleep(time.Millisecond)
+
+	// nothing published yet since the restart
+	bus = NewBus()
+	broker = NewBroker(DefaultHistorySize)
+	bus.Subscribe(broker.Publish)
+	_, missed, complete := broker.SubscribeFrom(stale, 1)
+	assert.False(t, complete)
+	assert.Empty(t, missed)
+
+	// more published since the restart than before it
+	for i := 0; i < 3; i++ {
+		bus.Publish(Event{Type: PersonAdded})
+	}
+	_, missed, complete = broker.SubscribeFrom(stale, 1)
+	assert.False(t, complete)
+	assert.Len(t, missed, 3)
+}
@@ -0,0 +1,89 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Publisher accepts domain events. The match service only depends on this, so side effects such
+// as notifications, metrics, audit or persistence subscribe to a Bus instead of living in matching code.
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Handler reacts to an event. Handlers run synchronously on the publishing goroutine, in the order
+// events were published, so they must be quick and must not call back into the publisher.
+type Handler func(event Event)
+
+type subscriber struct {
+	handler Handler
+	// types is nil for handlers interested in every event
+	types map[Type]bool
+}
+
+// Bus is an in-process pub/sub bus that assigns every event its ID and hands it to each
+// subscribed handler in subscription order. Unlike a Broker it never drops events, which makes it
+// the place to attach side effects that must see every change.
+//
+// IDs count up from the time the bus was created, in microseconds, so those of a restarted server
+// come after every ID it gave out before, as long as it published less than an event per
+// microsecond. A client resuming with an ID from before the restart is then told it missed events
+// instead of being sent the wrong ones.
+type Bus struct {
+	// publishing serializes Publish so handlers observe one total order
+	publishing  sync.Mutex
+	lastID      uint64
+	mu          sync.RWMutex
+	subscribers []*subscriber
+}
+
+func NewBus() *Bus {
+	return &Bus{lastID: uint64(time.Now().UnixMicro())}
+}
+
+// Subscribe registers handler for events of the given types, or all events when none are given.
+// The returned function unsubscribes it.
+func (b *Bus) Subscribe(handler Handler, types ...Type) (unsubscribe func()) {
+	sub := &subscriber{handler: handler}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, sub)
+	b.mu.Unlock()
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, existing := range b.subscribers {
+			if existing == sub {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish assigns the next ID to event and hands it to every interested handler
+func (b *Bus) Publish(event Event) {
+	b.publishing.Lock()
+	defer b.publishing.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, sub := range subscribers {
+		if sub.types == nil || sub.types[event.Type] {
+			sub.handler(event)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_PublishAssignsIDsInOrder(t *testing.T) {
+	bus := NewBus()
+	var first, second []uint64
+	bus.Subscribe(func(event Event) { first = append(first, event.ID) })
+	bus.Subscribe(func(event Event) { second = append(second, event.ID) })
+
+	bus.Publish(Event{Type: PersonAdded})
+	bus.Publish(Event{Type: Matched})
+
+	require.Len(t, first, 2)
+	assert.Equal(t, first[0]+1, first[1])
+	assert.Equal(t, first, second)
+}
+
+func TestBus_IDsComeAfterThoseOfAnEarlierBus(t *testing.T) {
+	var last uint64
+	earlier := NewBus()
+	earlier.Subscribe(func(event Event) { last = event.ID })
+	for i := 0; i < 10; i++ {
+		earlier.Publish(Event{Type: PersonAdded})
+	}
+	time.Sleep(time.Millisecond)
+
+	var first uint64
+	restarted := NewBus()
+	restarted.Subscribe(func(event Event) { first = event.ID })
+	restarted.Publish(Event{Type: PersonAdded})
+	assert.Greater(t, first, last)
+}
+
+func TestBus_SubscribeToTypes(t *testing.T) {
+	bus := NewBus()
+	var received []Type
+	bus.Subscribe(func(event Event) { received = append(received, event.Type) }, Matched, DatesExhausted)
+
+	for _, eventType := range []Type{PersonAdded, Matched, PersonRemoved, DatesExhausted} {
+		bus.Publish(Event{Type: eventType})
+	}
+
+	assert.Equal(t, []Type{Matched, DatesExhausted}, received)
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+	calls := 0
+	unsubscribe := bus.Subscribe(func(Event) { calls++ })
+
+	bus.Publish(Event{Type: PersonAdded})
+	unsubscribe()
+	unsubscribe()
+	bus.Publish(Event{Type: PersonAdded})
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestBus_ForwardsToBrokerKeepingIDs(t *testing.T) {
+	bus := NewBus()
+	broker := NewBroker(DefaultHistorySize)
+	bus.Subscribe(broker.Publish)
+	var published []uint64
+	bus.Subscribe(func(event Event) { published = append(published, event.ID) })
+	// the first event reaches the broker history before anyone subscribes
+	bus.Publish(Event{Type: PersonAdded})
+
+	sub := broker.Subscribe(1)
+	bus.Publish(Event{Type: Matched})
+
+	assert.Equal(t, published[1], (<-sub.Events()).ID, "the broker should keep the bus ID")
+	missed, complete := broker.history.since(published[0] - 1)
+	assert.True(t, complete)
+	assert.Equal(t, published, ids(missed))
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"matching_system/internal/models"
+	"time"
+)
+
+// Type identifies what happened in the matching pool
+type Type string
+
+const (
+	// PersonAdded is published when a person joins the pool, before they are matched
+	PersonAdded Type = "person_added"
+	// PersonUpdated is published when a person's attributes are replaced
+	PersonUpdated Type = "person_updated"
+	// Matched is published once per match, for both people involved
+	Matched Type = "matched"
+	// PersonRemoved is published when a person is removed on request
+	PersonRemoved Type = "person_removed"
+	// DatesExhausted is published when a person used up their dates and left the pool
+	DatesExhausted Type = "dates_exhausted"
+)
+
+// Event is a change in the matching pool
+type Event struct {
+	// ID increases with every published event, so clients can resume after the last one they saw
+	ID         uint64         `json:"id"`
+	Pool       string         `json:"pool,omitempty"`
+	Type       Type           `json:"type"`
+	PersonID   string         `json:"person_id"`
+	Person     *models.Person `json:"person,omitempty"`
+	Match      *models.Match  `json:"match,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// Involves reports whether the event concerns personID, either as its subject or as one side of a match
+func (e Event) Involves(personID string) bool {
+	if e.PersonID == personID {
+		return true
+	}
+	return e.Match != nil && (e.Match.Person1.ID == personID || e.Match.Person2.ID == personID)
+}
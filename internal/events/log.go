@@ -0,0 +1,16 @@
+package events
+
+import (
+	"matching_system/pkg/logger"
+)
+
+// LogHandler logs a one-line summary of every event
+func LogHandler(l *logger.Logger) Handler {
+	return func(event Event) {
+		args := []any{"id", event.ID, "pool", event.Pool, "type", event.Type, "person_id", event.PersonID}
+		if event.Match != nil {
+			args = append(args, "match", event.Match.Person1.ID+"+"+event.Match.Person2.ID)
+		}
+		l.Info("Event", args...)
+	}
+}
@@ -0,0 +1,41 @@
+package events
+
+// ring keeps the most recent events, overwriting the oldest once full
+type ring struct {
+	events []Event
+	start  int
+	size   int
+}
+
+// newRing keeps at least the latest event, so since can tell whether events were missed
+func newRing(capacity int) *ring {
+	return &ring{events: make([]Event, max(capacity, 1))}
+}
+
+func (r *ring) push(event Event) {
+	end := (r.start + r.size) % len(r.events)
+	r.events[end] = event
+	if r.size < len(r.events) {
+		r.size++
+	} else {
+		r.start = (r.start + 1) % len(r.events)
+	}
+}
+
+// since returns the buffered events with an ID greater than lastID, oldest first. complete is false
+// when events after lastID were already overwritten, so the caller missed some.
+func (r *ring) since(lastID uint64) (events []Event, complete bool) {
+	if r.size == 0 {
+		return nil, true
+	}
+
+	oldest := r.events[r.start].ID
+	complete = lastID+1 >= oldest
+	for i := 0; i < r.size; i++ {
+		event := r.events[(r.start+i)%len(r.events)]
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+	return events, complete
+}
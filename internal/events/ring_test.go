@@ -0,0 +1,60 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(events []Event) []uint64 {
+	result := make([]uint64, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.ID)
+	}
+	return result
+}
+
+func TestRing_Since(t *testing.T) {
+	r := newRing(3)
+
+	events, complete := r.since(0)
+	assert.Empty(t, events)
+	assert.True(t, complete)
+
+	for id := uint64(1); id <= 5; id++ {
+		r.push(Event{ID: id})
+	}
+
+	// only the last 3 events are kept
+	events, complete = r.since(0)
+	assert.Equal(t, []uint64{3, 4, 5}, ids(events))
+	assert.False(t, complete, "events 1 and 2 were overwritten")
+
+	events, complete = r.since(2)
+	assert.Equal(t, []uint64{3, 4, 5}, ids(events))
+	assert.True(t, complete)
+
+	events, complete = r.since(4)
+	assert.Equal(t, []uint64{5}, ids(events))
+	assert.True(t, complete)
+
+	events, complete = r.since(5)
+	assert.Empty(t, events)
+	assert.True(t, complete)
+}
+
+func TestRing_KeepsAtLeastOneEvent(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		r := newRing(capacity)
+		r.push(Event{ID: 1})
+		r.push(Event{ID: 2})
+
+		events, complete := r.since(0)
+		assert.Equal(t, []uint64{2}, ids(events))
+		assert.False(t, complete, "event 1 was overwritten")
+
+		events, complete = r.since(1)
+		assert.Equal(t, []uint64{2}, ids(events))
+		assert.True(t, complete)
+	}
+}
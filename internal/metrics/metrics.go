@@ -0,0 +1,179 @@
+package metrics
+
+import (
+	"errors"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "matching"
+
+// Pools is what the active people gauge reads, the pool registry of the server
+type Pools interface {
+	List() []models.Pool
+	Get(name string) (models.Pool, services.MatchService, error)
+}
+
+// Metrics holds the server's Prometheus metrics. Pool changes are counted from the events on the
+// bus (see HandleEvent), matches per add by the match services (see ObserveAdd), events the webhook
+// dispatcher lost (see ObserveWebhookEventsLost), HTTP requests by Middleware, and active people are
+// read from the pools when scraped (see WatchPools).
+type Metrics struct {
+	registry           *prometheus.Registry
+	matchesPerAdd      *prometheus.HistogramVec
+	wantedDates        *prometheus.HistogramVec
+	removals           *prometheus.CounterVec
+	expirations        *prometheus.CounterVec
+	validationFailures *prometheus.CounterVec
+	requestDuration    *prometheus.HistogramVec
+	webhookEventsLost  prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		matchesPerAdd: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "matches_per_add",
+			Help:      "Matches made by adding a person.",
+			Buckets:   []float64{0, 1, 2, 3, 5, 8, 13, 21},
+		}, []string{"pool"}),
+		wantedDates: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "wanted_dates",
+			Help:      "Dates wanted by the people joining a pool.",
+			Buckets:   []float64{1, 2, 3, 5, 8, 13, 21, 34},
+		}, []string{"pool"}),
+		removals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "removals_total",
+			Help:      "People removed from a pool on request.",
+		}, []string{"pool"}),
+		expirations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "expirations_total",
+			Help:      "People who left a pool after using up their dates.",
+		}, []string{"pool"}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "validation_failures_total",
+			Help:      "Requests rejected as invalid, by route.",
+		}, []string{"method", "route"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to answer HTTP requests, by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		webhookEventsLost: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "webhook_events_lost_total",
+			Help:      "Events never delivered to webhooks because the dispatcher fell too far behind.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.matchesPerAdd,
+		m.wantedDates,
+		m.removals,
+		m.expirations,
+		m.validationFailures,
+		m.requestDuration,
+		m.webhookEventsLost,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// WatchPools reports the active people of every pool in pools, by gender
+func (m *Metrics) WatchPools(pools Pools) {
+	m.registry.MustRegister(&activePeople{pools: pools})
+}
+
+// HandleEvent counts a pool change; subscribe it to the event bus
+func (m *Metrics) HandleEvent(event events.Event) {
+	switch event.Type {
+	case events.PersonAdded:
+		m.wantedDates.WithLabelValues(event.Pool).Observe(float64(event.Person.WantedDates))
+	case events.PersonRemoved:
+		m.removals.WithLabelValues(event.Pool).Inc()
+	case events.DatesExhausted:
+		m.expirations.WithLabelValues(event.Pool).Inc()
+	}
+}
+
+// ObserveAdd records how many matches adding a person made; see services.WithAddObserver
+func (m *Metrics) ObserveAdd(pool string, matches int) {
+	m.matchesPerAdd.WithLabelValues(pool).Observe(float64(matches))
+}
+
+// ObserveWebhookEventsLost counts events the webhook dispatcher lost; see webhooks.WithLostObserver
+func (m *Metrics) ObserveWebhookEventsLost(events int) {
+	m.webhookEventsLost.Add(float64(events))
+}
+
+// Middleware times every request and counts those that failed validation. Routes are labelled by
+// their pattern, such as /v1/pools/:pool/people, so pools and IDs do not add series.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+		for _, err := range c.Errors {
+			var serviceErr *services.Error
+			if errors.As(err.Err, &serviceErr) && serviceErr.Code == services.CodeValidationFailed {
+				m.validationFailures.WithLabelValues(c.Request.Method, route).Inc()
+				break
+			}
+		}
+	}
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// activePeople reads the number of active people from the pools on every scrape, so it stays right
+// however the pools change: on requests, by replaying logs, or by following a leader
+type activePeople struct {
+	pools Pools
+}
+
+var activePeopleDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "people_active"),
+	"People waiting for dates, by pool and gender.",
+	[]string{"pool", "gender"}, nil,
+)
+
+func (a *activePeople) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activePeopleDesc
+}
+
+func (a *activePeople) Collect(ch chan<- prometheus.Metric) {
+	for _, pool := range a.pools.List() {
+		_, service, err := a.pools.Get(pool.Name)
+		if err != nil {
+			// deleted since it was listed
+			continue
+		}
+		for gender, count := range service.CountByGender() {
+			ch <- prometheus.MustNewConstMetric(activePeopleDesc, prometheus.GaugeValue, float64(count), pool.Name, gender)
+		}
+	}
+}
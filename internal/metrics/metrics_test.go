@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/events"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics_Pools(t *testing.T) {
+	m := New()
+	bus := events.NewBus()
+	bus.Subscribe(m.HandleEvent)
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithEvents(bus), services.WithAddObserver(m.ObserveAdd)))
+	require.NoError(t, err)
+	m.WatchPools(registry)
+
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+	_, eu, err := registry.Get("eu")
+	require.NoError(t, err)
+
+	eu.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	eu.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 165, Gender: "female", WantedDates: 1})
+	eu.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 3})
+	dave, _ := eu.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Dave", Height: 150, Gender: "male", WantedDates: 1})
+	require.True(t, eu.RemoveSinglePerson(dave.ID))
+
+	body := scrape(t, m)
+	// Bob matched both women and still wants one date; Carol used up hers
+	assert.Contains(t, body, `matching_people_active{gender="male",pool="eu"} 1`)
+	assert.Contains(t, body, `matching_people_active{gender="female",pool="eu"} 1`)
+	assert.Contains(t, body, `matching_people_active{gender="female",pool="default"} 0`)
+	assert.Contains(t, body, `matching_matches_per_add_count{pool="eu"} 4`)
+	assert.Contains(t, body, `matching_matches_per_add_sum{pool="eu"} 2`)
+	assert.Contains(t, body, `matching_matches_per_add_bucket{pool="eu",le="0"} 3`)
+	assert.Contains(t, body, `matching_wanted_dates_sum{pool="eu"} 7`)
+	assert.Contains(t, body, `matching_removals_total{pool="eu"} 1`)
+	assert.Contains(t, body, `matching_expirations_total{pool="eu"} 1`)
+
+	m.ObserveWebhookEventsLost(3)
+	assert.Contains(t, scrape(t, m), `matching_webhook_events_lost_total 3`)
+
+	require.NoError(t, registry.Delete(context.Background(), "eu"))
+	assert.NotContains(t, scrape(t, m), `matching_people_active{gender="male",pool="eu"}`)
+}
+
+func TestMetrics_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	router := gin.New()
+	router.Use(m.Middleware())
+	router.POST("/v1/pools/:pool/people", func(c *gin.Context) {
+		if c.Param("pool") == "invalid" {
+			c.Error(services.NewValidationError(services.FieldError{Field: "height", Message: "is required"}))
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	for _, pool := range []string{"eu", "us", "invalid"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/v1/pools/"+pool+"/people", nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/nowhere", nil))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `matching_http_request_duration_seconds_count{method="POST",route="/v1/pools/:pool/people",status="201"} 2`)
+	assert.Contains(t, body, `matching_http_request_duration_seconds_count{method="POST",route="/v1/pools/:pool/people",status="400"} 1`)
+	assert.Contains(t, body, `matching_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `matching_validation_failures_total{method="POST",route="/v1/pools/:pool/people"} 1`)
+}
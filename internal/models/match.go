@@ -1,6 +1,9 @@
 package models
 
+import "time"
+
 type Match struct {
-	Person1 Person `json:"person1"`
-	Person2 Person `json:"person2"`
+	Person1   Person    `json:"person1"`
+	Person2   Person    `json:"person2"`
+	MatchedAt time.Time `json:"matched_at"`
 }
@@ -6,4 +6,5 @@ type Person struct {
 	Height      int    `json:"height"`
 	Gender      string `json:"gender"`
 	WantedDates int    `json:"wanted_dates"`
+	Version     int64  `json:"version"`
 }
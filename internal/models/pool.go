@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// MatchRules are the height rules a pool matches by. A man is matched with women shorter than him
+// by at least MinHeightGap and, unless MaxHeightGap is zero, by at most MaxHeightGap.
+type MatchRules struct {
+	MinHeightGap int `json:"min_height_gap"`
+	MaxHeightGap int `json:"max_height_gap"`
+}
+
+// Pool is an isolated set of people matched only with each other
+type Pool struct {
+	Name      string     `json:"name"`
+	Rules     MatchRules `json:"rules"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// DeliveryStatus is where a webhook delivery is in its retry lifecycle
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Webhook asks for pool events of the given types to be POSTed to URL, signed with Secret
+type Webhook struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret is write-only, it is never sent back to clients
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookAttempt records a single POST of a delivery
+type WebhookAttempt struct {
+	Number     int       `json:"number"`
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// WebhookDelivery is one event sent to one webhook, with every attempt made so far
+type WebhookDelivery struct {
+	ID        string           `json:"id"`
+	WebhookID string           `json:"webhook_id"`
+	EventID   uint64           `json:"event_id"`
+	EventType string           `json:"event_type"`
+	Status    DeliveryStatus   `json:"status"`
+	Attempts  []WebhookAttempt `json:"attempts"`
+	CreatedAt time.Time        `json:"created_at"`
+}
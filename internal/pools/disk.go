@@ -0,0 +1,238 @@
+package pools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/wal"
+	"matching_system/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DiskConfig says where pools are persisted. The default pool uses SnapshotDir and WALPath as
+// they are, every other pool a "pools/<name>" directory next to them.
+type DiskConfig struct {
+	// SnapshotDir is empty to disable snapshots
+	SnapshotDir      string
+	SnapshotInterval time.Duration
+	SnapshotRetain   int
+	// WALPath is empty to disable the write-ahead log
+	WALPath string
+}
+
+type diskPool struct {
+	stop context.CancelFunc
+	done chan struct{}
+	// journal is nil without a write-ahead log
+	journal *wal.Log
+}
+
+// DiskBackend restores each pool from its latest snapshot and write-ahead log, and snapshots it
+// in the background until it is closed
+type DiskBackend struct {
+	config  DiskConfig
+	options []services.Option
+	logger  *logger.Logger
+
+	mu      sync.Mutex
+	running map[string]*diskPool
+}
+
+func NewDiskBackend(config DiskConfig, l *logger.Logger, options ...services.Option) *DiskBackend {
+	return &DiskBackend{
+		config:  config,
+		options: options,
+		logger:  l,
+		running: make(map[string]*diskPool),
+	}
+}
+
+// poolsFile keeps the pool definitions with the snapshots, or next to the log without snapshots
+func (b *DiskBackend) poolsFile() string {
+	switch {
+	case b.config.SnapshotDir != "":
+		return filepath.Join(b.config.SnapshotDir, "pools.json")
+	case b.config.WALPath != "":
+		return filepath.Join(filepath.Dir(b.config.WALPath), "pools.json")
+	}
+	return ""
+}
+
+func (b *DiskBackend) snapshotDir(name string) string {
+	if name == DefaultPool {
+		return b.config.SnapshotDir
+	}
+	return filepath.Join(b.config.SnapshotDir, "pools", name)
+}
+
+func (b *DiskBackend) walPath(name string) string {
+	if name == DefaultPool {
+		return b.config.WALPath
+	}
+	dir, file := filepath.Split(b.config.WALPath)
+	return filepath.Join(dir, "pools", name, file)
+}
+
+func (b *DiskBackend) LoadPools() ([]models.Pool, error) {
+	path := b.poolsFile()
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read pools: %w", err)
+	}
+
+	var pools []models.Pool
+	if err := json.Unmarshal(data, &pools); err != nil {
+		return nil, fmt.Errorf("decode pools: %w", err)
+	}
+	return pools, nil
+}
+
+// SavePools replaces the pool definitions atomically, like a snapshot
+func (b *DiskBackend) SavePools(pools []models.Pool) error {
+	path := b.poolsFile()
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(pools, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pools-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (b *DiskBackend) Open(pool models.Pool) (services.MatchService, error) {
+	var (
+		own        []services.Option
+		snapshots  *snapshot.Store
+		journal    *wal.Log
+		compactors []snapshot.Compactor
+	)
+
+	if b.config.SnapshotDir != "" {
+		store, err := snapshot.NewStore(b.snapshotDir(pool.Name), b.config.SnapshotRetain)
+		if err != nil {
+			return nil, err
+		}
+		state, err := store.Latest()
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			b.logger.Info("Restored pool from snapshot", "pool", pool.Name, "people", len(state.People), "matches", len(state.Matches))
+			own = append(own, services.WithState(*state))
+		}
+		snapshots = store
+	}
+
+	if b.config.WALPath != "" {
+		var records []wal.Record
+		var err error
+		journal, records, err = wal.Open(b.walPath(pool.Name))
+		if err != nil {
+			return nil, err
+		}
+		if journal.Truncated() > 0 {
+			b.logger.Warn("Dropped damaged bytes from the end of the write-ahead log", "pool", pool.Name, "bytes", journal.Truncated())
+		}
+		b.logger.Info("Replaying the write-ahead log", "pool", pool.Name, "records", len(records))
+		own = append(own, services.WithWriteAheadLog(journal, records))
+		// records a saved snapshot contains are no longer needed for recovery
+		compactors = append(compactors, journal)
+	}
+
+	service, err := services.OpenMatchService(poolOptions(b.options, pool, own...)...)
+	if err != nil {
+		if journal != nil {
+			journal.Close()
+		}
+		return nil, fmt.Errorf("restore pool %s: %w", pool.Name, err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	running := &diskPool{stop: stop, done: make(chan struct{}), journal: journal}
+	if snapshots != nil {
+		go func() {
+			defer close(running.done)
+			snapshots.Run(ctx, service, b.config.SnapshotInterval, b.logger, compactors...)
+		}()
+	} else {
+		close(running.done)
+	}
+
+	b.mu.Lock()
+	b.running[pool.Name] = running
+	b.mu.Unlock()
+	return service, nil
+}
+
+// Close stops snapshotting the pool after one last snapshot and closes its write-ahead log. Requests
+// that looked the pool up just before may still write to it, those writes fail.
+func (b *DiskBackend) Close(name string) {
+	b.mu.Lock()
+	running, ok := b.running[name]
+	delete(b.running, name)
+	b.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	running.stop()
+	<-running.done
+	if running.journal != nil {
+		if err := running.journal.Close(); err != nil {
+			b.logger.Error("Failed to close the write-ahead log", "pool", name, "error", err)
+		}
+	}
+}
+
+func (b *DiskBackend) Destroy(name string) error {
+	b.Close(name)
+	if name == DefaultPool {
+		return errors.New("the default pool's data is not deleted")
+	}
+
+	if b.config.SnapshotDir != "" {
+		if err := os.RemoveAll(b.snapshotDir(name)); err != nil {
+			return err
+		}
+	}
+	if b.config.WALPath != "" {
+		if err := os.RemoveAll(filepath.Dir(b.walPath(name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
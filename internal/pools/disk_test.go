@@ -0,0 +1,100 @@
+package pools
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"matching_system/internal/snapshot"
+	"matching_system/pkg/logger"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskBackend_RestoresPools(t *testing.T) {
+	dir := t.TempDir()
+	config := DiskConfig{
+		SnapshotDir:      filepath.Join(dir, "snapshots"),
+		SnapshotInterval: time.Hour,
+		SnapshotRetain:   1,
+		WALPath:          filepath.Join(dir, "wal.log"),
+	}
+
+	registry, err := NewRegistry(NewDiskBackend(config, logger.New()))
+	require.NoError(t, err)
+	rules := models.MatchRules{MinHeightGap: 3, MaxHeightGap: 30}
+	_, err = registry.Create("eu", rules)
+	require.NoError(t, err)
+	_, eu, err := registry.Get("eu")
+	require.NoError(t, err)
+	eu.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	registry.Default().AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	registry.Close()
+
+	// each pool keeps its own files
+	assert.FileExists(t, filepath.Join(dir, "pools", "eu", "wal.log"))
+	assert.DirExists(t, filepath.Join(dir, "snapshots", "pools", "eu"))
+
+	restarted, err := NewRegistry(NewDiskBackend(config, logger.New()))
+	require.NoError(t, err)
+	pool, eu, err := restarted.Get("eu")
+	require.NoError(t, err)
+	assert.Equal(t, rules, pool.Rules, "rules should survive a restart")
+
+	people := eu.QuerySinglePeople(0)
+	require.Len(t, people, 1)
+	assert.Equal(t, "Alice", people[0].Name)
+	people = restarted.Default().QuerySinglePeople(0)
+	require.Len(t, people, 1)
+	assert.Equal(t, "Bob", people[0].Name)
+
+	// deleting a pool deletes its files, and closes its log: a write that still reaches the pool fails
+	require.NoError(t, restarted.Delete(context.Background(), "eu"))
+	_, _, err = eu.AddSinglePersonAndMatchIdempotent(context.Background(), "", dto.AddPersonRequest{Name: "Carol", Height: 150, Gender: "female", WantedDates: 1})
+	assert.ErrorIs(t, err, services.ErrJournalFailed)
+	restarted.Close()
+	_, err = os.Stat(filepath.Join(dir, "pools", "eu"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "snapshots", "pools", "eu"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDiskBackend_RecoversFromAnOlderSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	config := DiskConfig{
+		SnapshotDir:      filepath.Join(dir, "snapshots"),
+		SnapshotInterval: 5 * time.Millisecond,
+		SnapshotRetain:   2,
+		WALPath:          filepath.Join(dir, "wal.log"),
+	}
+	store, err := snapshot.NewStore(config.SnapshotDir, config.SnapshotRetain)
+	require.NoError(t, err)
+
+	registry, err := NewRegistry(NewDiskBackend(config, logger.New()))
+	require.NoError(t, err)
+	for _, name := range []string{"Alice", "Carol", "Eve"} {
+		registry.Default().AddSinglePersonAndMatch(dto.AddPersonRequest{Name: name, Height: 160, Gender: "female", WantedDates: 1})
+		revision := registry.Default().Snapshot().Revision
+		require.Eventually(t, func() bool {
+			state, err := store.Latest()
+			return err == nil && state != nil && state.Revision == revision
+		}, time.Second, 5*time.Millisecond)
+	}
+	registry.Close()
+
+	// the newest snapshot is damaged, the older one is used with the log on top
+	names, err := filepath.Glob(filepath.Join(config.SnapshotDir, "snapshot-*.json"))
+	require.NoError(t, err)
+	require.Len(t, names, 2)
+	require.NoError(t, os.WriteFile(names[1], []byte("{}"), 0o644))
+
+	restarted, err := NewRegistry(NewDiskBackend(config, logger.New()))
+	require.NoError(t, err)
+	defer restarted.Close()
+	assert.Len(t, restarted.Default().QuerySinglePeople(0), 3)
+}
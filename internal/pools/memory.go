@@ -0,0 +1,42 @@
+package pools
+
+import (
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+)
+
+// MemoryBackend keeps pools in memory only, they are gone after a restart
+type MemoryBackend struct {
+	options []services.Option
+}
+
+// NewMemoryBackend builds every pool's service with options, such as the shared event bus
+func NewMemoryBackend(options ...services.Option) *MemoryBackend {
+	return &MemoryBackend{options: options}
+}
+
+func (b *MemoryBackend) LoadPools() ([]models.Pool, error) {
+	return nil, nil
+}
+
+func (b *MemoryBackend) SavePools([]models.Pool) error {
+	return nil
+}
+
+func (b *MemoryBackend) Open(pool models.Pool) (services.MatchService, error) {
+	return services.NewMatchService(poolOptions(b.options, pool)...), nil
+}
+
+func (b *MemoryBackend) Close(string) {}
+
+func (b *MemoryBackend) Destroy(string) error {
+	return nil
+}
+
+// poolOptions appends the pool's own options without touching the shared slice
+func poolOptions(shared []services.Option, pool models.Pool, own ...services.Option) []services.Option {
+	options := make([]services.Option, 0, len(shared)+1+len(own))
+	options = append(options, shared...)
+	options = append(options, services.WithPool(pool.Name, pool.Rules))
+	return append(options, own...)
+}
@@ -0,0 +1,235 @@
+package pools
+
+import (
+	"context"
+	"fmt"
+	"matching_system/internal/audit"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"matching_system/pkg/logger"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultPool always exists and serves the routes outside /v1/pools
+const DefaultPool = "default"
+
+// namePattern keeps pool names safe to use in URLs and file paths
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Backend builds the match services behind pools and owns whatever they persist
+type Backend interface {
+	// LoadPools returns the pools saved by SavePools, so they can be reopened on start
+	LoadPools() ([]models.Pool, error)
+	SavePools(pools []models.Pool) error
+	// Open builds the match service of pool, restoring what the backend kept of it
+	Open(pool models.Pool) (services.MatchService, error)
+	// Close stops the pool's background work and keeps its data
+	Close(name string)
+	// Destroy closes the pool and deletes its data
+	Destroy(name string) error
+}
+
+type entry struct {
+	pool    models.Pool
+	service services.MatchService
+}
+
+// Registry holds the pools. Each pool has its own match service, so people are only ever matched
+// within their pool and a busy pool does not hold up the others.
+type Registry struct {
+	mu      sync.RWMutex
+	backend Backend
+	pools   map[string]*entry
+	now     func() time.Time
+	// recordAudit records the pools deleted, see WithAudit
+	recordAudit func(entries ...audit.Entry) error
+}
+
+// Option configures a Registry
+type Option func(*Registry)
+
+// WithClock dates the pools the registry creates with now
+func WithClock(now func() time.Time) Option {
+	return func(r *Registry) {
+		r.now = now
+	}
+}
+
+// WithAudit has record told of every pool deleted, with the audit source of the context it was
+// deleted with. An error is logged, the pool stays deleted.
+func WithAudit(record func(entries ...audit.Entry) error) Option {
+	return func(r *Registry) {
+		r.recordAudit = record
+	}
+}
+
+// NewRegistry reopens the pools the backend saved and creates the default pool if it is missing
+func NewRegistry(backend Backend, opts ...Option) (*Registry, error) {
+	r := &Registry{
+		backend: backend,
+		pools:   make(map[string]*entry),
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	saved, err := backend.LoadPools()
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range saved {
+		if err := r.open(pool); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	if _, ok := r.pools[DefaultPool]; !ok {
+		if _, err := r.Create(DefaultPool, services.DefaultRules); err != nil {
+			r.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Create opens a new, empty pool
+func (r *Registry) Create(name string, rules models.MatchRules) (models.Pool, error) {
+	return r.CreatePool(models.Pool{Name: name, Rules: rules, CreatedAt: r.now().UTC()})
+}
+
+// CreatePool opens a new, empty pool defined elsewhere, such as by the leader of a cluster, keeping
+// its creation time
+func (r *Registry) CreatePool(pool models.Pool) (models.Pool, error) {
+	name, rules := pool.Name, pool.Rules
+	if !namePattern.MatchString(name) {
+		return models.Pool{}, services.NewValidationError(services.FieldError{
+			Field:   "name",
+			Message: "must be 1 to 63 lowercase letters, digits or dashes, starting with a letter or digit",
+		})
+	}
+	if err := services.ValidateRules(rules); err != nil {
+		return models.Pool{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.pools[name]; ok {
+		return models.Pool{}, services.ErrPoolExists
+	}
+	if err := r.open(pool); err != nil {
+		return models.Pool{}, err
+	}
+	if err := r.backend.SavePools(r.list()); err != nil {
+		delete(r.pools, name)
+		r.backend.Destroy(name)
+		return models.Pool{}, fmt.Errorf("save pools: %w", err)
+	}
+	return pool, nil
+}
+
+func (r *Registry) open(pool models.Pool) error {
+	service, err := r.backend.Open(pool)
+	if err != nil {
+		return fmt.Errorf("open pool %s: %w", pool.Name, err)
+	}
+	r.pools[pool.Name] = &entry{pool: pool, service: service}
+	return nil
+}
+
+// Get returns a pool and its match service, or ErrPoolNotFound
+func (r *Registry) Get(name string) (models.Pool, services.MatchService, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e, ok := r.pools[name]
+	if !ok {
+		return models.Pool{}, nil, services.ErrPoolNotFound
+	}
+	return e.pool, e.service, nil
+}
+
+// Default returns the match service of the default pool
+func (r *Registry) Default() services.MatchService {
+	_, service, _ := r.Get(DefaultPool)
+	return service
+}
+
+// List returns the pools sorted by name
+func (r *Registry) List() []models.Pool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.list()
+}
+
+func (r *Registry) list() []models.Pool {
+	pools := make([]models.Pool, 0, len(r.pools))
+	for _, e := range r.pools {
+		pools = append(pools, e.pool)
+	}
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+	return pools
+}
+
+// Delete removes a pool with its people and match history, audited as made by the source in ctx.
+// The default pool cannot be deleted. Once the pools are saved without it the pool is gone, so data
+// that fails to be deleted after that is only logged.
+func (r *Registry) Delete(ctx context.Context, name string) error {
+	if name == DefaultPool {
+		return services.NewValidationError(services.FieldError{Field: "pool", Message: "the default pool cannot be deleted"})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.pools[name]
+	if !ok {
+		return services.ErrPoolNotFound
+	}
+	delete(r.pools, name)
+	if err := r.backend.SavePools(r.list()); err != nil {
+		// the pool is still saved, so it is still served
+		r.pools[name] = e
+		return fmt.Errorf("save pools: %w", err)
+	}
+	if err := r.backend.Destroy(name); err != nil {
+		logger.FromContextOr(ctx, logger.Default()).Error("Failed to delete the data of a deleted pool", "pool", name, "error", err)
+	}
+	r.audit(ctx, name)
+	return nil
+}
+
+func (r *Registry) audit(ctx context.Context, name string) {
+	if r.recordAudit == nil {
+		return
+	}
+	source := audit.FromContext(ctx)
+	entry := audit.Entry{
+		At:        r.now().UTC(),
+		Pool:      name,
+		Operation: audit.OpDeletePool,
+		Actor:     source.Actor,
+		RequestID: source.RequestID,
+		LogIndex:  source.LogIndex,
+	}
+	if err := r.recordAudit(entry); err != nil {
+		logger.FromContextOr(ctx, logger.Default()).Error("Failed to record the pool deletion in the audit log", "pool", name, "error", err)
+	}
+}
+
+// Close stops the background work of every pool, keeping their data
+func (r *Registry) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name := range r.pools {
+		r.backend.Close(name)
+	}
+}
@@ -0,0 +1,156 @@
+package pools
+
+import (
+	"context"
+	"errors"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_CreatesDefaultPool(t *testing.T) {
+	registry, err := NewRegistry(NewMemoryBackend())
+	require.NoError(t, err)
+
+	pool, service, err := registry.Get(DefaultPool)
+	require.NoError(t, err)
+	assert.Equal(t, services.DefaultRules, pool.Rules)
+	assert.Same(t, service, registry.Default())
+}
+
+func TestRegistry_Create(t *testing.T) {
+	registry, err := NewRegistry(NewMemoryBackend())
+	require.NoError(t, err)
+
+	_, err = registry.Create("us-east", models.MatchRules{MinHeightGap: 1})
+	require.NoError(t, err)
+	_, err = registry.Create("eu", models.MatchRules{MinHeightGap: 2, MaxHeightGap: 10})
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, pool := range registry.List() {
+		names = append(names, pool.Name)
+	}
+	assert.Equal(t, []string{"default", "eu", "us-east"}, names)
+
+	_, err = registry.Create("eu", services.DefaultRules)
+	assert.ErrorIs(t, err, services.ErrPoolExists)
+	_, err = registry.Create("EU/../x", services.DefaultRules)
+	assert.ErrorIs(t, err, &services.Error{Code: services.CodeValidationFailed})
+	_, err = registry.Create("asia", models.MatchRules{MinHeightGap: 5, MaxHeightGap: 3})
+	assert.ErrorIs(t, err, &services.Error{Code: services.CodeValidationFailed})
+}
+
+func TestRegistry_PoolsAreIsolated(t *testing.T) {
+	bus := events.NewBus()
+	var seen []events.Event
+	bus.Subscribe(func(event events.Event) { seen = append(seen, event) })
+
+	registry, err := NewRegistry(NewMemoryBackend(services.WithEvents(bus)))
+	require.NoError(t, err)
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+	_, eu, err := registry.Get("eu")
+	require.NoError(t, err)
+
+	registry.Default().AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	_, matches := eu.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	assert.Empty(t, matches, "people should never be matched across pools")
+	require.Len(t, seen, 2)
+	assert.Equal(t, DefaultPool, seen[0].Pool)
+	assert.Equal(t, "eu", seen[1].Pool)
+}
+
+func TestRegistry_Delete(t *testing.T) {
+	registry, err := NewRegistry(NewMemoryBackend())
+	require.NoError(t, err)
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+
+	require.NoError(t, registry.Delete(context.Background(), "eu"))
+	_, _, err = registry.Get("eu")
+	assert.ErrorIs(t, err, services.ErrPoolNotFound)
+	assert.ErrorIs(t, registry.Delete(context.Background(), "eu"), services.ErrPoolNotFound)
+	assert.ErrorIs(t, registry.Delete(context.Background(), DefaultPool), &services.Error{Code: services.CodeValidationFailed})
+}
+
+// failingBackend fails to save the pools once failSave is set, and to destroy them once failDestroy is
+type failingBackend struct {
+	*MemoryBackend
+	failSave    bool
+	failDestroy bool
+	destroyed   []string
+}
+
+func (b *failingBackend) SavePools(pools []models.Pool) error {
+	if b.failSave {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (b *failingBackend) Destroy(name string) error {
+	if b.failDestroy {
+		return errors.New("permission denied")
+	}
+	b.destroyed = append(b.destroyed, name)
+	return nil
+}
+
+func TestRegistry_DeleteKeepsThePoolWhenSavingFails(t *testing.T) {
+	backend := &failingBackend{MemoryBackend: NewMemoryBackend()}
+	registry, err := NewRegistry(backend)
+	require.NoError(t, err)
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+
+	backend.failSave = true
+	assert.ErrorContains(t, registry.Delete(context.Background(), "eu"), "disk full")
+	_, service, err := registry.Get("eu")
+	require.NoError(t, err, "the pool is still saved, so it is still served")
+	assert.NotNil(t, service)
+	assert.Empty(t, backend.destroyed)
+
+	backend.failSave = false
+	require.NoError(t, registry.Delete(context.Background(), "eu"))
+	assert.Equal(t, []string{"eu"}, backend.destroyed)
+}
+
+func TestRegistry_DeleteSucceedsWhenDestroyingFails(t *testing.T) {
+	backend := &failingBackend{MemoryBackend: NewMemoryBackend(), failDestroy: true}
+	registry, err := NewRegistry(backend)
+	require.NoError(t, err)
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+
+	require.NoError(t, registry.Delete(context.Background(), "eu"), "the pool is no longer saved")
+	_, _, err = registry.Get("eu")
+	assert.ErrorIs(t, err, services.ErrPoolNotFound)
+	assert.ErrorIs(t, registry.Delete(context.Background(), "eu"), services.ErrPoolNotFound)
+}
+
+func TestRegistry_AuditsDeletes(t *testing.T) {
+	var entries []audit.Entry
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	registry, err := NewRegistry(NewMemoryBackend(), WithClock(func() time.Time { return at }), WithAudit(func(recorded ...audit.Entry) error {
+		entries = append(entries, recorded...)
+		return nil
+	}))
+	require.NoError(t, err)
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+
+	ctx := audit.NewContext(context.Background(), audit.NewSource("alice", "req-1"))
+	require.NoError(t, registry.Delete(ctx, "eu"))
+	assert.ErrorIs(t, registry.Delete(ctx, "eu"), services.ErrPoolNotFound)
+
+	assert.Equal(t, []audit.Entry{{At: at, Pool: "eu", Operation: audit.OpDeletePool, Actor: "alice", RequestID: "req-1"}}, entries)
+}
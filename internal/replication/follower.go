@@ -0,0 +1,373 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/wal"
+	"matching_system/pkg/logger"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPollInterval  = 5 * time.Second
+	defaultRetryInterval = time.Second
+	defaultIdleTimeout   = 45 * time.Second
+)
+
+// Message is one line of a replication stream. The first message of a stream carries the state of
+// the pool, every later one the records of a batch the leader applied after it. A message with
+// neither is a heartbeat.
+type Message struct {
+	State   *snapshot.State `json:"state,omitempty"`
+	Records []wal.Record    `json:"records,omitempty"`
+}
+
+// StreamPath is where a leader serves the replication stream of a pool
+func StreamPath(pool string) string {
+	return "/v1/pools/" + url.PathEscape(pool) + "/replication/stream"
+}
+
+// Follower keeps the pools of a registry in step with a leader: it creates and deletes pools as the
+// leader does, and streams the operations of each pool into the local one. Until it is promoted the
+// server only serves reads.
+type Follower struct {
+	leader        string
+	registry      *pools.Registry
+	logger        *logger.Logger
+	client        *http.Client
+	pollInterval  time.Duration
+	retryInterval time.Duration
+	idleTimeout   time.Duration
+
+	mu       sync.Mutex
+	promoted bool
+	streams  map[string]*stream
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// stream follows one pool of the leader
+type stream struct {
+	// pool is the leader's pool, a change of it means the pool was recreated there
+	pool      models.Pool
+	cancel    context.CancelFunc
+	connected atomic.Bool
+	revision  atomic.Uint64
+}
+
+// Option configures a Follower
+type Option func(*Follower)
+
+// WithPollInterval sets how often the leader's pools are listed to pick up created and deleted pools
+func WithPollInterval(interval time.Duration) Option {
+	return func(f *Follower) {
+		f.pollInterval = interval
+	}
+}
+
+// WithRetryInterval sets how long a pool waits before it reconnects after its stream broke
+func WithRetryInterval(interval time.Duration) Option {
+	return func(f *Follower) {
+		f.retryInterval = interval
+	}
+}
+
+// WithIdleTimeout sets how long a stream may stay silent, heartbeats included, before it is dropped
+// and reconnected
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(f *Follower) {
+		f.idleTimeout = timeout
+	}
+}
+
+// WithHTTPClient talks to the leader with client. It must not have a timeout, streams are open for
+// as long as the leader is up.
+func WithHTTPClient(client *http.Client) Option {
+	return func(f *Follower) {
+		f.client = client
+	}
+}
+
+// NewFollower follows the leader at the base URL leader, such as http://leader:8080, into registry
+func NewFollower(leader string, registry *pools.Registry, l *logger.Logger, opts ...Option) *Follower {
+	f := &Follower{
+		leader:        leader,
+		registry:      registry,
+		logger:        l,
+		client:        &http.Client{},
+		pollInterval:  defaultPollInterval,
+		retryInterval: defaultRetryInterval,
+		idleTimeout:   defaultIdleTimeout,
+		streams:       make(map[string]*stream),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Start follows the leader in the background until the follower is promoted or stopped
+func (f *Follower) Start() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.wg.Add(1)
+	go f.run(ctx)
+}
+
+// Stop stops following and waits for the streams to end. The pools keep what they replicated.
+func (f *Follower) Stop() {
+	f.mu.Lock()
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.mu.Unlock()
+	f.wg.Wait()
+}
+
+// Promote stops following and makes the server accept writes, on top of what it replicated so far
+func (f *Follower) Promote() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	if !f.promoted {
+		f.promoted = true
+		f.logger.Warn("Promoted to leader, no longer following", "leader", f.leader)
+	}
+	f.mu.Unlock()
+	f.Stop()
+}
+
+// CheckWritable returns ErrReadOnly until the follower is promoted. A nil follower is a leader.
+func (f *Follower) CheckWritable() error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.promoted {
+		return nil
+	}
+	return &services.Error{
+		Code:    services.CodeReadOnly,
+		Message: "server is a read-only follower, send writes to the leader at " + f.leader,
+	}
+}
+
+// Status reports the role of the server and how far each pool got. A nil follower is a leader.
+func (f *Follower) Status() dto.ReplicationStatus {
+	if f == nil {
+		return dto.ReplicationStatus{Role: "leader"}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.promoted {
+		return dto.ReplicationStatus{Role: "leader"}
+	}
+	status := dto.ReplicationStatus{Role: "follower", Leader: f.leader, Pools: []dto.ReplicatedPool{}}
+	for name, s := range f.streams {
+		status.Pools = append(status.Pools, dto.ReplicatedPool{
+			Pool:      name,
+			Connected: s.connected.Load(),
+			Revision:  s.revision.Load(),
+		})
+	}
+	sort.Slice(status.Pools, func(i, j int) bool {
+		return status.Pools[i].Pool < status.Pools[j].Pool
+	})
+	return status
+}
+
+func (f *Follower) run(ctx context.Context) {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := f.syncPools(ctx); err != nil && ctx.Err() == nil {
+			f.logger.Warn("Failed to list the leader's pools", "error", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// syncPools creates and deletes local pools to match the leader's, and follows each of them
+func (f *Follower) syncPools(ctx context.Context) error {
+	var resp dto.QueryPoolsResponse
+	if err := f.get(ctx, "/v1/pools", &resp); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ctx.Err() != nil {
+		// stopped or promoted meanwhile, the pools are no longer the leader's to change
+		return nil
+	}
+
+	leaderPools := make(map[string]models.Pool, len(resp.Pools))
+	for _, pool := range resp.Pools {
+		leaderPools[pool.Name] = pool
+		if s, ok := f.streams[pool.Name]; ok && s.pool.Rules == pool.Rules && s.pool.CreatedAt.Equal(pool.CreatedAt) {
+			continue
+		}
+		if err := f.ensurePool(pool); err != nil {
+			f.logger.Error("Failed to create pool", "pool", pool.Name, "error", err)
+			continue
+		}
+		f.follow(ctx, pool)
+	}
+
+	for _, pool := range f.registry.List() {
+		if _, ok := leaderPools[pool.Name]; ok || pool.Name == pools.DefaultPool {
+			continue
+		}
+		f.unfollow(pool.Name)
+		if err := f.registry.Delete(context.Background(), pool.Name); err != nil && !errors.Is(err, services.ErrPoolNotFound) {
+			f.logger.Error("Failed to delete pool", "pool", pool.Name, "error", err)
+		}
+	}
+	return nil
+}
+
+// ensurePool creates the local pool, or recreates it if its rules differ from the leader's
+func (f *Follower) ensurePool(pool models.Pool) error {
+	local, _, err := f.registry.Get(pool.Name)
+	if err == nil && local.Rules == pool.Rules {
+		return nil
+	}
+	if err == nil {
+		f.unfollow(pool.Name)
+		if err := f.registry.Delete(context.Background(), pool.Name); err != nil {
+			return err
+		}
+	}
+	_, err = f.registry.Create(pool.Name, pool.Rules)
+	return err
+}
+
+// follow (re)starts streaming pool, must be called with mu held
+func (f *Follower) follow(ctx context.Context, pool models.Pool) {
+	f.unfollow(pool.Name)
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &stream{pool: pool, cancel: cancel}
+	f.streams[pool.Name] = s
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			err := f.stream(ctx, s)
+			s.connected.Store(false)
+			if ctx.Err() != nil {
+				return
+			}
+			f.logger.Warn("Replication stream broke, reconnecting", "pool", pool.Name, "error", err)
+			select {
+			case <-time.After(f.retryInterval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// unfollow must be called with mu held
+func (f *Follower) unfollow(name string) {
+	if s, ok := f.streams[name]; ok {
+		s.cancel()
+		delete(f.streams, name)
+	}
+}
+
+// stream resets the local pool to the leader's state and applies the leader's operations after it,
+// until the stream breaks
+func (f *Follower) stream(ctx context.Context, s *stream) error {
+	_, service, err := f.registry.Get(s.pool.Name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.leader+StreamPath(s.pool.Name), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader answered %s", resp.Status)
+	}
+
+	// a leader that went away without closing the connection only shows as silence
+	idle := time.AfterFunc(f.idleTimeout, cancel)
+	defer idle.Stop()
+
+	decoder := json.NewDecoder(resp.Body)
+	for started := false; ; {
+		var message Message
+		if err := decoder.Decode(&message); err != nil {
+			return err
+		}
+		idle.Reset(f.idleTimeout)
+
+		switch {
+		case message.State != nil:
+			service.Reset(*message.State)
+			s.revision.Store(message.State.Revision)
+			s.connected.Store(true)
+			started = true
+		case !started:
+			return errors.New("stream did not start with the state of the pool")
+		case len(message.Records) > 0:
+			revision, err := service.Apply(message.Records)
+			s.revision.Store(revision)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (f *Follower) get(ctx context.Context, path string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, f.idleTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.leader+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader answered %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,186 @@
+package replication_test
+
+import (
+	"encoding/json"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/api/routes"
+	"matching_system/internal/events"
+	"matching_system/internal/pools"
+	"matching_system/internal/replication"
+	"matching_system/internal/webhooks"
+	"matching_system/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer runs a whole API server in process; follower is nil for a leader
+func startServer(t *testing.T, registry *pools.Registry, follower *replication.Follower) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	broker := events.NewBroker(events.DefaultHistorySize)
+	server := httptest.NewServer(routes.Setup(registry, broker, webhooks.NewStore(), follower, nil, nil, nil, nil))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newRegistry(t *testing.T) *pools.Registry {
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend())
+	require.NoError(t, err)
+	return registry
+}
+
+// startCluster starts a leader and a follower of it
+func startCluster(t *testing.T) (leader, follower *httptest.Server, f *replication.Follower) {
+	leader = startServer(t, newRegistry(t), nil)
+
+	registry := newRegistry(t)
+	f = replication.NewFollower(leader.URL, registry, logger.New(),
+		replication.WithPollInterval(20*time.Millisecond),
+		replication.WithRetryInterval(20*time.Millisecond),
+	)
+	f.Start()
+	t.Cleanup(f.Stop)
+	return leader, startServer(t, registry, f), f
+}
+
+func request(t *testing.T, server *httptest.Server, method, path, body string) (int, string) {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+// assertReplicated waits until the follower answers path like the leader
+func assertReplicated(t *testing.T, leader, follower *httptest.Server, path string) {
+	_, want := request(t, leader, "GET", path, "")
+	assert.Eventually(t, func() bool {
+		_, got := request(t, follower, "GET", path, "")
+		return got == want
+	}, 5*time.Second, 10*time.Millisecond, "%s on the follower should match the leader", path)
+}
+
+func TestFollower_ReplicatesPools(t *testing.T) {
+	leader, follower, f := startCluster(t)
+
+	for _, person := range []string{
+		`{"name":"Alice","height":160,"gender":"female","wanted_dates":2}`,
+		`{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`,
+		`{"name":"Carol","height":170,"gender":"female","wanted_dates":1}`,
+	} {
+		code, _ := request(t, leader, "POST", "/v1/people", person)
+		require.Equal(t, http.StatusCreated, code)
+	}
+	code, _ := request(t, leader, "POST", "/v1/pools", `{"name":"tall","rules":{"min_height_gap":5,"max_height_gap":0}}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = request(t, leader, "POST", "/v1/pools/tall/people", `{"name":"Dan","height":190,"gender":"male","wanted_dates":3}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	assertReplicated(t, leader, follower, "/v1/people")
+	assertReplicated(t, leader, follower, "/v1/matches")
+	assertReplicated(t, leader, follower, "/v1/pools/tall/people")
+	_, pool := request(t, follower, "GET", "/v1/pools/tall", "")
+	assert.Contains(t, pool, `"min_height_gap":5`)
+
+	// later writes stream in as they happen
+	code, body := request(t, leader, "GET", "/v1/people", "")
+	require.Equal(t, http.StatusOK, code)
+	var people dto.QueryPeopleResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &people))
+	for _, person := range people.People {
+		code, _ = request(t, leader, "DELETE", "/v1/people/"+person.ID, "")
+		require.Equal(t, http.StatusOK, code)
+	}
+	assertReplicated(t, leader, follower, "/v1/people")
+
+	status := f.Status()
+	assert.Equal(t, "follower", status.Role)
+	assert.Equal(t, leader.URL, status.Leader)
+	require.Len(t, status.Pools, 2)
+	assert.Equal(t, "default", status.Pools[0].Pool)
+	assert.True(t, status.Pools[0].Connected)
+
+	// deleted pools go away on the follower too
+	code, _ = request(t, leader, "DELETE", "/v1/pools/tall", "")
+	require.Equal(t, http.StatusNoContent, code)
+	assert.Eventually(t, func() bool {
+		code, _ := request(t, follower, "GET", "/v1/pools/tall", "")
+		return code == http.StatusNotFound
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestFollower_ReadOnlyUntilPromoted(t *testing.T) {
+	leader, follower, _ := startCluster(t)
+	code, _ := request(t, leader, "POST", "/v1/people", `{"name":"Alice","height":160,"gender":"female","wanted_dates":2}`)
+	require.Equal(t, http.StatusCreated, code)
+	assertReplicated(t, leader, follower, "/v1/people")
+
+	code, body := request(t, follower, "POST", "/v1/people", `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, `"code":"read_only"`)
+	code, _ = request(t, follower, "POST", "/v1/pools", `{"name":"tall"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	code, body = request(t, follower, "POST", "/graphql", `{"query":"mutation { removePerson(id: \"x\") }"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"code":"read_only"`)
+
+	code, body = request(t, follower, "POST", "/v1/replication/promote", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"role":"leader"}`, body)
+
+	// the promoted follower takes writes on top of what it replicated, and no longer follows
+	code, body = request(t, follower, "POST", "/v1/people", `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	require.Equal(t, http.StatusCreated, code)
+	assert.Contains(t, body, `"name":"Alice"`, "Bob should match the replicated Alice")
+	code, _ = request(t, leader, "POST", "/v1/people", `{"name":"Carol","height":170,"gender":"female","wanted_dates":1}`)
+	require.Equal(t, http.StatusCreated, code)
+	time.Sleep(100 * time.Millisecond)
+	_, body = request(t, follower, "GET", "/v1/people", "")
+	assert.NotContains(t, body, "Carol")
+}
+
+func TestFollower_LeaderStatus(t *testing.T) {
+	leader := startServer(t, newRegistry(t), nil)
+
+	code, body := request(t, leader, "GET", "/v1/replication", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"role":"leader"}`, body)
+}
+
+func TestFollower_Reconnects(t *testing.T) {
+	registry := newRegistry(t)
+	service := registry.Default()
+	service.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+
+	leader := startServer(t, registry, nil)
+
+	followerRegistry := newRegistry(t)
+	f := replication.NewFollower(leader.URL, followerRegistry, logger.New(),
+		replication.WithPollInterval(20*time.Millisecond),
+		replication.WithRetryInterval(20*time.Millisecond),
+	)
+	f.Start()
+	defer f.Stop()
+	assert.Eventually(t, func() bool {
+		return len(followerRegistry.Default().QuerySinglePeople(0)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// cutting the streams makes the follower start over from a fresh state
+	leader.CloseClientConnections()
+	service.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1})
+	assert.Eventually(t, func() bool {
+		return len(followerRegistry.Default().QuerySinglePeople(0)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, service.Snapshot().Revision, followerRegistry.Default().Snapshot().Revision)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/audit"
+	"matching_system/internal/models"
+	"time"
+)
+
+// WithAudit has record told of every add, update, remove and import made on request, with the
+// source in the request's context and the person before and after, and of the partners each write
+// matched the person with. Writes replayed from the write-ahead log or applied from another
+// server's operations are not recorded. record is called by the writer with the entries of a batch
+// once the batch is durable; an error is logged, the writes stand.
+func WithAudit(record func(entries ...audit.Entry) error) Option {
+	return func(ms *matchService) {
+		ms.recordAudit = record
+	}
+}
+
+// audit queues the entry of a write for the batch to record
+func (ms *matchService) audit(ctx context.Context, op audit.Op, at time.Time, before, after *models.Person) {
+	if ms.recordAudit == nil {
+		return
+	}
+	source := audit.FromContext(ctx)
+	entry := audit.Entry{
+		At:        at,
+		Pool:      ms.pool,
+		Operation: op,
+		Actor:     source.Actor,
+		RequestID: source.RequestID,
+		LogIndex:  source.LogIndex,
+	}
+	// the stored person keeps changing, the entry keeps copies
+	if before != nil {
+		person := *before
+		entry.Before, entry.PersonID = &person, person.ID
+	}
+	if after != nil {
+		person := *after
+		entry.After, entry.PersonID = &person, person.ID
+	}
+	ms.auditing = append(ms.auditing, entry)
+}
+
+// auditPartners queues the entries of the people a write matched: each has one date less, and is
+// removed once they have none left. The matches hold the partners as they were before.
+func (ms *matchService) auditPartners(ctx context.Context, at time.Time, matches []models.Match) {
+	if ms.recordAudit == nil {
+		return
+	}
+	for _, match := range matches {
+		before := match.Person2
+		if after, ok := ms.people.Get(before.ID); ok {
+			ms.audit(ctx, audit.OpUpdate, at, &before, after)
+		} else {
+			ms.audit(ctx, audit.OpRemove, at, &before, nil)
+		}
+	}
+}
+
+// flushAudit records the entries of the batch
+func (ms *matchService) flushAudit() {
+	entries := ms.auditing
+	ms.auditing = nil
+	if len(entries) == 0 {
+		return
+	}
+	if err := ms.recordAudit(entries...); err != nil {
+		ms.logger.Error("Failed to record writes in the audit log", "pool", ms.pool, "entries", len(entries), "error", err)
+	}
+}
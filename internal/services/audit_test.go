@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/wal"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// auditRecorder keeps the entries a service records, see WithAudit
+type auditRecorder struct {
+	entries []audit.Entry
+}
+
+func (r *auditRecorder) record(entries ...audit.Entry) error {
+	r.entries = append(r.entries, entries...)
+	return nil
+}
+
+func TestMatchService_AuditsWrites(t *testing.T) {
+	var recorder auditRecorder
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := NewMatchService(WithPool("eu", DefaultRules), WithAudit(recorder.record), WithClock(func() time.Time { return at }))
+	ctx := audit.NewContext(context.Background(), audit.NewSource("alice", "req-1"))
+
+	alice, _, err := ms.AddSinglePersonAndMatchIdempotent(ctx, "key", dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	require.NoError(t, err)
+	// a replayed add changes nothing
+	_, _, err = ms.AddSinglePersonAndMatchIdempotent(ctx, "key", dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	require.NoError(t, err)
+	_, _, err = ms.UpdateSinglePerson(ctx, alice.ID, dto.UpdatePersonRequest{Name: "Alicia", Height: 165, Gender: "female", WantedDates: 2}, 0)
+	require.NoError(t, err)
+	require.ErrorIs(t, ms.RemoveSinglePersonIfMatch(ctx, alice.ID, 1), ErrVersionMismatch)
+	require.NoError(t, ms.RemoveSinglePersonIfMatch(context.Background(), alice.ID, 2))
+
+	require.Len(t, recorder.entries, 3)
+	add, update, remove := recorder.entries[0], recorder.entries[1], recorder.entries[2]
+
+	assert.Equal(t, audit.OpAdd, add.Operation)
+	assert.Equal(t, "eu", add.Pool)
+	assert.Equal(t, alice.ID, add.PersonID)
+	assert.Equal(t, "alice", add.Actor)
+	assert.Equal(t, "req-1", add.RequestID)
+	assert.Equal(t, at, add.At)
+	assert.Nil(t, add.Before)
+	assert.Equal(t, *alice, *add.After)
+
+	assert.Equal(t, audit.OpUpdate, update.Operation)
+	assert.Equal(t, "Alice", update.Before.Name)
+	assert.Equal(t, int64(1), update.Before.Version)
+	assert.Equal(t, "Alicia", update.After.Name)
+	assert.Equal(t, int64(2), update.After.Version)
+
+	assert.Equal(t, audit.OpRemove, remove.Operation)
+	assert.Equal(t, audit.SystemActor, remove.Actor, "writes outside of requests are the system's")
+	assert.Equal(t, "Alicia", remove.Before.Name)
+	assert.Nil(t, remove.After)
+}
+
+func TestMatchService_AuditsMatchedPeopleAsMatched(t *testing.T) {
+	var recorder auditRecorder
+	ms := NewMatchService(WithAudit(recorder.record))
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 165, Gender: "female", WantedDates: 2})
+	ctx := audit.NewContext(context.Background(), audit.NewSource("bob", "req-3"))
+	_, _, err := ms.AddSinglePersonAndMatchIdempotent(ctx, "", dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 2})
+	require.NoError(t, err)
+
+	require.Len(t, recorder.entries, 5)
+	bob, alice, carol := recorder.entries[2], recorder.entries[3], recorder.entries[4]
+	assert.Equal(t, 0, bob.After.WantedDates, "after is the person once matched")
+
+	// the partners were changed by Bob's add, and are recorded as such
+	assert.Equal(t, audit.OpRemove, alice.Operation, "Alice has no dates left")
+	assert.Equal(t, "Alice", alice.Before.Name)
+	assert.Equal(t, 1, alice.Before.WantedDates)
+	assert.Nil(t, alice.After)
+	assert.Equal(t, audit.OpUpdate, carol.Operation)
+	assert.Equal(t, 2, carol.Before.WantedDates)
+	assert.Equal(t, 1, carol.After.WantedDates)
+	assert.Equal(t, carol.Before.Version+1, carol.After.Version)
+	for _, entry := range []audit.Entry{alice, carol} {
+		assert.Equal(t, "bob", entry.Actor)
+		assert.Equal(t, "req-3", entry.RequestID)
+	}
+}
+
+func TestMatchService_AuditsImports(t *testing.T) {
+	var recorder auditRecorder
+	ms := NewMatchService(WithAudit(recorder.record))
+	ctx := audit.NewContext(context.Background(), audit.NewSource("ops", "req-2"))
+	people := []dto.ImportPerson{
+		{ID: "a", Name: "Alice", Height: 160, Gender: "female", WantedDates: 1},
+		{ID: "b", Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+	}
+
+	_, _, err := ms.ImportPeople(ctx, people, false, true)
+	require.NoError(t, err)
+	assert.Empty(t, recorder.entries, "a dry run changes nothing")
+
+	_, _, err = ms.ImportPeople(ctx, people, false, false)
+	require.NoError(t, err)
+	require.Len(t, recorder.entries, 2)
+	for i, entry := range recorder.entries {
+		assert.Equal(t, audit.OpImport, entry.Operation)
+		assert.Equal(t, people[i].ID, entry.PersonID)
+		assert.Equal(t, "ops", entry.Actor)
+	}
+}
+
+func TestMatchService_DoesNotAuditReplays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	journal, _, err := wal.Open(path)
+	require.NoError(t, err)
+	ms := NewMatchService(WithWriteAheadLog(journal, nil))
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	require.NoError(t, journal.Close())
+
+	journal, records, err := wal.Open(path)
+	require.NoError(t, err)
+	defer journal.Close()
+	var recorder auditRecorder
+	replayed := NewMatchService(WithWriteAheadLog(journal, records), WithAudit(recorder.record))
+	require.Len(t, replayed.QuerySinglePeople(0), 1)
+	assert.Empty(t, recorder.entries)
+
+	follower := NewMatchService(WithAudit(recorder.record))
+	_, err = follower.Apply(records)
+	require.NoError(t, err)
+	assert.Empty(t, recorder.entries)
+}
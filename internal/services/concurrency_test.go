@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/events"
+	"matching_system/internal/wal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchService_ConcurrentWritesReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	journal, _ := openJournal(t, path)
+	ms := NewMatchService(WithWriteAheadLog(journal, nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				gender := "female"
+				if (i+j)%2 == 0 {
+					gender = "male"
+				}
+				person, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: fmt.Sprintf("P%d-%d", i, j), Height: 150 + (i*7+j*3)%50, Gender: gender, WantedDates: 1 + j%3})
+				if j%5 == 0 {
+					ms.RemoveSinglePerson(person.ID)
+				}
+				ms.QuerySinglePeople(10)
+			}
+		}(i)
+	}
+	wg.Wait()
+	before := ms.Snapshot()
+
+	// however the writes interleaved, the log replays them in the order they were applied
+	journal.Close()
+	journal, records := openJournal(t, path)
+	restored := NewMatchService(WithWriteAheadLog(journal, records))
+	assertSameState(t, before, restored.Snapshot())
+}
+
+func TestMatchService_ConcurrentWritesPublishInOrder(t *testing.T) {
+	bus := events.NewBus()
+	var (
+		published int
+		active    = map[string]bool{}
+		problems  []string
+	)
+	// the bus delivers one event at a time, so the subscriber needs no lock of its own
+	bus.Subscribe(func(event events.Event) {
+		published++
+		switch event.Type {
+		case events.PersonAdded:
+			active[event.PersonID] = true
+		case events.Matched:
+			if !active[event.Match.Person1.ID] || !active[event.Match.Person2.ID] {
+				problems = append(problems, fmt.Sprintf("match of %s before they were added", event.PersonID))
+			}
+		case events.DatesExhausted, events.PersonRemoved:
+			if !active[event.PersonID] {
+				problems = append(problems, fmt.Sprintf("%s left twice", event.PersonID))
+			}
+			delete(active, event.PersonID)
+		}
+	})
+	ms := NewMatchService(WithEvents(bus))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				gender := "female"
+				if (i+j)%2 == 0 {
+					gender = "male"
+				}
+				ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 150 + (i+j)%40, Gender: gender, WantedDates: 2})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Empty(t, problems)
+	assert.Len(t, ms.QuerySinglePeople(0), len(active))
+	assert.Equal(t, uint64(published), ms.Snapshot().Revision)
+}
+
+func TestMatchService_PanicReachesCaller(t *testing.T) {
+	ms := NewMatchService().(*matchService)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		ms.write(context.Background(), func() { panic("boom") })
+	})
+	// the writer is released, later writes still go through
+	person, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	_, ok := ms.GetSinglePerson(person.ID)
+	assert.True(t, ok)
+}
+
+// benchmarkWomen fills a pool with women nobody matches in the benchmarks
+func benchmarkWomen(ms MatchService, n int) {
+	for i := 0; i < n; i++ {
+		ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 140 + i%60, Gender: "female", WantedDates: 1 + i%5})
+	}
+}
+
+// benchmarkWrite adds a woman and removes her again, two writes that never match anyone
+func benchmarkWrite(ms MatchService) {
+	person, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1})
+	ms.RemoveSinglePerson(person.ID)
+}
+
+func benchmarkJournal(b *testing.B) Option {
+	journal, _, err := wal.Open(filepath.Join(b.TempDir(), "wal.log"))
+	require.NoError(b, err)
+	b.Cleanup(func() { journal.Close() })
+	return WithWriteAheadLog(journal, nil)
+}
+
+func BenchmarkMatchService_Writes(b *testing.B) {
+	ms := NewMatchService()
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			benchmarkWrite(ms)
+		}
+	})
+}
+
+func BenchmarkMatchService_WritesWithJournal(b *testing.B) {
+	ms := NewMatchService(benchmarkJournal(b))
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			benchmarkWrite(ms)
+		}
+	})
+}
+
+// BenchmarkMatchService_Reads queries a pool of 10000 people while every tenth operation writes
+func BenchmarkMatchService_Reads(b *testing.B) {
+	ms := NewMatchService()
+	benchmarkWomen(ms, 10000)
+	var ops atomic.Int64
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			switch ops.Add(1) % 10 {
+			case 0:
+				benchmarkWrite(ms)
+			case 1:
+				ms.FindCandidates("male", 200, 20)
+			default:
+				ms.QuerySinglePeople(20)
+			}
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package services
+
+import "strings"
+
+// ErrorCode is a stable, machine readable identifier of a failure that clients can rely on
+type ErrorCode string
+
+const (
+	CodeValidationFailed       ErrorCode = "validation_failed"
+	CodeMalformedRequest       ErrorCode = "malformed_request"
+	CodePersonNotFound         ErrorCode = "person_not_found"
+	CodeRouteNotFound          ErrorCode = "route_not_found"
+	CodeMethodNotAllowed       ErrorCode = "method_not_allowed"
+	CodeVersionMismatch        ErrorCode = "version_mismatch"
+	CodeIdempotencyKeyMismatch ErrorCode = "idempotency_key_mismatch"
+	CodeWebhookNotFound        ErrorCode = "webhook_not_found"
+	CodePoolNotFound           ErrorCode = "pool_not_found"
+	CodePoolExists             ErrorCode = "pool_exists"
+	CodeReadOnly               ErrorCode = "read_only"
+	CodeNoLeader               ErrorCode = "no_leader"
+	CodeShardUnavailable       ErrorCode = "shard_unavailable"
+	CodeInternal               ErrorCode = "internal_error"
+)
+
+// FieldError describes why a single request field was rejected
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is the typed error returned by the service layer and mapped to HTTP problems by the API
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+
+	details := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		details = append(details, field.Field+" "+field.Message)
+	}
+	return e.Message + ": " + strings.Join(details, ", ")
+}
+
+// Is reports errors with the same code as equal, so errors.Is works against the sentinels below
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var (
+	// ErrPersonNotFound is returned when no active person has the given ID
+	ErrPersonNotFound = &Error{Code: CodePersonNotFound, Message: "person not found"}
+	// ErrVersionMismatch is returned when a conditional write targets a stale version of a person
+	ErrVersionMismatch = &Error{Code: CodeVersionMismatch, Message: "person version does not match"}
+	// ErrIdempotencyKeyMismatch is returned when an idempotency key is reused with a different request body
+	ErrIdempotencyKeyMismatch = &Error{Code: CodeIdempotencyKeyMismatch, Message: "idempotency key was already used with a different request"}
+	// ErrWebhookNotFound is returned when no webhook subscription has the given ID
+	ErrWebhookNotFound = &Error{Code: CodeWebhookNotFound, Message: "webhook not found"}
+	// ErrPoolNotFound is returned when no pool has the given name
+	ErrPoolNotFound = &Error{Code: CodePoolNotFound, Message: "pool not found"}
+	// ErrPoolExists is returned when creating a pool under a name that is taken
+	ErrPoolExists = &Error{Code: CodePoolExists, Message: "pool already exists"}
+	// ErrReadOnly is returned for writes sent to a follower, which only applies what its leader sends
+	ErrReadOnly = &Error{Code: CodeReadOnly, Message: "server is a read-only follower"}
+	// ErrNoLeader is returned for writes a cluster cannot commit because it has no leader right now
+	ErrNoLeader = &Error{Code: CodeNoLeader, Message: "cluster has no leader"}
+	// ErrJournalFailed is returned for writes to a pool whose write-ahead log failed, which can no
+	// longer make writes durable
+	ErrJournalFailed = &Error{Code: CodeInternal, Message: "write-ahead log failed, the pool no longer takes writes"}
+	// ErrShardUnavailable is returned by the shard router when the shard owning a pool does not answer
+	ErrShardUnavailable = &Error{Code: CodeShardUnavailable, Message: "shard is unavailable"}
+)
+
+// NewValidationError reports one or more invalid request fields
+func NewValidationError(fields ...FieldError) *Error {
+	return &Error{Code: CodeValidationFailed, Message: "request validation failed", Fields: fields}
+}
+
+// NewMalformedRequestError reports a request that could not be decoded at all
+func NewMalformedRequestError(message string) *Error {
+	return &Error{Code: CodeMalformedRequest, Message: message}
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_Is(t *testing.T) {
+	wrapped := fmt.Errorf("remove: %w", &Error{Code: CodePersonNotFound, Message: "no such person"})
+
+	assert.True(t, errors.Is(wrapped, ErrPersonNotFound), "errors with the same code should match")
+	assert.False(t, errors.Is(wrapped, ErrVersionMismatch), "errors with another code should not match")
+}
+
+func TestError_Error(t *testing.T) {
+	err := NewValidationError(
+		FieldError{Field: "height", Message: "must be at least 100"},
+		FieldError{Field: "gender", Message: "is required"},
+	)
+
+	assert.Equal(t, "request validation failed: height must be at least 100, gender is required", err.Error())
+	assert.Equal(t, "person not found", ErrPersonNotFound.Error())
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/events"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func drain(sub *events.Subscription) []events.Type {
+	var types []events.Type
+	for {
+		select {
+		case event := <-sub.Events():
+			types = append(types, event.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestMatchService_PublishesEvents(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	ms := NewMatchService(WithEvents(broker))
+	sub := broker.Subscribe(16)
+
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	assert.Equal(t, []events.Type{events.PersonAdded}, drain(sub))
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	assert.Equal(t, []events.Type{events.PersonAdded, events.Matched, events.DatesExhausted}, drain(sub))
+
+	ms.UpdateSinglePerson(context.Background(), alice.ID, dto.UpdatePersonRequest{Name: "Alice", Height: 162, Gender: "female", WantedDates: 1}, 0)
+	assert.Equal(t, []events.Type{events.PersonUpdated}, drain(sub))
+
+	ms.RemoveSinglePerson(alice.ID)
+	assert.Equal(t, []events.Type{events.PersonRemoved}, drain(sub))
+}
+
+func TestMatchService_MatchedEventInvolvesBothPeople(t *testing.T) {
+	broker := events.NewBroker(events.DefaultHistorySize)
+	ms := NewMatchService(WithEvents(broker))
+
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	sub := broker.Subscribe(16)
+	bob, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	<-sub.Events()
+	matched := <-sub.Events()
+	assert.Equal(t, events.Matched, matched.Type)
+	assert.True(t, matched.Involves(alice.ID), "Alice should be notified of the match")
+	assert.True(t, matched.Involves(bob.ID), "Bob should be notified of the match")
+}
+
+func TestMatchService_PublishesToBusHandlers(t *testing.T) {
+	bus := events.NewBus()
+	ms := NewMatchService(WithEvents(bus))
+
+	// handlers run synchronously, so the side effect has happened by the time the call returns
+	var matched, all []events.Event
+	bus.Subscribe(func(event events.Event) { matched = append(matched, event) }, events.Matched)
+	bus.Subscribe(func(event events.Event) { all = append(all, event) })
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	bob, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	assert.Len(t, matched, 1)
+	assert.Equal(t, bob.ID, matched[0].PersonID)
+	assert.Equal(t, all[0].ID+2, matched[0].ID, "person_added for Alice and Bob came first")
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"container/list"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"time"
+)
+
+const (
+	defaultIdempotencyTTL      = 24 * time.Hour
+	defaultIdempotencyCapacity = 10000
+)
+
+// IdempotencyKey is an add request remembered by its key, with the person and matches it made, as
+// a cluster snapshot keeps it
+type IdempotencyKey struct {
+	Key       string               `json:"key"`
+	Request   dto.AddPersonRequest `json:"request"`
+	Person    models.Person        `json:"person"`
+	Matches   []models.Match       `json:"matches,omitempty"`
+	ExpiresAt time.Time            `json:"expires_at"`
+}
+
+type idempotencyEntry struct {
+	key       string
+	request   dto.AddPersonRequest
+	person    models.Person
+	matches   []models.Match
+	expiresAt time.Time
+}
+
+// idempotencyCache remembers the outcome of add requests by key for a bounded time and size.
+// Entries share the same TTL, so insertion order is also expiry order and the oldest entry
+// is always at the front of the list.
+type idempotencyCache struct {
+	ttl      time.Duration
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+func newIdempotencyCache(ttl time.Duration, capacity int) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:      ttl,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (c *idempotencyCache) get(key string) (*idempotencyEntry, bool) {
+	c.evictExpired()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return elem.Value.(*idempotencyEntry), true
+}
+
+func (c *idempotencyCache) put(key string, req dto.AddPersonRequest, person models.Person, matches []models.Match) {
+	c.evictExpired()
+
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+	for c.capacity > 0 && c.order.Len() >= c.capacity {
+		c.removeOldest()
+	}
+
+	c.entries[key] = c.order.PushBack(&idempotencyEntry{
+		key:       key,
+		request:   req,
+		person:    person,
+		matches:   append([]models.Match(nil), matches...),
+		expiresAt: c.now().Add(c.ttl),
+	})
+}
+
+// keys returns the entries, oldest first
+func (c *idempotencyCache) keys() []IdempotencyKey {
+	keys := make([]IdempotencyKey, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*idempotencyEntry)
+		keys = append(keys, IdempotencyKey{
+			Key:       entry.key,
+			Request:   entry.request,
+			Person:    entry.person,
+			Matches:   append([]models.Match(nil), entry.matches...),
+			ExpiresAt: entry.expiresAt,
+		})
+	}
+	return keys
+}
+
+// restore replaces the entries with keys, which keep their expiry
+func (c *idempotencyCache) restore(keys []IdempotencyKey) {
+	c.entries = make(map[string]*list.Element, len(keys))
+	c.order.Init()
+	for _, key := range keys {
+		if elem, ok := c.entries[key.Key]; ok {
+			c.order.Remove(elem)
+		}
+		c.entries[key.Key] = c.order.PushBack(&idempotencyEntry{
+			key:       key.Key,
+			request:   key.Request,
+			person:    key.Person,
+			matches:   append([]models.Match(nil), key.Matches...),
+			expiresAt: key.ExpiresAt,
+		})
+	}
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		c.removeOldest()
+	}
+	c.evictExpired()
+}
+
+func (c *idempotencyCache) evictExpired() {
+	now := c.now()
+	for front := c.order.Front(); front != nil; front = c.order.Front() {
+		if front.Value.(*idempotencyEntry).expiresAt.After(now) {
+			return
+		}
+		c.removeOldest()
+	}
+}
+
+func (c *idempotencyCache) removeOldest() {
+	front := c.order.Front()
+	if front == nil {
+		return
+	}
+	c.order.Remove(front)
+	delete(c.entries, front.Value.(*idempotencyEntry).key)
+}
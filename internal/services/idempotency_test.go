@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchService_AddSinglePersonAndMatchIdempotent_Replay(t *testing.T) {
+	ms := NewMatchService()
+
+	female := dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2}
+	male := dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1}
+
+	ms.AddSinglePersonAndMatch(female)
+
+	// first request creates the person and matches
+	person, matches, err := ms.AddSinglePersonAndMatchIdempotent(context.Background(), "key-1", male)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(matches), "should match once")
+
+	// retry replays the original response without creating anyone
+	replayed, replayedMatches, err := ms.AddSinglePersonAndMatchIdempotent(context.Background(), "key-1", male)
+	assert.NoError(t, err)
+	assert.Equal(t, *person, *replayed, "the person should be replayed")
+	assert.Equal(t, matches, replayedMatches, "the matches should be replayed")
+
+	result := ms.QuerySinglePeople(0)
+	assert.Equal(t, 1, len(result), "only Alice should remain")
+	assert.Equal(t, 1, result[0].WantedDates, "Alice should have used one date only")
+}
+
+func TestMatchService_AddSinglePersonAndMatchIdempotent_Mismatch(t *testing.T) {
+	ms := NewMatchService()
+
+	req := dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2}
+	_, _, err := ms.AddSinglePersonAndMatchIdempotent(context.Background(), "key-1", req)
+	assert.NoError(t, err)
+
+	req.Height = 165
+	_, _, err = ms.AddSinglePersonAndMatchIdempotent(context.Background(), "key-1", req)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyMismatch)
+
+	assert.Equal(t, 1, len(ms.QuerySinglePeople(0)), "should not add a second person")
+}
+
+func TestIdempotencyCache_Expiry(t *testing.T) {
+	now := time.Now()
+	cache := newIdempotencyCache(time.Minute, 10)
+	cache.now = func() time.Time { return now }
+
+	cache.put("key-1", dto.AddPersonRequest{Name: "Alice"}, models.Person{ID: "1"}, nil)
+
+	_, ok := cache.get("key-1")
+	assert.True(t, ok, "should be cached before the TTL")
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get("key-1")
+	assert.False(t, ok, "should expire after the TTL")
+}
+
+func TestIdempotencyCache_Capacity(t *testing.T) {
+	cache := newIdempotencyCache(time.Hour, 2)
+
+	cache.put("key-1", dto.AddPersonRequest{Name: "Alice"}, models.Person{ID: "1"}, nil)
+	cache.put("key-2", dto.AddPersonRequest{Name: "Bob"}, models.Person{ID: "2"}, nil)
+	cache.put("key-3", dto.AddPersonRequest{Name: "Carol"}, models.Person{ID: "3"}, nil)
+
+	_, ok := cache.get("key-1")
+	assert.False(t, ok, "the oldest key should be evicted")
+	_, ok = cache.get("key-3")
+	assert.True(t, ok, "the newest key should be kept")
+}
+
+func TestIdempotencyCache_KeysAndRestore(t *testing.T) {
+	now := time.Now()
+	cache := newIdempotencyCache(time.Minute, 10)
+	cache.now = func() time.Time { return now }
+	cache.put("key-1", dto.AddPersonRequest{Name: "Alice"}, models.Person{ID: "1"}, nil)
+	now = now.Add(30 * time.Second)
+	cache.put("key-2", dto.AddPersonRequest{Name: "Bob"}, models.Person{ID: "2"}, []models.Match{{Person1: models.Person{ID: "2"}, Person2: models.Person{ID: "1"}}})
+
+	restored := newIdempotencyCache(time.Minute, 10)
+	restored.now = cache.now
+	restored.restore(cache.keys())
+	assert.Equal(t, cache.keys(), restored.keys())
+
+	// the keys expire as they would have, not a TTL after the restore
+	now = now.Add(45 * time.Second)
+	_, ok := restored.get("key-1")
+	assert.False(t, ok)
+	entry, ok := restored.get("key-2")
+	assert.True(t, ok)
+	assert.Equal(t, "2", entry.person.ID)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/internal/wal"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxImportErrors keeps the problem for a badly broken file readable
+const maxImportErrors = 100
+
+// ImportPeople is traced as one operation, without the stages of matching each person
+func (ms *matchService) ImportPeople(ctx context.Context, people []dto.ImportPerson, runMatching bool, dryRun bool) (imported []models.Person, matches []models.Match, err error) {
+	ctx, span := ms.startOperation(ctx, "MatchService.ImportPeople",
+		attribute.Int("people", len(people)),
+		attribute.Bool("match", runMatching),
+		attribute.Bool("dry_run", dryRun),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if err := ms.write(ctx, func() {
+		imported, matches, err = ms.importPeople(ctx, people, runMatching, dryRun)
+	}); err != nil {
+		return nil, nil, err
+	}
+	return imported, matches, err
+}
+
+func (ms *matchService) importPeople(ctx context.Context, people []dto.ImportPerson, runMatching bool, dryRun bool) ([]models.Person, []models.Match, error) {
+	imported, err := ms.validateImport(people)
+	if err != nil || dryRun {
+		return imported, nil, err
+	}
+
+	var matches []models.Match
+	for _, person := range imported {
+		person := person
+		at := ms.now()
+		if runMatching {
+			ms.record(wal.OpAdd, at, person)
+			made := ms.insertPerson(context.Background(), &person, at)
+			ms.audit(ctx, audit.OpImport, at, nil, &person)
+			ms.auditPartners(ctx, at, made)
+			matches = append(matches, made...)
+		} else {
+			ms.record(wal.OpImport, at, person)
+			ms.importPerson(&person)
+			ms.audit(ctx, audit.OpImport, at, nil, &person)
+		}
+	}
+	return imported, matches, nil
+}
+
+// validateImport checks every person against the add rules and for IDs that are taken, reporting
+// the problems of the whole file at once
+func (ms *matchService) validateImport(people []dto.ImportPerson) ([]models.Person, error) {
+	var fields []FieldError
+	fail := func(line int, field, message string) {
+		if len(fields) < maxImportErrors {
+			fields = append(fields, FieldError{Field: fmt.Sprintf("line %d: %s", line, field), Message: message})
+		}
+	}
+
+	imported := make([]models.Person, 0, len(people))
+	lines := make(map[string]int, len(people))
+	for i, person := range people {
+		line := person.Line
+		if line == 0 {
+			line = i + 1
+		}
+
+		if err := ValidateRequest(person); err != nil {
+			var serviceErr *Error
+			if !errors.As(err, &serviceErr) {
+				return nil, err
+			}
+			for _, field := range serviceErr.Fields {
+				fail(line, field.Field, field.Message)
+			}
+		}
+
+		if person.ID == "" {
+			person.ID = ms.newID()
+		}
+		if first, ok := lines[person.ID]; ok {
+			fail(line, "id", fmt.Sprintf("duplicates line %d", first))
+		} else if _, ok := ms.people.Get(person.ID); ok {
+			fail(line, "id", "is already in the pool")
+		} else {
+			lines[person.ID] = line
+		}
+
+		if person.Version == 0 {
+			person.Version = 1
+		}
+		imported = append(imported, models.Person{
+			ID:          person.ID,
+			Name:        person.Name,
+			Height:      person.Height,
+			Gender:      person.Gender,
+			WantedDates: person.WantedDates,
+			Version:     person.Version,
+		})
+	}
+
+	if len(fields) > 0 {
+		return nil, NewValidationError(fields...)
+	}
+	return imported, nil
+}
+
+// importPerson adds a person as is. Compatible people may end up side by side in the pool until one
+// of them is matched again.
+func (ms *matchService) importPerson(person *models.Person) {
+	ms.people.Put(person)
+	ms.publish(events.PersonAdded, *person, nil)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchService_ImportPeople(t *testing.T) {
+	ms := NewMatchService()
+	people := []dto.ImportPerson{
+		{Line: 1, ID: "alice", Name: "Alice", Height: 160, Gender: "female", WantedDates: 1, Version: 4},
+		{Line: 2, Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+	}
+
+	// dry run validates only
+	imported, matches, err := ms.ImportPeople(context.Background(), people, false, true)
+	require.NoError(t, err)
+	assert.Len(t, imported, 2)
+	assert.Empty(t, matches)
+	assert.Empty(t, ms.QuerySinglePeople(0), "a dry run should not add anyone")
+
+	// without matching, compatible people stay side by side
+	imported, matches, err = ms.ImportPeople(context.Background(), people, false, false)
+	require.NoError(t, err)
+	assert.Empty(t, matches)
+	assert.Equal(t, "alice", imported[0].ID, "given IDs should be kept")
+	assert.Equal(t, int64(4), imported[0].Version, "given versions should be kept")
+	assert.NotEmpty(t, imported[1].ID, "a missing ID should be generated")
+	assert.Equal(t, int64(1), imported[1].Version, "a missing version should start at 1")
+	assert.Len(t, ms.QuerySinglePeople(0), 2)
+}
+
+func TestMatchService_ImportPeople_Matching(t *testing.T) {
+	ms := NewMatchService()
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+
+	_, matches, err := ms.ImportPeople(context.Background(), []dto.ImportPerson{
+		{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+		{Name: "Carol", Height: 150, Gender: "female", WantedDates: 1},
+		{Name: "David", Height: 170, Gender: "male", WantedDates: 1},
+	}, true, false)
+	require.NoError(t, err)
+
+	// imported people are matched in file order, against the pool and earlier lines
+	require.Len(t, matches, 2)
+	assert.Equal(t, "Alice", matches[0].Person2.Name)
+	assert.Equal(t, "David", matches[1].Person1.Name)
+	assert.Equal(t, "Carol", matches[1].Person2.Name)
+	assert.Empty(t, ms.QuerySinglePeople(0))
+}
+
+func TestMatchService_ImportPeople_Invalid(t *testing.T) {
+	ms := NewMatchService()
+	_, _, err := ms.ImportPeople(context.Background(), []dto.ImportPerson{{ID: "alice", Name: "Alice", Height: 160, Gender: "female", WantedDates: 1}}, false, false)
+	require.NoError(t, err)
+
+	_, _, err = ms.ImportPeople(context.Background(), []dto.ImportPerson{
+		{Line: 2, ID: "bob", Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+		{Line: 3, ID: "alice", Name: "Alice", Height: 160, Gender: "female", WantedDates: 1},
+		{Line: 4, ID: "bob", Name: "Bob", Height: 90, Gender: "other", WantedDates: 1},
+	}, false, false)
+
+	assert.ErrorIs(t, err, &Error{Code: CodeValidationFailed})
+	assert.Equal(t, []FieldError{
+		{Field: "line 3: id", Message: "is already in the pool"},
+		{Field: "line 4: height", Message: "must be at least 100"},
+		{Field: "line 4: gender", Message: "must be one of: male, female"},
+		{Field: "line 4: id", Message: "duplicates line 2"},
+	}, err.(*Error).Fields)
+	assert.Len(t, ms.QuerySinglePeople(0), 1, "nothing should be imported when any line is invalid")
+}
+
+func TestMatchService_ReplaysImports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	journal, _ := openJournal(t, path)
+	ms := NewMatchService(WithWriteAheadLog(journal, nil))
+
+	_, _, err := ms.ImportPeople(context.Background(), []dto.ImportPerson{
+		{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1},
+		{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+	}, false, false)
+	require.NoError(t, err)
+	_, _, err = ms.ImportPeople(context.Background(), []dto.ImportPerson{{Name: "Carol", Height: 150, Gender: "female", WantedDates: 1}}, true, false)
+	require.NoError(t, err)
+	before := ms.Snapshot()
+
+	journal.Close()
+	journal, records := openJournal(t, path)
+	restored := NewMatchService(WithWriteAheadLog(journal, records))
+	assertSameState(t, before, restored.Snapshot())
+	// Bob was imported next to Alice without matching and only later matched with Carol
+	people := restored.QuerySinglePeople(0)
+	require.Len(t, people, 1)
+	assert.Equal(t, "Alice", people[0].Name)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/wal"
+	"time"
+)
+
+// WithWriteAheadLog records every mutating operation to journal before applying it. The records
+// read when the journal was opened are replayed first, on top of any state restored with WithState,
+// without publishing events.
+func WithWriteAheadLog(journal *wal.Log, records []wal.Record) Option {
+	return func(ms *matchService) {
+		ms.journal = journal
+		ms.replay = records
+	}
+}
+
+// record must be called by the writer, before the operation changes anything. The records of a
+// batch go to the journal, if any, and to the followers' feeds.
+func (ms *matchService) record(op wal.Op, at time.Time, person models.Person) {
+	ms.unsynced = append(ms.unsynced, wal.Record{Revision: ms.revision, Op: op, At: at, Person: person})
+}
+
+// sync appends the records of the batch to the journal with a single fsync and returns them
+func (ms *matchService) sync() ([]wal.Record, error) {
+	records := ms.unsynced
+	ms.unsynced = nil
+	if ms.journal == nil || len(records) == 0 {
+		return records, nil
+	}
+	if err := ms.journal.Append(records...); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// replayJournal re-applies the records the restored state does not contain yet
+func (ms *matchService) replayJournal() error {
+	replay := ms.replay
+	ms.replay = nil
+	// subscribers already saw the events of replayed operations
+	defer func() { ms.outbox = nil }()
+
+	for _, record := range replay {
+		if record.Revision < ms.revision {
+			// already part of the snapshot
+			continue
+		}
+		if err := ms.checkRecord(record); err != nil {
+			return fmt.Errorf("write-ahead log: %w", err)
+		}
+		ms.applyRecord(record)
+	}
+	return nil
+}
+
+// checkRecord makes sure record can be applied to the pool as it is
+func (ms *matchService) checkRecord(record wal.Record) error {
+	if record.Revision != ms.revision {
+		return fmt.Errorf("record at revision %d does not follow state at revision %d, records are missing", record.Revision, ms.revision)
+	}
+	switch record.Op {
+	case wal.OpAdd, wal.OpImport:
+		return nil
+	case wal.OpUpdate, wal.OpRemove:
+		if _, ok := ms.people.Get(record.Person.ID); !ok {
+			return fmt.Errorf("%s of unknown person %s at revision %d", record.Op, record.Person.ID, record.Revision)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q at revision %d", record.Op, record.Revision)
+}
+
+// applyRecord repeats the operation of a record checkRecord accepted. It reuses the recorded IDs and
+// timestamps, and matching breaks ties deterministically, so the pool ends up as it did where the
+// record was made.
+func (ms *matchService) applyRecord(record wal.Record) {
+	person := record.Person
+	switch record.Op {
+	case wal.OpAdd:
+		ms.insertPerson(context.Background(), &person, record.At)
+	case wal.OpImport:
+		ms.importPerson(&person)
+	case wal.OpUpdate:
+		stored, _ := ms.people.Get(person.ID)
+		ms.updatePerson(context.Background(), stored, dto.UpdatePersonRequest{
+			Name:        person.Name,
+			Height:      person.Height,
+			Gender:      person.Gender,
+			WantedDates: person.WantedDates,
+		}, record.At)
+	case wal.OpRemove:
+		stored, _ := ms.people.Get(person.ID)
+		ms.removePerson(stored)
+	}
+}
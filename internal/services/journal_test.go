@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/events"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/wal"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openJournal(t *testing.T, path string) (*wal.Log, []wal.Record) {
+	journal, records, err := wal.Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { journal.Close() })
+	return journal, records
+}
+
+// assertSameState compares pools and histories as they would be persisted
+func assertSameState(t *testing.T, want, got snapshot.State) {
+	assert.Equal(t, want.Revision, got.Revision)
+
+	wantJSON, _ := json.Marshal([]interface{}{want.People, want.Matches})
+	gotJSON, _ := json.Marshal([]interface{}{got.People, got.Matches})
+	assert.JSONEq(t, string(wantJSON), string(gotJSON))
+}
+
+func TestMatchService_ReplaysJournalAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	journal, _ := openJournal(t, path)
+	ms := NewMatchService(WithWriteAheadLog(journal, nil))
+
+	// two women of the same height, so replay must break the tie the same way
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 160, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	david, _, err := ms.AddSinglePersonAndMatchIdempotent(context.Background(), "key", dto.AddPersonRequest{Name: "David", Height: 150, Gender: "male", WantedDates: 2})
+	require.NoError(t, err)
+	_, _, err = ms.UpdateSinglePerson(context.Background(), david.ID, dto.UpdatePersonRequest{Name: "David", Height: 185, Gender: "male", WantedDates: 1}, 0)
+	require.NoError(t, err)
+	eve, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Eve", Height: 170, Gender: "female", WantedDates: 1})
+	require.True(t, ms.RemoveSinglePerson(eve.ID))
+	before := ms.Snapshot()
+
+	// crash: nothing but the log survives
+	journal.Close()
+	journal, records := openJournal(t, path)
+	require.Len(t, records, 7)
+
+	restored := NewMatchService(WithWriteAheadLog(journal, records))
+	assertSameState(t, before, restored.Snapshot())
+
+	// the restored service keeps logging
+	restored.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Frank", Height: 190, Gender: "male", WantedDates: 1})
+	journal.Close()
+	_, records = openJournal(t, path)
+	assert.Len(t, records, 8)
+}
+
+func TestMatchService_ReplaysJournalOnTopOfSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wal.log")
+	journal, _ := openJournal(t, path)
+	ms := NewMatchService(WithWriteAheadLog(journal, nil))
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	state := ms.Snapshot()
+	require.NoError(t, journal.Compact(state.Revision))
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 150, Gender: "female", WantedDates: 1})
+	afterCarol := ms.Snapshot()
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "David", Height: 175, Gender: "male", WantedDates: 2})
+
+	// crash halfway through writing David's record
+	journal.Close()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-5))
+
+	journal, records := openJournal(t, path)
+	require.Len(t, records, 1, "only Carol survives compaction and the torn write")
+
+	restored := NewMatchService(WithState(state), WithWriteAheadLog(journal, records))
+	assertSameState(t, afterCarol, restored.Snapshot())
+}
+
+func TestMatchService_ReplayDetectsMissingRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	journal, _ := openJournal(t, path)
+	ms := NewMatchService(WithWriteAheadLog(journal, nil))
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	require.NoError(t, journal.Compact(ms.Snapshot().Revision))
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	// the snapshot the log was compacted against is lost, so Alice's add is nowhere to be found
+	journal.Close()
+	journal, records := openJournal(t, path)
+	_, err := OpenMatchService(WithWriteAheadLog(journal, records))
+	assert.ErrorContains(t, err, "records are missing")
+}
+
+func TestMatchService_FailsWritesWhenTheJournalFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	journal, _ := openJournal(t, path)
+	bus := events.NewBus()
+	var published []events.Event
+	bus.Subscribe(func(event events.Event) { published = append(published, event) })
+	ms := NewMatchService(WithWriteAheadLog(journal, nil), WithEvents(bus))
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	before := ms.Snapshot()
+	published = nil
+
+	// the disk goes away under the log
+	require.NoError(t, journal.Close())
+	person, matches, err := ms.AddSinglePersonAndMatchIdempotent(context.Background(), "", dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	assert.ErrorIs(t, err, ErrJournalFailed)
+	assert.Nil(t, person)
+	assert.Empty(t, matches)
+
+	// nothing of the write is published, and the pool takes no more writes
+	assertSameState(t, before, ms.Snapshot())
+	assert.Empty(t, published)
+	alice := ms.QuerySinglePeople(0)[0]
+	assert.ErrorIs(t, ms.RemoveSinglePersonIfMatch(context.Background(), alice.ID, 0), ErrJournalFailed)
+	assert.Len(t, ms.QuerySinglePeople(0), 1)
+}
@@ -1,167 +1,570 @@
 package services
 
 import (
+	"context"
 	"matching_system/internal/api/dto"
+	"matching_system/internal/audit"
+	"matching_system/internal/events"
 	"matching_system/internal/models"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/storage"
+	"matching_system/internal/wal"
 	"matching_system/pkg/logger"
-	"sort"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DefaultMatchHistory is how many matches a pool remembers unless WithMatchHistory says otherwise
+const DefaultMatchHistory = 10000
+
 type MatchService interface {
 	AddSinglePersonAndMatch(req dto.AddPersonRequest) (*models.Person, []models.Match)
 	RemoveSinglePerson(personID string) bool
 	QuerySinglePeople(limit int) []models.Person
+	// AddSinglePersonAndMatchIdempotent behaves like AddSinglePersonAndMatch, but replays the
+	// original person and matches when the same key is seen again within the cache TTL. An empty key
+	// adds without idempotency. Like the other writes taking a ctx, it is traced as part of its trace
+	// and audited as made by its audit source.
+	AddSinglePersonAndMatchIdempotent(ctx context.Context, key string, req dto.AddPersonRequest) (*models.Person, []models.Match, error)
+	GetSinglePerson(personID string) (*models.Person, bool)
+	// UpdateSinglePerson replaces a person's attributes and matches them again. A non-zero
+	// expectedVersion makes the update conditional on the person's current version.
+	UpdateSinglePerson(ctx context.Context, personID string, req dto.UpdatePersonRequest, expectedVersion int64) (*models.Person, []models.Match, error)
+	// RemoveSinglePersonIfMatch removes a person only if their current version is expectedVersion,
+	// or unconditionally when expectedVersion is zero.
+	RemoveSinglePersonIfMatch(ctx context.Context, personID string, expectedVersion int64) error
+	// QueryMatches returns the match history, newest first, optionally only the matches of personID.
+	// The history only holds the latest matches, see WithMatchHistory.
+	QueryMatches(personID string, limit int) []models.Match
+	// Snapshot returns a consistent copy of the pool and match history for persistence
+	Snapshot() snapshot.State
+	// FindCandidates returns the active people a person of the given gender and height is compatible
+	// with, in the order they would be matched. Matching is greedy, so an active person normally has
+	// no candidates left; this mostly previews who a new person would match.
+	FindCandidates(gender string, height int, limit int) []models.Person
+	// CountByGender returns how many people of each gender are active
+	CountByGender() map[string]int
+	// ImportPeople validates people and adds them all or none, optionally matching each in turn.
+	// With dryRun nothing is added. It returns the people as they were added, before matching.
+	ImportPeople(ctx context.Context, people []dto.ImportPerson, runMatching bool, dryRun bool) ([]models.Person, []models.Match, error)
+	// Follow returns the current state and a feed of every operation applied after it, for a
+	// follower to Reset to the state and Apply the feed.
+	Follow(buffer int) (snapshot.State, *Feed)
+	// Reset replaces the pool and match history with state, forgetting idempotency keys. It is
+	// meant for followers, which keep no write-ahead log.
+	Reset(state snapshot.State)
+	// IdempotencyKeys returns the idempotency keys remembered, oldest first. A cluster keeps them in
+	// its snapshots, so a node restored from one replays the same adds as the others.
+	IdempotencyKeys() []IdempotencyKey
+	// RestoreIdempotencyKeys replaces the idempotency keys remembered with keys
+	RestoreIdempotencyKeys(keys []IdempotencyKey)
+	// Apply applies operations from a leader's feed in order and returns the revision reached. A
+	// record that does not follow on from the pool fails it; the follower has to Reset.
+	Apply(records []wal.Record) (uint64, error)
 }
 
+// matchService keeps the pool in two forms: the people store and match history, which only the
+// writer uses (see write), and the immutable view readers query.
 type matchService struct {
-	mu           sync.RWMutex
-	activePeople map[string]*models.Person
-	logger       *logger.Logger
+	people       storage.PeopleStore
+	newStore     func() storage.PeopleStore
+	matchHistory []models.Match
+	// maxMatches bounds matchHistory, see WithMatchHistory
+	maxMatches  int
+	idempotency *idempotencyCache
+	events      events.Publisher
+	restored    *snapshot.State
+	// pool names the pool the service matches, stamped on every event
+	pool  string
+	rules models.MatchRules
+	// now and newID stamp the operations and name new people
+	now   func() time.Time
+	newID func() string
+	// observeAdd is told how many matches each add made, see WithAddObserver
+	observeAdd func(pool string, matches int)
+	// logger logs what happens outside of requests, requests log with their own, see log
+	logger *logger.Logger
+	// recordAudit records the writes made on request, see WithAudit
+	recordAudit func(entries ...audit.Entry) error
+	// revision counts published changes, so snapshots can tell whether anything changed
+	revision uint64
+	// journal records every mutating operation before it is applied
+	journal *wal.Log
+	replay  []wal.Record
+	// queue holds the writes waiting for the writer, see write
+	queueMu sync.Mutex
+	queue   []*command
+	writing bool
+	// unsynced and outbox hold the log records and events of the batch being applied
+	unsynced []wal.Record
+	outbox   []events.Event
+	auditing []audit.Entry
+	// view is what readers see. changed holds the IDs of the people the batch changed, published
+	// the people as they are in the view.
+	view      atomic.Pointer[view]
+	changed   map[string]struct{}
+	published map[string]*models.Person
+	// reset has the batch rebuild the view from scratch
+	reset bool
+	// failed fails every write once the journal could not record a batch, see applyBatch
+	failed error
+	// feedMu makes publishing a view and delivering its records to feeds one step, see Follow
+	feedMu sync.Mutex
+	feeds  map[*Feed]struct{}
+}
+
+// Option configures a matchService
+type Option func(*matchService)
+
+// WithEvents publishes pool changes to publisher, usually an events.Bus that side effects subscribe to
+func WithEvents(publisher events.Publisher) Option {
+	return func(ms *matchService) {
+		ms.events = publisher
+	}
+}
+
+// WithPool names the pool the service matches and sets its rules. The rules must not change over
+// the life of a write-ahead log, or replay would match differently.
+func WithPool(name string, rules models.MatchRules) Option {
+	return func(ms *matchService) {
+		ms.pool = name
+		ms.rules = rules
+	}
+}
+
+// WithPeopleStore keeps the active people in a store made by newStore instead of the default index.
+// The option can be shared by several services, each gets a store of its own.
+func WithPeopleStore(newStore func() storage.PeopleStore) Option {
+	return func(ms *matchService) {
+		ms.newStore = newStore
+	}
+}
+
+// WithClock and WithIDs make operations take their time and new people their IDs from now and
+// newID, which are only called by the writer. Replicas that apply the same operations with the same
+// times and IDs end up with the same pool.
+func WithClock(now func() time.Time) Option {
+	return func(ms *matchService) {
+		ms.now = now
+	}
+}
+
+func WithIDs(newID func() string) Option {
+	return func(ms *matchService) {
+		ms.newID = newID
+	}
+}
+
+// WithAddObserver has observe told the number of matches of every person added on request, which
+// the events only tell one match at a time. It is called by the writer, so it must be quick.
+func WithAddObserver(observe func(pool string, matches int)) Option {
+	return func(ms *matchService) {
+		ms.observeAdd = observe
+	}
+}
+
+// WithLogger logs with l instead of the default logger
+func WithLogger(l *logger.Logger) Option {
+	return func(ms *matchService) {
+		ms.logger = l
+	}
 }
 
-func NewMatchService() MatchService {
-	return &matchService{
-		activePeople: make(map[string]*models.Person),
-		logger:       logger.New(),
+// WithMatchHistory keeps only the last size matches in the history, older ones are dropped. A size
+// of zero or less keeps every match. Replicas should use the same size, or their histories differ.
+func WithMatchHistory(size int) Option {
+	return func(ms *matchService) {
+		ms.maxMatches = size
 	}
 }
 
+// WithIdempotency sets how long and how many idempotency keys are remembered
+func WithIdempotency(ttl time.Duration, capacity int) Option {
+	return func(ms *matchService) {
+		ms.idempotency = newIdempotencyCache(ttl, capacity)
+	}
+}
+
+// NewMatchService is OpenMatchService for services that cannot fail to open, it panics if the
+// write-ahead log does not follow on from the restored state
+func NewMatchService(opts ...Option) MatchService {
+	ms, err := OpenMatchService(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return ms
+}
+
+// OpenMatchService builds a match service, restoring the state and replaying the write-ahead log
+// given as options. It fails when the log does not follow on from the state, for instance because
+// it was compacted past the snapshot the state was restored from.
+func OpenMatchService(opts ...Option) (MatchService, error) {
+	ms := &matchService{
+		newStore:    func() storage.PeopleStore { return storage.NewIndexedStore() },
+		maxMatches:  DefaultMatchHistory,
+		idempotency: newIdempotencyCache(defaultIdempotencyTTL, defaultIdempotencyCapacity),
+		events:      events.NewBus(),
+		rules:       DefaultRules,
+		now:         time.Now,
+		newID:       func() string { return uuid.New().String() },
+		logger:      logger.Default(),
+	}
+	for _, opt := range opts {
+		opt(ms)
+	}
+	// keys expire by the operations' clock too
+	ms.idempotency.now = ms.now
+	ms.people = ms.newStore()
+	ms.restoreState()
+	if err := ms.replayJournal(); err != nil {
+		return nil, err
+	}
+	ms.buildView()
+	return ms, nil
+}
+
 func (ms *matchService) AddSinglePersonAndMatch(req dto.AddPersonRequest) (*models.Person, []models.Match) {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
+	person, matches, _ := ms.AddSinglePersonAndMatchIdempotent(context.Background(), "", req)
+	return person, matches
+}
+
+func (ms *matchService) AddSinglePersonAndMatchIdempotent(ctx context.Context, key string, req dto.AddPersonRequest) (person *models.Person, matches []models.Match, err error) {
+	ctx, span := ms.startOperation(ctx, "MatchService.AddPerson")
+	defer func() { endSpan(span, err) }()
+
+	if err := ms.write(ctx, func() {
+		person, matches, err = ms.addPersonIdempotent(ctx, key, req)
+	}); err != nil {
+		return nil, nil, err
+	}
+	if person != nil {
+		span.SetAttributes(attribute.String("person.id", person.ID), attribute.Int("matches", len(matches)))
+		ms.log(ctx).Debug("Person added", "person_id", person.ID, "matches", len(matches))
+	}
+	return person, matches, err
+}
+
+func (ms *matchService) addPersonIdempotent(ctx context.Context, key string, req dto.AddPersonRequest) (*models.Person, []models.Match, error) {
+	if key == "" {
+		person, matches := ms.addPerson(ctx, req)
+		return person, matches, nil
+	}
+	if entry, ok := ms.idempotency.get(key); ok {
+		if entry.request != req {
+			return nil, nil, ErrIdempotencyKeyMismatch
+		}
+		person := entry.person
+		return &person, append([]models.Match(nil), entry.matches...), nil
+	}
 
+	person, matches := ms.addPerson(ctx, req)
+	ms.idempotency.put(key, req, *person, matches)
+
+	return person, matches, nil
+}
+
+func (ms *matchService) addPerson(ctx context.Context, req dto.AddPersonRequest) (*models.Person, []models.Match) {
 	person := &models.Person{
-		ID:          uuid.New().String(),
+		ID:          ms.newID(),
 		Name:        req.Name,
 		Height:      req.Height,
 		Gender:      req.Gender,
 		WantedDates: req.WantedDates,
+		Version:     1,
+	}
+	at := ms.now()
+	ms.record(wal.OpAdd, at, *person)
+	matches := ms.insertPerson(ctx, person, at)
+	if ms.observeAdd != nil {
+		ms.observeAdd(ms.pool, len(matches))
 	}
+	ms.audit(ctx, audit.OpAdd, at, nil, person)
+	ms.auditPartners(ctx, at, matches)
 
-	ms.activePeople[person.ID] = person
-	// jsonData, _ := json.MarshalIndent(ms.activePeople, "", "  ")
-	// ms.logger.Info("Active people:\n" + string(jsonData))
+	// the stored person keeps changing as they are matched later on
+	added := *person
+	return &added, matches
+}
 
-	matches := ms.findMatches(person)
+// log returns the logger of the request in ctx, which carries its request ID, or the service's own
+func (ms *matchService) log(ctx context.Context) *logger.Logger {
+	return logger.FromContextOr(ctx, ms.logger).With("pool", ms.pool)
+}
 
-	return person, matches
+func (ms *matchService) insertPerson(ctx context.Context, person *models.Person, at time.Time) []models.Match {
+	ms.people.Put(person)
+	ms.publish(events.PersonAdded, *person, nil)
+
+	return ms.findMatches(ctx, person, at)
 }
 
 func (ms *matchService) RemoveSinglePerson(personID string) bool {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
+	return ms.RemoveSinglePersonIfMatch(context.Background(), personID, 0) == nil
+}
 
-	if _, ok := ms.activePeople[personID]; ok {
-		delete(ms.activePeople, personID)
-	} else {
-		return false
+func (ms *matchService) RemoveSinglePersonIfMatch(ctx context.Context, personID string, expectedVersion int64) (err error) {
+	if err := ms.write(ctx, func() {
+		err = ms.removePersonIfMatch(ctx, personID, expectedVersion)
+	}); err != nil {
+		return err
 	}
+	if err == nil {
+		ms.log(ctx).Debug("Person removed", "person_id", personID)
+	}
+	return err
+}
 
-	// jsonData, _ := json.MarshalIndent(ms.activePeople, "", "  ")
-	// ms.logger.Info("Active people:\n" + string(jsonData))
+func (ms *matchService) removePersonIfMatch(ctx context.Context, personID string, expectedVersion int64) error {
+	person, ok := ms.people.Get(personID)
+	if !ok {
+		return ErrPersonNotFound
+	}
+	if expectedVersion != 0 && person.Version != expectedVersion {
+		return ErrVersionMismatch
+	}
 
-	return true
+	at := ms.now()
+	ms.record(wal.OpRemove, at, models.Person{ID: personID})
+	ms.removePerson(person)
+	ms.audit(ctx, audit.OpRemove, at, person, nil)
+	return nil
 }
 
-func (ms *matchService) QuerySinglePeople(limit int) []models.Person {
-	ms.mu.RLock()
-	defer ms.mu.RUnlock()
+func (ms *matchService) removePerson(person *models.Person) {
+	ms.people.Delete(person.ID)
+	ms.publish(events.PersonRemoved, *person, nil)
+}
 
-	// Convert map to slice
-	people := make([]models.Person, 0, len(ms.activePeople))
-	for _, person := range ms.activePeople {
-		people = append(people, *person)
+func (ms *matchService) GetSinglePerson(personID string) (*models.Person, bool) {
+	person, ok := ms.view.Load().byID.Get(&models.Person{ID: personID})
+	if !ok {
+		return nil, false
 	}
+	found := *person
+	return &found, true
+}
 
-	sort.Slice(people, func(i, j int) bool {
-		// sort by wanted dates
-		if people[i].WantedDates != people[j].WantedDates {
-			return people[i].WantedDates > people[j].WantedDates
-		}
+func (ms *matchService) UpdateSinglePerson(ctx context.Context, personID string, req dto.UpdatePersonRequest, expectedVersion int64) (person *models.Person, matches []models.Match, err error) {
+	ctx, span := ms.startOperation(ctx, "MatchService.UpdatePerson", attribute.String("person.id", personID))
+	defer func() { endSpan(span, err) }()
 
-		// sort by gender
-		if people[i].Gender != people[j].Gender {
-			return people[i].Gender == "female"
-		}
-		// sort by height
-		if people[i].Gender == "female" {
-			// female height from low to high
-			return people[i].Height < people[j].Height
-		} else {
-			// male height from high to low
-			return people[i].Height > people[j].Height
-		}
-	})
+	if err := ms.write(ctx, func() {
+		person, matches, err = ms.updatePersonIfMatch(ctx, personID, req, expectedVersion)
+	}); err != nil {
+		return nil, nil, err
+	}
+	span.SetAttributes(attribute.Int("matches", len(matches)))
+	if err == nil {
+		ms.log(ctx).Debug("Person updated", "person_id", personID, "matches", len(matches))
+	}
+	return person, matches, err
+}
 
-	// fmt.Println("people:", people)
-	if limit > 0 && limit < len(people) {
-		people = people[:limit]
+func (ms *matchService) updatePersonIfMatch(ctx context.Context, personID string, req dto.UpdatePersonRequest, expectedVersion int64) (*models.Person, []models.Match, error) {
+	person, ok := ms.people.Get(personID)
+	if !ok {
+		return nil, nil, ErrPersonNotFound
+	}
+	if expectedVersion != 0 && person.Version != expectedVersion {
+		return nil, nil, ErrVersionMismatch
 	}
 
-	return people
+	at := ms.now()
+	ms.record(wal.OpUpdate, at, models.Person{
+		ID:          personID,
+		Name:        req.Name,
+		Height:      req.Height,
+		Gender:      req.Gender,
+		WantedDates: req.WantedDates,
+	})
+	before := *person
+	matches := ms.updatePerson(ctx, person, req, at)
+	ms.audit(ctx, audit.OpUpdate, at, &before, person)
+	ms.auditPartners(ctx, at, matches)
+
+	updated := *person
+	return &updated, matches, nil
 }
 
-func (ms *matchService) findMatches(newPerson *models.Person) []models.Match {
-	var matches []models.Match
-	var potentialMatches []*models.Person
+func (ms *matchService) updatePerson(ctx context.Context, person *models.Person, req dto.UpdatePersonRequest, at time.Time) []models.Match {
+	person.Name = req.Name
+	person.Height = req.Height
+	person.Gender = req.Gender
+	person.WantedDates = req.WantedDates
+	person.Version++
+	ms.people.Put(person)
+	ms.publish(events.PersonUpdated, *person, nil)
+
+	// the new attributes may make the person compatible with people they skipped before
+	return ms.findMatches(ctx, person, at)
+}
+
+func (ms *matchService) QuerySinglePeople(limit int) []models.Person {
+	ranking := ms.view.Load().ranking
+	size := ranking.Len()
+	if limit > 0 {
+		size = min(size, limit)
+	}
+	people := make([]models.Person, 0, size)
+	ranking.Each(func(person *models.Person) bool {
+		people = append(people, *person)
+		return limit <= 0 || len(people) < limit
+	})
+	return people
+}
 
-	// Find potential matches based on gender and height rules
-	for _, person := range ms.activePeople {
-		if person.ID == newPerson.ID {
+func (ms *matchService) QueryMatches(personID string, limit int) []models.Match {
+	history := ms.view.Load().matches
+	matches := make([]models.Match, 0)
+	for i := len(history) - 1; i >= 0; i-- {
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+		match := history[i]
+		if personID != "" && match.Person1.ID != personID && match.Person2.ID != personID {
 			continue
 		}
+		matches = append(matches, match)
+	}
+
+	return matches
+}
+
+func (ms *matchService) FindCandidates(gender string, height int, limit int) []models.Person {
+	candidates := make([]models.Person, 0)
+	r, ok := candidateRange(&models.Person{Gender: gender, Height: height}, ms.rules)
+	index := ms.view.Load().candidates[r.Gender]
+	if !ok || index == nil {
+		return candidates
+	}
 
-		if ms.isCompatible(newPerson, person) {
-			potentialMatches = append(potentialMatches, person)
+	// the index is in the order of the range, so candidates start at its first end
+	from := &models.Person{Height: r.Min}
+	if r.Descending {
+		from.Height = r.Max
+	}
+	index.Ascend(from, func(person *models.Person) bool {
+		if person.Height < r.Min || person.Height > r.Max {
+			return false
 		}
+		candidates = append(candidates, *person)
+		return limit <= 0 || len(candidates) < limit
+	})
+	return candidates
+}
+
+func (ms *matchService) CountByGender() map[string]int {
+	candidates := ms.view.Load().candidates
+	counts := make(map[string]int, len(candidates))
+	for gender, index := range candidates {
+		counts[gender] = index.Len()
 	}
+	return counts
+}
 
-	if newPerson.Gender == "male" {
-		sort.Slice(potentialMatches, func(i, j int) bool {
-			return potentialMatches[i].Height < potentialMatches[j].Height
-		})
-	} else {
-		sort.Slice(potentialMatches, func(i, j int) bool {
-			return potentialMatches[i].Height > potentialMatches[j].Height
-		})
+// findPotentialMatches returns the first limit people compatible with newPerson in the order they
+// would be matched, or all of them when limit <= 0
+func (ms *matchService) findPotentialMatches(newPerson *models.Person, limit int) []*models.Person {
+	r, ok := candidateRange(newPerson, ms.rules)
+	if !ok {
+		return nil
 	}
+	return ms.people.TopN(r, limit)
+}
 
+// candidateRange is who a person is compatible with: men match shorter women, shortest first, and
+// women match taller men, tallest first, within the height gaps of rules. Equal heights are ordered
+// by ID, so replaying the write-ahead log matches the same people.
+func candidateRange(person *models.Person, rules models.MatchRules) (storage.HeightRange, bool) {
+	maxGap := rules.MaxHeightGap
+	if maxGap == 0 {
+		maxGap = math.MaxInt32
+	}
+
+	switch person.Gender {
+	case "male":
+		return storage.HeightRange{Gender: "female", Min: person.Height - maxGap, Max: person.Height - rules.MinHeightGap}, true
+	case "female":
+		return storage.HeightRange{Gender: "male", Min: person.Height + rules.MinHeightGap, Max: person.Height + maxGap, Descending: true}, true
+	}
+	return storage.HeightRange{}, false
+}
+
+// findMatches traces its two stages in ctx: looking up the candidates, which come in the order they
+// are matched in, and pairing the person with them
+func (ms *matchService) findMatches(ctx context.Context, newPerson *models.Person, matchedAt time.Time) []models.Match {
+	_, lookup := startStage(ctx, "match.find_candidates",
+		attribute.String("person.gender", newPerson.Gender),
+		attribute.Int("person.height", newPerson.Height),
+		attribute.Int("person.wanted_dates", newPerson.WantedDates),
+	)
+	var matches []models.Match
+	var potentialMatches []*models.Person
+	// only as many candidates as the person still wants dates, a limit of zero would mean all
+	if newPerson.WantedDates > 0 {
+		potentialMatches = ms.findPotentialMatches(newPerson, newPerson.WantedDates)
+	}
+	lookup.SetAttributes(attribute.Int("candidates", len(potentialMatches)))
+	lookup.End()
+
+	_, pair := startStage(ctx, "match.pair")
+	defer pair.End()
 	for _, potentialMatch := range potentialMatches {
-		if newPerson.WantedDates <= 0 {
-			break
+		match := models.Match{
+			Person1:   *newPerson,
+			Person2:   *potentialMatch,
+			MatchedAt: matchedAt,
 		}
-		matches = append(matches, models.Match{
-			Person1: *newPerson,
-			Person2: *potentialMatch,
-		})
+		matches = append(matches, match)
 		newPerson.WantedDates--
 		potentialMatch.WantedDates--
+		potentialMatch.Version++
+		ms.publish(events.Matched, *newPerson, &match)
 
 		if potentialMatch.WantedDates <= 0 {
-			delete(ms.activePeople, potentialMatch.ID)
+			ms.people.Delete(potentialMatch.ID)
+			ms.publish(events.DatesExhausted, *potentialMatch, nil)
 		}
 	}
 	if newPerson.WantedDates <= 0 {
-		delete(ms.activePeople, newPerson.ID)
+		ms.people.Delete(newPerson.ID)
+		ms.publish(events.DatesExhausted, *newPerson, nil)
 	}
+	ms.recordMatches(matches...)
+	pair.SetAttributes(attribute.Int("matches", len(matches)))
 	return matches
 }
 
-func (ms *matchService) isCompatible(person1, person2 *models.Person) bool {
-	if person1.Gender == person2.Gender {
-		return false
-	}
-
-	if person1.Gender == "male" && person2.Gender == "female" {
-		return person1.Height > person2.Height
+// recordMatches adds matches to the history and drops the oldest beyond its size, must be called by
+// the writer. Views keep the history they were published with, appends never write to it.
+func (ms *matchService) recordMatches(matches ...models.Match) {
+	ms.matchHistory = append(ms.matchHistory, matches...)
+	if ms.maxMatches > 0 && len(ms.matchHistory) > ms.maxMatches {
+		// dropping from the front shrinks the capacity, so the next growth copies only what is kept
+		ms.matchHistory = ms.matchHistory[len(ms.matchHistory)-ms.maxMatches:]
 	}
+}
 
-	if person1.Gender == "female" && person2.Gender == "male" {
-		return person2.Height > person1.Height
+// publish must be called by the writer. Events are delivered once the batch is durable, in the
+// order the changes were made.
+func (ms *matchService) publish(eventType events.Type, person models.Person, match *models.Match) {
+	ms.revision++
+	// every change to a person is published, so this is where the view learns about them
+	ms.changedPerson(person.ID)
+	if match != nil {
+		ms.changedPerson(match.Person2.ID)
 	}
-
-	return false
+	ms.outbox = append(ms.outbox, events.Event{
+		Pool:       ms.pool,
+		Type:       eventType,
+		PersonID:   person.ID,
+		Person:     &person,
+		Match:      match,
+		OccurredAt: time.Now(),
+	})
 }
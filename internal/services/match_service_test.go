@@ -1,7 +1,13 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"log/slog"
 	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/storage"
+	"matching_system/pkg/logger"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,7 +33,7 @@ func TestMatchService_AddSinglePersonAndMatch(t *testing.T) {
 	assert.Equal(t, "female", person.Gender, "the gender should match")
 	assert.Equal(t, 3, person.WantedDates, "the WantedDates should match")
 
-	// verify the person is in activePeople
+	// verify the person is in the pool
 	result := ms.QuerySinglePeople(0)
 	assert.Equal(t, 1, len(result), "should have 1 person")
 	assert.Equal(t, person.ID, result[0].ID, "the ID should match")
@@ -46,7 +52,7 @@ func TestMatchService_RemoveSinglePerson(t *testing.T) {
 
 	person, _ := ms.AddSinglePersonAndMatch(req)
 
-	// verify the person is in activePeople
+	// verify the person is in the pool
 	result := ms.QuerySinglePeople(0)
 	assert.Equal(t, 1, len(result), "should have 1 person")
 
@@ -162,7 +168,7 @@ func TestMatchService_QuerySinglePeople_Limit(t *testing.T) {
 		{Name: "David", Height: 155, Gender: "male", WantedDates: 2},
 	}
 
-	// directly add to activePeople map
+	// add to the pool
 	for _, req := range testPeople {
 		ms.AddSinglePersonAndMatch(req)
 	}
@@ -177,3 +183,120 @@ func TestMatchService_QuerySinglePeople_Limit(t *testing.T) {
 	result = ms.QuerySinglePeople(0)
 	assert.Equal(t, 4, len(result), "should return all 4 people")
 }
+
+func TestMatchService_QueryMatches(t *testing.T) {
+	ms := NewMatchService()
+
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	bob, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	carol, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "David", Height: 175, Gender: "male", WantedDates: 2})
+
+	// David matches Alice and Carol after Bob matched Alice
+	result := ms.QueryMatches("", 0)
+	assert.Equal(t, 3, len(result), "should have 3 matches")
+	assert.Equal(t, bob.ID, result[2].Person1.ID, "the oldest match should be last")
+
+	// filter by person
+	result = ms.QueryMatches(alice.ID, 0)
+	assert.Equal(t, 2, len(result), "Alice should have 2 matches")
+
+	result = ms.QueryMatches(carol.ID, 0)
+	assert.Equal(t, 1, len(result), "Carol should have 1 match")
+
+	// limit
+	result = ms.QueryMatches("", 1)
+	assert.Equal(t, 1, len(result), "should return 1 match")
+}
+
+func TestMatchService_FindCandidates(t *testing.T) {
+	ms := NewMatchService()
+
+	testPeople := []dto.AddPersonRequest{
+		{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+		{Name: "David", Height: 175, Gender: "male", WantedDates: 1},
+		{Name: "Frank", Height: 165, Gender: "male", WantedDates: 1},
+		{Name: "Carol", Height: 185, Gender: "female", WantedDates: 1},
+	}
+	for _, req := range testPeople {
+		ms.AddSinglePersonAndMatch(req)
+	}
+
+	// a 170cm woman would be matched with the tallest man first
+	candidates := ms.FindCandidates("female", 170, 0)
+	assert.Equal(t, 2, len(candidates), "should have 2 candidates")
+	assert.Equal(t, "Bob", candidates[0].Name, "the tallest man should come first")
+	assert.Equal(t, "David", candidates[1].Name, "the second tallest man should come second")
+
+	// a 170cm man would be matched with the shortest woman first, Carol is too tall
+	candidates = ms.FindCandidates("male", 170, 0)
+	assert.Equal(t, 0, len(candidates), "should have no candidates")
+
+	// limit
+	candidates = ms.FindCandidates("female", 160, 1)
+	assert.Equal(t, 1, len(candidates), "should return 1 candidate")
+}
+
+func TestMatchService_MatchesWithAnyPeopleStore(t *testing.T) {
+	stores := map[string]func() storage.PeopleStore{
+		"map":     func() storage.PeopleStore { return storage.NewMapStore() },
+		"indexed": func() storage.PeopleStore { return storage.NewIndexedStore() },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ms := NewMatchService(WithPeopleStore(newStore))
+
+			ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+			ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 150, Gender: "female", WantedDates: 2})
+			ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Eve", Height: 175, Gender: "female", WantedDates: 1})
+
+			// Bob is matched with the shortest women first and stops when his dates run out
+			bob, matches := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 170, Gender: "male", WantedDates: 2})
+			assert.Equal(t, 2, len(matches), "Bob should be matched twice")
+			assert.Equal(t, "Carol", matches[0].Person2.Name, "the shortest woman should come first")
+			assert.Equal(t, "Alice", matches[1].Person2.Name, "the second shortest woman should come second")
+			_, ok := ms.GetSinglePerson(bob.ID)
+			assert.False(t, ok, "Bob should have no dates left")
+
+			david, matches := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "David", Height: 165, Gender: "male", WantedDates: 2})
+			assert.Equal(t, "Carol", matches[0].Person2.Name, "Carol should have one date left")
+
+			// a taller update makes David compatible with Eve
+			_, matches, err := ms.UpdateSinglePerson(context.Background(), david.ID, dto.UpdatePersonRequest{Name: "David", Height: 180, Gender: "male", WantedDates: 1}, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, len(matches), "David should be matched after the update")
+			assert.Equal(t, "Eve", matches[0].Person2.Name, "David should be matched with Eve")
+
+			assert.Equal(t, 0, len(ms.QuerySinglePeople(0)), "everyone should be matched")
+		})
+	}
+}
+
+func TestMatchService_PoolRules(t *testing.T) {
+	ms := NewMatchService(WithPool("eu", models.MatchRules{MinHeightGap: 5, MaxHeightGap: 15}))
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 177, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 160, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Eve", Height: 170, Gender: "female", WantedDates: 1})
+
+	// Alice is 3cm shorter, too close; Carol is 20cm shorter, too far
+	_, matches := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 3})
+	assert.Equal(t, 1, len(matches), "only Eve is within the height gaps")
+	assert.Equal(t, "Eve", matches[0].Person2.Name)
+
+	candidates := ms.FindCandidates("female", 175, 0)
+	assert.Equal(t, 1, len(candidates), "Bob is 5cm taller than 175cm")
+}
+
+func TestMatchService_LogsWithTheRequestsLogger(t *testing.T) {
+	var own, request bytes.Buffer
+	ms := NewMatchService(WithPool("eu", DefaultRules), WithLogger(logger.New(logger.WithLevel(slog.LevelDebug), logger.WithOutput(&own))))
+	ctx := logger.NewContext(context.Background(), logger.New(logger.WithLevel(slog.LevelDebug), logger.WithOutput(&request)).With("request_id", "req-42"))
+
+	person, _, err := ms.AddSinglePersonAndMatchIdempotent(ctx, "", dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	assert.NoError(t, err)
+
+	assert.Empty(t, own.String())
+	assert.Contains(t, request.String(), "msg=\"Person added\" request_id=req-42 pool=eu person_id="+person.ID+" matches=0")
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/snapshot"
+	"matching_system/internal/wal"
+)
+
+// Feed receives the operations a pool applies after Follow, batch by batch
+type Feed struct {
+	ms *matchService
+	ch chan []wal.Record
+}
+
+// Records delivers the batches in order. The channel is closed when the feed falls more than its
+// buffer behind, the pool is Reset, or the feed is closed; the follower then has to Follow again.
+func (f *Feed) Records() <-chan []wal.Record {
+	return f.ch
+}
+
+func (f *Feed) Close() {
+	f.ms.feedMu.Lock()
+	defer f.ms.feedMu.Unlock()
+
+	if _, ok := f.ms.feeds[f]; ok {
+		delete(f.ms.feeds, f)
+		close(f.ch)
+	}
+}
+
+func (ms *matchService) Follow(buffer int) (snapshot.State, *Feed) {
+	// views are published and their records delivered under feedMu, so the state and the first
+	// batch on the feed follow on from each other exactly
+	ms.feedMu.Lock()
+	defer ms.feedMu.Unlock()
+
+	feed := &Feed{ms: ms, ch: make(chan []wal.Record, buffer)}
+	if ms.feeds == nil {
+		ms.feeds = make(map[*Feed]struct{})
+	}
+	ms.feeds[feed] = struct{}{}
+	return ms.Snapshot(), feed
+}
+
+// deliver hands the records of a batch to every feed, must be called with feedMu held
+func (ms *matchService) deliver(records []wal.Record) {
+	if len(records) == 0 {
+		return
+	}
+	for feed := range ms.feeds {
+		select {
+		case feed.ch <- records:
+		default:
+			// too far behind, the follower starts over rather than hold up the writer
+			delete(ms.feeds, feed)
+			close(feed.ch)
+		}
+	}
+}
+
+// closeFeeds must be called with feedMu held
+func (ms *matchService) closeFeeds() {
+	for feed := range ms.feeds {
+		delete(ms.feeds, feed)
+		close(feed.ch)
+	}
+}
+
+func (ms *matchService) Reset(state snapshot.State) {
+	ms.write(context.Background(), func() {
+		ms.people = ms.newStore()
+		ms.restored = &state
+		ms.restoreState()
+		ms.idempotency = newIdempotencyCache(ms.idempotency.ttl, ms.idempotency.capacity)
+		ms.idempotency.now = ms.now
+		// earlier writes of the batch may have left records and events behind
+		ms.unsynced = nil
+		ms.outbox = nil
+		ms.reset = true
+	})
+}
+
+func (ms *matchService) Apply(records []wal.Record) (revision uint64, err error) {
+	if err := ms.write(context.Background(), func() {
+		for _, record := range records {
+			if err = ms.checkRecord(record); err != nil {
+				break
+			}
+			// recorded again, so this pool can be followed in turn
+			ms.record(record.Op, record.At, record.Person)
+			ms.applyRecord(record)
+		}
+		revision = ms.revision
+	}); err != nil {
+		return 0, err
+	}
+	return revision, err
+}
+
+func (ms *matchService) IdempotencyKeys() (keys []IdempotencyKey) {
+	ms.write(context.Background(), func() {
+		keys = ms.idempotency.keys()
+	})
+	return keys
+}
+
+func (ms *matchService) RestoreIdempotencyKeys(keys []IdempotencyKey) {
+	ms.write(context.Background(), func() {
+		ms.idempotency.restore(keys)
+	})
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/wal"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchService_FollowerCatchesUp(t *testing.T) {
+	leader := NewMatchService()
+	leader.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	state, feed := leader.Follow(1024)
+	defer feed.Close()
+
+	follower := NewMatchService()
+	follower.Reset(state)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				gender := "female"
+				if (i+j)%2 == 0 {
+					gender = "male"
+				}
+				person, _ := leader.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: fmt.Sprintf("P%d-%d", i, j), Height: 150 + (i*7+j*3)%50, Gender: gender, WantedDates: 2})
+				if j%4 == 0 {
+					leader.UpdateSinglePerson(context.Background(), person.ID, dto.UpdatePersonRequest{Name: "Carol", Height: 170, Gender: gender, WantedDates: 1}, 0)
+				}
+				if j%5 == 0 {
+					leader.RemoveSinglePerson(person.ID)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	want := leader.Snapshot()
+
+	for revision := state.Revision; revision < want.Revision; {
+		records := <-feed.Records()
+		var err error
+		revision, err = follower.Apply(records)
+		require.NoError(t, err)
+	}
+	assertSameState(t, want, follower.Snapshot())
+}
+
+func TestMatchService_ApplyRejectsGaps(t *testing.T) {
+	leader := NewMatchService()
+	_, feed := leader.Follow(16)
+	defer feed.Close()
+	leader.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	leader.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1})
+	<-feed.Records()
+	second := <-feed.Records()
+
+	follower := NewMatchService()
+	_, err := follower.Apply(second)
+	assert.ErrorContains(t, err, "records are missing")
+	_, err = follower.Apply([]wal.Record{{Revision: 0, Op: wal.OpRemove}})
+	assert.ErrorContains(t, err, "unknown person")
+	assert.Empty(t, follower.QuerySinglePeople(0))
+}
+
+func TestMatchService_SlowFeedIsClosed(t *testing.T) {
+	ms := NewMatchService()
+	_, feed := ms.Follow(1)
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1})
+
+	_, ok := <-feed.Records()
+	assert.True(t, ok)
+	_, ok = <-feed.Records()
+	assert.False(t, ok, "a feed that falls behind is closed")
+	feed.Close()
+}
+
+func TestMatchService_ResetClosesFeeds(t *testing.T) {
+	ms := NewMatchService()
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	_, feed := ms.Follow(16)
+
+	other := NewMatchService()
+	other.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	ms.Reset(other.Snapshot())
+
+	_, ok := <-feed.Records()
+	assert.False(t, ok)
+	assertSameState(t, other.Snapshot(), ms.Snapshot())
+	people := ms.QuerySinglePeople(0)
+	require.Len(t, people, 1)
+	assert.Equal(t, "Bob", people[0].Name)
+}
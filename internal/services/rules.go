@@ -0,0 +1,24 @@
+package services
+
+import "matching_system/internal/models"
+
+// DefaultRules match a man with any shorter woman
+var DefaultRules = models.MatchRules{MinHeightGap: 1}
+
+// ValidateRules checks rules a pool is created with, reporting fields under their JSON names
+func ValidateRules(rules models.MatchRules) error {
+	var fields []FieldError
+	if rules.MinHeightGap < 1 {
+		fields = append(fields, FieldError{Field: "rules.min_height_gap", Message: "must be at least 1"})
+	}
+	if rules.MaxHeightGap < 0 {
+		fields = append(fields, FieldError{Field: "rules.max_height_gap", Message: "must be at least 0"})
+	} else if rules.MaxHeightGap != 0 && rules.MaxHeightGap < rules.MinHeightGap {
+		fields = append(fields, FieldError{Field: "rules.max_height_gap", Message: "must be 0 or at least min_height_gap"})
+	}
+
+	if len(fields) > 0 {
+		return NewValidationError(fields...)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"matching_system/internal/models"
+	"matching_system/internal/snapshot"
+	"time"
+)
+
+// WithState restores the pool and match history from a snapshot. People are put into the people
+// store once all options are applied; idempotency keys are not part of these snapshots and start
+// empty, only cluster snapshots keep them.
+func WithState(state snapshot.State) Option {
+	return func(ms *matchService) {
+		ms.restored = &state
+	}
+}
+
+func (ms *matchService) restoreState() {
+	if ms.restored == nil {
+		return
+	}
+	for _, person := range ms.restored.People {
+		person := person
+		ms.people.Put(&person)
+	}
+	// a snapshot taken with a larger history is cut down to this one's size
+	ms.matchHistory = nil
+	ms.recordMatches(ms.restored.Matches...)
+	ms.revision = ms.restored.Revision
+	ms.restored = nil
+}
+
+func (ms *matchService) Snapshot() snapshot.State {
+	v := ms.view.Load()
+	// by ID, so snapshots of the same state are identical
+	people := make([]models.Person, 0, v.byID.Len())
+	v.byID.Each(func(person *models.Person) bool {
+		people = append(people, *person)
+		return true
+	})
+
+	return snapshot.State{
+		Revision: v.revision,
+		People:   people,
+		Matches:  append([]models.Match{}, v.matches...),
+		TakenAt:  time.Now(),
+	}
+}
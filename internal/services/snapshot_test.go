@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchService_SnapshotAndRestore(t *testing.T) {
+	ms := NewMatchService()
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+	carol, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Carol", Height: 170, Gender: "female", WantedDates: 1})
+
+	state := ms.Snapshot()
+	require.Len(t, state.People, 2)
+	require.Len(t, state.Matches, 1)
+	assert.NotZero(t, state.Revision)
+
+	restored := NewMatchService(WithState(state))
+	assert.Equal(t, ms.QuerySinglePeople(0), restored.QuerySinglePeople(0))
+	assert.Equal(t, ms.QueryMatches("", 0), restored.QueryMatches("", 0))
+
+	// people are indexed again and keep their versions
+	person, ok := restored.GetSinglePerson(alice.ID)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), person.Version)
+	assert.NoError(t, restored.RemoveSinglePersonIfMatch(context.Background(), carol.ID, 1))
+
+	// matching carries on from the restored pool
+	_, matches := restored.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "David", Height: 175, Gender: "male", WantedDates: 1})
+	require.Len(t, matches, 1)
+	assert.Equal(t, alice.ID, matches[0].Person2.ID)
+	assert.Len(t, restored.QueryMatches("", 0), 2)
+	assert.Greater(t, restored.Snapshot().Revision, state.Revision)
+}
+
+func TestMatchService_KeepsTheLatestMatches(t *testing.T) {
+	ms := NewMatchService(WithMatchHistory(2))
+	for _, name := range []string{"Alice", "Carol", "Eve"} {
+		ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: name, Height: 160, Gender: "female", WantedDates: 1})
+	}
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 3})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Grace", Height: 160, Gender: "female", WantedDates: 1})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Dan", Height: 180, Gender: "male", WantedDates: 1})
+
+	matches := ms.QueryMatches("", 0)
+	require.Len(t, matches, 2, "the oldest matches are dropped")
+	assert.Equal(t, "Dan", matches[0].Person1.Name)
+	assert.Equal(t, "Bob", matches[1].Person1.Name)
+	assert.Len(t, ms.Snapshot().Matches, 2)
+
+	// a snapshot of a longer history is cut down on restore
+	restored := NewMatchService(WithMatchHistory(1))
+	restored.Reset(ms.Snapshot())
+	matches = restored.QueryMatches("", 0)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "Dan", matches[0].Person1.Name)
+}
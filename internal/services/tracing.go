@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer uses the global tracer provider, which is a no-op unless one is installed, see internal/tracing
+var tracer = otel.Tracer("matching_system/internal/services")
+
+// startOperation starts the span of a call to the service
+func (ms *matchService) startOperation(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("pool", ms.pool))
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// startStage starts the span of a stage of the operation traced in ctx. Work outside of a traced
+// operation, such as replaying the write-ahead log, gets the no-op span of ctx instead.
+func startStage(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks span as failed with err, if any, and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
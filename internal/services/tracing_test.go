@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var (
+	installTracing sync.Once
+	spans          = tracetest.NewInMemoryExporter()
+)
+
+// recordSpans has the spans ended from now on recorded by spans. The global provider is only
+// installed once: tracers taken before that keep using the first one installed.
+func recordSpans(t *testing.T) {
+	installTracing.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	})
+	spans.Reset()
+	t.Cleanup(spans.Reset)
+}
+
+func spanNamed(t *testing.T, stubs tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, stub := range stubs {
+		if stub.Name == name {
+			return stub
+		}
+	}
+	require.Failf(t, "span not found", "no span named %s", name)
+	return tracetest.SpanStub{}
+}
+
+func attributeOf(stub tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range stub.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMatchService_TracesMatchingStages(t *testing.T) {
+	recordSpans(t)
+	ms := NewMatchService(WithPool("eu", DefaultRules))
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	spans.Reset()
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "request")
+	_, matches, err := ms.AddSinglePersonAndMatchIdempotent(ctx, "", dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 2})
+	request.End()
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	stubs := spans.GetSpans()
+	require.Len(t, stubs, 5)
+	add := spanNamed(t, stubs, "MatchService.AddPerson")
+	assert.Equal(t, request.SpanContext().SpanID(), add.Parent.SpanID())
+	assert.Equal(t, "eu", attributeOf(add, "pool").AsString())
+	assert.Equal(t, int64(1), attributeOf(add, "matches").AsInt64())
+
+	lookup := spanNamed(t, stubs, "match.find_candidates")
+	pair := spanNamed(t, stubs, "match.pair")
+	commit := spanNamed(t, stubs, "match.commit")
+	for _, stage := range []tracetest.SpanStub{lookup, pair, commit} {
+		assert.Equal(t, add.SpanContext.SpanID(), stage.Parent.SpanID(), "%s should be a stage of the add", stage.Name)
+	}
+	assert.Equal(t, int64(1), attributeOf(lookup, "candidates").AsInt64())
+	assert.Equal(t, int64(1), attributeOf(pair, "matches").AsInt64())
+	assert.Equal(t, int64(1), attributeOf(commit, "batch.size").AsInt64())
+	assert.False(t, commit.StartTime.Before(pair.EndTime), "the batch commits after matching")
+}
+
+func TestMatchService_TracesFailures(t *testing.T) {
+	recordSpans(t)
+	ms := NewMatchService()
+
+	_, _, err := ms.UpdateSinglePerson(context.Background(), "nobody", dto.UpdatePersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1}, 0)
+	require.ErrorIs(t, err, ErrPersonNotFound)
+
+	update := spanNamed(t, spans.GetSpans(), "MatchService.UpdatePerson")
+	assert.Equal(t, codes.Error, update.Status.Code)
+	assert.Equal(t, "nobody", attributeOf(update, "person.id").AsString())
+}
+
+func TestMatchService_TracesImportAsOneOperation(t *testing.T) {
+	recordSpans(t)
+	ms := NewMatchService()
+
+	_, matches, err := ms.ImportPeople(context.Background(), []dto.ImportPerson{
+		{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1},
+		{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1},
+	}, true, false)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+
+	var names []string
+	for _, stub := range spans.GetSpans() {
+		names = append(names, stub.Name)
+	}
+	assert.ElementsMatch(t, []string{"MatchService.ImportPeople", "match.commit"}, names)
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"matching_system/internal/api/dto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRequest(t *testing.T) {
+	err := ValidateRequest(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 1})
+	assert.NoError(t, err)
+
+	err = ValidateRequest(dto.AddPersonRequest{Height: 300, Gender: "other", WantedDates: 1})
+	assert.ErrorIs(t, err, NewValidationError())
+
+	serviceErr := err.(*Error)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "height", Message: "must be at most 250"},
+		{Field: "gender", Message: "must be one of: male, female"},
+	}, serviceErr.Fields)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchService_Version_BumpedByMatch(t *testing.T) {
+	ms := NewMatchService()
+
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+	assert.Equal(t, int64(1), alice.Version, "a new person should start at version 1")
+
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	found, ok := ms.GetSinglePerson(alice.ID)
+	assert.True(t, ok, "Alice should still be active")
+	assert.Equal(t, int64(2), found.Version, "using a date should bump the version")
+	assert.Equal(t, 1, found.WantedDates, "Alice should have one date left")
+}
+
+func TestMatchService_UpdateSinglePerson(t *testing.T) {
+	ms := NewMatchService()
+
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 190, Gender: "female", WantedDates: 2})
+	ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+
+	// stale version is rejected
+	_, _, err := ms.UpdateSinglePerson(context.Background(), alice.ID, dto.UpdatePersonRequest{Name: "Alice", Height: 170, Gender: "female", WantedDates: 2}, 5)
+	assert.ErrorIs(t, err, ErrVersionMismatch)
+
+	// current version is accepted and the new height matches Bob
+	updated, matches, err := ms.UpdateSinglePerson(context.Background(), alice.ID, dto.UpdatePersonRequest{Name: "Alice", Height: 170, Gender: "female", WantedDates: 2}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version, "the update should bump the version")
+	assert.Equal(t, 1, len(matches), "Alice should now match Bob")
+	assert.Equal(t, 1, updated.WantedDates, "Alice should have used one date")
+
+	_, _, err = ms.UpdateSinglePerson(context.Background(), "non-existent-id", dto.UpdatePersonRequest{Name: "Nobody", Height: 170, Gender: "female", WantedDates: 1}, 0)
+	assert.ErrorIs(t, err, ErrPersonNotFound)
+}
+
+func TestMatchService_RemoveSinglePersonIfMatch(t *testing.T) {
+	ms := NewMatchService()
+
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+
+	assert.ErrorIs(t, ms.RemoveSinglePersonIfMatch(context.Background(), alice.ID, 2), ErrVersionMismatch)
+	assert.NoError(t, ms.RemoveSinglePersonIfMatch(context.Background(), alice.ID, 1))
+	assert.ErrorIs(t, ms.RemoveSinglePersonIfMatch(context.Background(), alice.ID, 0), ErrPersonNotFound)
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"matching_system/internal/models"
+	"matching_system/internal/storage"
+)
+
+// view is an immutable point-in-time copy of the pool that readers query without any lock. The
+// writer publishes a new one after every batch; its indexes share everything the batch did not
+// change with the previous view. The people in a view are copies that are never changed, each
+// shared by all indexes.
+type view struct {
+	revision uint64
+	// byID holds every active person, by ID
+	byID *storage.Sorted[*models.Person]
+	// ranking holds them in the order QuerySinglePeople returns them
+	ranking *storage.Sorted[*models.Person]
+	// candidates holds them by gender, in the order candidateRange visits them
+	candidates map[string]*storage.Sorted[*models.Person]
+	// matches is the history; the writer only ever appends to it past the view's length
+	matches []models.Match
+}
+
+func byIDLess(a, b *models.Person) bool {
+	return a.ID < b.ID
+}
+
+// rankingLess puts people with more dates wanted first, then women shortest first, then men tallest
+// first
+func rankingLess(a, b *models.Person) bool {
+	if a.WantedDates != b.WantedDates {
+		return a.WantedDates > b.WantedDates
+	}
+	if a.Gender != b.Gender {
+		return a.Gender == "female"
+	}
+	if a.Height != b.Height {
+		if a.Gender == "female" {
+			return a.Height < b.Height
+		}
+		return a.Height > b.Height
+	}
+	return a.ID < b.ID
+}
+
+// candidatesLess orders people like storage.HeightRange: by height, equal heights by ID
+func candidatesLess(descending bool) func(a, b *models.Person) bool {
+	return func(a, b *models.Person) bool {
+		if a.Height != b.Height {
+			return a.Height < b.Height != descending
+		}
+		return a.ID < b.ID
+	}
+}
+
+// candidateOrder is whether candidateRange visits the people of a gender tallest first: men are
+// women's candidates, tallest first, and women men's, shortest first
+var candidateOrder = map[string]bool{
+	"female": false,
+	"male":   true,
+}
+
+// buildView indexes the whole pool, for the first view
+func (ms *matchService) buildView() {
+	people := make([]*models.Person, 0, ms.people.Len())
+	ms.published = make(map[string]*models.Person, ms.people.Len())
+	ms.people.Each(func(person *models.Person) bool {
+		published := *person
+		people = append(people, &published)
+		ms.published[person.ID] = &published
+		return true
+	})
+
+	v := &view{
+		revision:   ms.revision,
+		byID:       storage.NewSorted(byIDLess, people...),
+		ranking:    storage.NewSorted(rankingLess, people...),
+		candidates: make(map[string]*storage.Sorted[*models.Person], len(candidateOrder)),
+		matches:    ms.matchHistory[:len(ms.matchHistory):len(ms.matchHistory)],
+	}
+	for gender, descending := range candidateOrder {
+		v.candidates[gender] = storage.NewSorted(candidatesLess(descending), ofGender(people, gender)...)
+	}
+	ms.view.Store(v)
+	ms.changed = nil
+}
+
+// publishView publishes a view with the changes of the batch, must be called by the writer
+func (ms *matchService) publishView() {
+	previous := ms.view.Load()
+	var removed, added []*models.Person
+	for id := range ms.changed {
+		if person, ok := ms.published[id]; ok {
+			removed = append(removed, person)
+			delete(ms.published, id)
+		}
+		if person, ok := ms.people.Get(id); ok {
+			published := *person
+			added = append(added, &published)
+			ms.published[id] = &published
+		}
+	}
+	ms.changed = nil
+
+	v := &view{
+		revision:   ms.revision,
+		byID:       previous.byID.Apply(removed, added),
+		ranking:    previous.ranking.Apply(removed, added),
+		candidates: make(map[string]*storage.Sorted[*models.Person], len(previous.candidates)),
+		matches:    ms.matchHistory[:len(ms.matchHistory):len(ms.matchHistory)],
+	}
+	for gender, index := range previous.candidates {
+		v.candidates[gender] = index.Apply(ofGender(removed, gender), ofGender(added, gender))
+	}
+	ms.view.Store(v)
+}
+
+// changedPerson marks a person whose view entries are out of date, must be called by the writer
+func (ms *matchService) changedPerson(id string) {
+	if ms.changed == nil {
+		ms.changed = make(map[string]struct{})
+	}
+	ms.changed[id] = struct{}{}
+}
+
+func ofGender(people []*models.Person, gender string) []*models.Person {
+	var result []*models.Person
+	for _, person := range people {
+		if person.Gender == gender {
+			result = append(result, person)
+		}
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchService_ReadsSeeWholeBatches(t *testing.T) {
+	ms := NewMatchService().(*matchService)
+	alice, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 160, Gender: "female", WantedDates: 2})
+
+	var during []models.Person
+	ms.write(context.Background(), func() {
+		ms.addPerson(context.Background(), dto.AddPersonRequest{Name: "Bob", Height: 180, Gender: "male", WantedDates: 1})
+		// reads never wait for the writer, they see the pool as of the last batch
+		during = ms.QuerySinglePeople(0)
+	})
+
+	require.Len(t, during, 1)
+	assert.Equal(t, 2, during[0].WantedDates)
+	person, ok := ms.GetSinglePerson(alice.ID)
+	require.True(t, ok)
+	assert.Equal(t, 1, person.WantedDates)
+	assert.Len(t, ms.QueryMatches("", 0), 1)
+}
+
+func TestMatchService_ViewMatchesStore(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ms := NewMatchService(WithPool("test", models.MatchRules{MinHeightGap: 2, MaxHeightGap: 15})).(*matchService)
+	genders := []string{"female", "male"}
+
+	var ids []string
+	for i := 0; i < 2000; i++ {
+		switch {
+		case len(ids) > 0 && rng.Intn(4) == 0:
+			j := rng.Intn(len(ids))
+			ms.RemoveSinglePerson(ids[j])
+			ids = append(ids[:j], ids[j+1:]...)
+		case len(ids) > 0 && rng.Intn(4) == 0:
+			ms.UpdateSinglePerson(context.Background(), ids[rng.Intn(len(ids))], dto.UpdatePersonRequest{Name: "Carol", Height: 150 + rng.Intn(40), Gender: genders[rng.Intn(2)], WantedDates: 1 + rng.Intn(3)}, 0)
+		default:
+			person, _ := ms.AddSinglePersonAndMatch(dto.AddPersonRequest{Name: "Alice", Height: 150 + rng.Intn(40), Gender: genders[rng.Intn(2)], WantedDates: 1 + rng.Intn(3)})
+			ids = append(ids, person.ID)
+		}
+	}
+
+	// the view must hold what the writer's store holds, in the orders the store would give
+	var stored []models.Person
+	ms.people.Each(func(person *models.Person) bool {
+		stored = append(stored, *person)
+		return true
+	})
+	sort.Slice(stored, func(i, j int) bool { return rankingLess(&stored[i], &stored[j]) })
+	assert.Equal(t, stored, ms.QuerySinglePeople(0))
+	assert.Equal(t, len(stored), len(ms.Snapshot().People))
+
+	for height := 140; height < 200; height += 3 {
+		for _, gender := range genders {
+			r, _ := candidateRange(&models.Person{Gender: gender, Height: height}, ms.rules)
+			want := []models.Person{}
+			for _, person := range ms.people.TopN(r, 5) {
+				want = append(want, *person)
+			}
+			assert.Equal(t, want, ms.FindCandidates(gender, height, 5), "%s of %dcm", gender, height)
+		}
+	}
+}
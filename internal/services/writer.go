@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"runtime"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBatch bounds how many writes one caller applies for others before handing over
+const maxBatch = 256
+
+// command is a write waiting to be applied
+type command struct {
+	// ctx traces the write, whose commit is a stage of it
+	ctx   context.Context
+	apply func()
+	// wake receives false once the write is applied, or true when its caller is to apply the next batch
+	wake     chan bool
+	panicked interface{}
+	// err is why the write could not be committed, see applyBatch
+	err error
+}
+
+func (cmd *command) run() {
+	defer func() { cmd.panicked = recover() }()
+	cmd.apply()
+}
+
+// write applies fn as the only writer of the pool. Concurrent writes queue up, and whoever finds
+// no writer running applies the queue as one batch: the log records of the batch are fsynced
+// together, then a view with all of its changes is published for readers and its events after
+// that, in order. The writer then hands over to the first write queued in the meantime, so batches
+// keep forming under load and no caller works through the queue for long.
+//
+// fn must not call back into the service. write returns an error, and what fn did is not published,
+// when the batch cannot be made durable.
+func (ms *matchService) write(ctx context.Context, fn func()) error {
+	cmd := &command{ctx: ctx, apply: fn, wake: make(chan bool, 1)}
+
+	ms.queueMu.Lock()
+	ms.queue = append(ms.queue, cmd)
+	lead := !ms.writing
+	ms.writing = true
+	ms.queueMu.Unlock()
+
+	if !lead {
+		lead = <-cmd.wake
+	}
+	if lead {
+		// the queue starts with cmd, so it is part of the batch
+		ms.applyBatch()
+	}
+	if cmd.panicked != nil {
+		panic(cmd.panicked)
+	}
+	return cmd.err
+}
+
+func (ms *matchService) applyBatch() {
+	// let writers that are ready to run queue up first, a batch costs one fsync and one view
+	// however large
+	runtime.Gosched()
+
+	ms.queueMu.Lock()
+	n := min(len(ms.queue), maxBatch)
+	batch := ms.queue[:n:n]
+	ms.queue = ms.queue[n:]
+	ms.queueMu.Unlock()
+
+	for _, cmd := range batch {
+		if ms.failed != nil {
+			cmd.err = ms.failed
+			continue
+		}
+		cmd.run()
+	}
+	// the batch commits as one, every write in it waits for the whole commit
+	commits := make([]trace.Span, len(batch))
+	for i, cmd := range batch {
+		_, commits[i] = startStage(cmd.ctx, "match.commit", attribute.Int("batch.size", len(batch)))
+	}
+	// readers must not see changes that could still be lost
+	records, err := ms.sync()
+	if err != nil {
+		// the log may now end in a torn record, so nothing can be made durable any more: the batch is
+		// not published, and it and every later write fail while readers keep the last view
+		ms.log(context.Background()).Error("Failed to append to the write-ahead log, the pool no longer takes writes", "error", err)
+		ms.failed = ErrJournalFailed
+		ms.outbox, ms.auditing, ms.reset = nil, nil, false
+		for i, cmd := range batch {
+			cmd.err = ms.failed
+			endSpan(commits[i], err)
+		}
+		ms.handOver(batch)
+		return
+	}
+	ms.feedMu.Lock()
+	if ms.reset {
+		ms.reset = false
+		ms.buildView()
+		// followers of this pool have to start over from the new state
+		ms.closeFeeds()
+	} else {
+		ms.publishView()
+	}
+	ms.deliver(records)
+	ms.feedMu.Unlock()
+	outbox := ms.outbox
+	ms.outbox = nil
+
+	for _, event := range outbox {
+		ms.events.Publish(event)
+	}
+	ms.flushAudit()
+	for _, commit := range commits {
+		commit.End()
+	}
+	ms.handOver(batch)
+}
+
+// handOver wakes the writes of batch and the write to apply the next batch, if any
+func (ms *matchService) handOver(batch []*command) {
+	for _, cmd := range batch {
+		cmd.wake <- false
+	}
+
+	ms.queueMu.Lock()
+	if len(ms.queue) == 0 {
+		ms.writing = false
+	} else {
+		ms.queue[0].wake <- true
+	}
+	ms.queueMu.Unlock()
+}
@@ -0,0 +1,189 @@
+package sharding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/internal/transfer"
+	"net/http"
+	"net/url"
+)
+
+// Move is a pool on a shard other than the one owning it, and how many people it holds
+type Move struct {
+	Pool   string `json:"pool"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	People int    `json:"people"`
+}
+
+// Rebalance moves every pool to the shard owning it in ring, as needed after adding shards to it.
+// Pools are listed on all of ring's shards, so the new ring must still include the shards pools move
+// away from. A pool is moved by exporting its people, importing them into the pool on its owner with
+// their IDs and versions, unmatched, and then deleting the pool from the old shard. The default pool
+// exists on every shard, so only its people are moved and removed. Match history stays behind.
+//
+// Writes to a pool while it moves are lost, so the routers should keep writes away until they are
+// restarted with ring. A rebalance that was interrupted can be run again, people already moved are
+// not imported twice. With dryRun the moves are only listed.
+func Rebalance(ctx context.Context, client *http.Client, ring *Ring, dryRun bool) ([]Move, error) {
+	var moves []Move
+	for _, shard := range ring.Shards() {
+		held, err := listPools(ctx, client, shard)
+		if err != nil {
+			return moves, err
+		}
+		for _, pool := range held {
+			owner := ring.Owner(pool.Name)
+			if owner.ID == shard.ID {
+				continue
+			}
+			people, err := call(ctx, client, http.MethodGet, shard, poolPath(pool.Name)+"/admin/export/people?format=jsonl", nil)
+			if err != nil {
+				return moves, err
+			}
+			exported, err := transfer.ReadPeople(bytes.NewReader(people), transfer.JSONL)
+			if err != nil {
+				return moves, fmt.Errorf("read the people of pool %s on shard %s: %w", pool.Name, shard.ID, err)
+			}
+			// every shard has an empty default pool, only the one that owned it has anything to move
+			if pool.Name == pools.DefaultPool && len(exported) == 0 {
+				continue
+			}
+
+			move := Move{Pool: pool.Name, From: shard.ID, To: owner.ID, People: len(exported)}
+			if !dryRun {
+				if err := movePool(ctx, client, pool, shard, owner, exported); err != nil {
+					return moves, fmt.Errorf("move pool %s from shard %s to %s: %w", pool.Name, shard.ID, owner.ID, err)
+				}
+			}
+			moves = append(moves, move)
+		}
+	}
+	return moves, nil
+}
+
+func movePool(ctx context.Context, client *http.Client, pool models.Pool, from, to Shard, exported []dto.ImportPerson) error {
+	if pool.Name != pools.DefaultPool {
+		body, _ := json.Marshal(dto.CreatePoolRequest{Name: pool.Name, Rules: &pool.Rules})
+		_, err := call(ctx, client, http.MethodPost, to, "/v1/pools", body)
+		// a pool left there by an interrupted rebalance is filled up with the people it lacks
+		if err != nil && !isCode(err, services.CodePoolExists) {
+			return err
+		}
+	}
+	// an import is all or nothing and refuses IDs the pool has, so only the people not moved yet go
+	missing, err := missingPeople(ctx, client, pool.Name, to, exported)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		var people bytes.Buffer
+		encoder := json.NewEncoder(&people)
+		for _, person := range missing {
+			if err := encoder.Encode(person); err != nil {
+				return err
+			}
+		}
+		query := url.Values{"format": {string(transfer.JSONL)}, "match": {"false"}}
+		if _, err := call(ctx, client, http.MethodPost, to, poolPath(pool.Name)+"/admin/import/people?"+query.Encode(), people.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if pool.Name != pools.DefaultPool {
+		_, err := call(ctx, client, http.MethodDelete, from, poolPath(pool.Name), nil)
+		return err
+	}
+	for _, person := range exported {
+		if _, err := call(ctx, client, http.MethodDelete, from, poolPath(pool.Name)+"/people/"+url.PathEscape(person.ID), nil); err != nil && !isCode(err, services.CodePersonNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingPeople returns the people of exported the pool on shard does not have
+func missingPeople(ctx context.Context, client *http.Client, pool string, shard Shard, exported []dto.ImportPerson) ([]dto.ImportPerson, error) {
+	body, err := call(ctx, client, http.MethodGet, shard, poolPath(pool)+"/admin/export/people?format=jsonl", nil)
+	if err != nil {
+		return nil, err
+	}
+	held, err := transfer.ReadPeople(bytes.NewReader(body), transfer.JSONL)
+	if err != nil {
+		return nil, fmt.Errorf("read the people of pool %s on shard %s: %w", pool, shard.ID, err)
+	}
+	ids := make(map[string]bool, len(held))
+	for _, person := range held {
+		ids[person.ID] = true
+	}
+	var missing []dto.ImportPerson
+	for _, person := range exported {
+		if !ids[person.ID] {
+			missing = append(missing, person)
+		}
+	}
+	return missing, nil
+}
+
+func poolPath(name string) string {
+	return poolsPrefix + url.PathEscape(name)
+}
+
+func listPools(ctx context.Context, client *http.Client, shard Shard) ([]models.Pool, error) {
+	body, err := call(ctx, client, http.MethodGet, shard, "/v1/pools", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp dto.QueryPoolsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, &services.Error{Code: services.CodeShardUnavailable, Message: "shard " + shard.ID + " listed its pools as " + err.Error()}
+	}
+	return resp.Pools, nil
+}
+
+// call sends a request to a shard and returns the body of a successful response. A problem response
+// becomes a services.Error with the problem's code, anything else failing one with shard_unavailable.
+func call(ctx context.Context, client *http.Client, method string, shard Shard, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, shard.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, &services.Error{Code: services.CodeShardUnavailable, Message: "shard " + shard.ID + " is unavailable: " + err.Error()}
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &services.Error{Code: services.CodeShardUnavailable, Message: "shard " + shard.ID + " is unavailable: " + err.Error()}
+	}
+	if resp.StatusCode < 300 {
+		return data, nil
+	}
+
+	var problem dto.Problem
+	if json.Unmarshal(data, &problem) != nil || problem.Code == "" {
+		return nil, &services.Error{Code: services.CodeShardUnavailable, Message: "shard " + shard.ID + " answered " + resp.Status}
+	}
+	serviceErr := &services.Error{Code: services.ErrorCode(problem.Code), Message: "shard " + shard.ID + ": " + problem.Detail}
+	for _, field := range problem.Errors {
+		serviceErr.Fields = append(serviceErr.Fields, services.FieldError{Field: field.Field, Message: field.Message})
+	}
+	return nil, serviceErr
+}
+
+func isCode(err error, code services.ErrorCode) bool {
+	var serviceErr *services.Error
+	return errors.As(err, &serviceErr) && serviceErr.Code == code
+}
@@ -0,0 +1,103 @@
+package sharding
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultVirtualNodes is how many points each shard gets on the ring, enough to spread pools evenly
+const DefaultVirtualNodes = 128
+
+// Shard is an API server holding some of the pools
+type Shard struct {
+	ID string
+	// URL is the base URL of the shard's API
+	URL string
+}
+
+// ParseShards reads shards written as id=url, separated by commas
+func ParseShards(value string) ([]Shard, error) {
+	var shards []Shard
+	for _, entry := range strings.Split(value, ",") {
+		id, url, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" || url == "" {
+			return nil, fmt.Errorf("shard %q is not id=url", entry)
+		}
+		shards = append(shards, Shard{ID: id, URL: strings.TrimSuffix(url, "/")})
+	}
+	return shards, nil
+}
+
+// Ring assigns pools to shards by consistent hashing: adding a shard only moves the pools the new
+// shard takes over, about 1/n of them, and leaves the others where they are
+type Ring struct {
+	shards []Shard
+	points []point
+}
+
+type point struct {
+	hash  uint64
+	shard int
+}
+
+// NewRing places virtualNodes points per shard on the ring; a pool belongs to the shard of the first
+// point at or after its own hash
+func NewRing(shards []Shard, virtualNodes int) (*Ring, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("a ring needs at least one shard")
+	}
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	r := &Ring{shards: shards}
+	seen := make(map[string]bool, len(shards))
+	for i, shard := range shards {
+		if seen[shard.ID] {
+			return nil, fmt.Errorf("shard %q is listed twice", shard.ID)
+		}
+		seen[shard.ID] = true
+		// points depend on the ID only, so a shard keeps its pools when it moves to another URL
+		for v := 0; v < virtualNodes; v++ {
+			r.points = append(r.points, point{hash: hash(shard.ID + "#" + strconv.Itoa(v)), shard: i})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		return r.points[i].hash < r.points[j].hash
+	})
+	return r, nil
+}
+
+// hash is FNV-1a with murmur3's finalizer, since FNV alone spreads keys differing only in their
+// last characters, such as a shard's virtual nodes, poorly
+func hash(key string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(key))
+	h := f.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Owner returns the shard holding the pool named key
+func (r *Ring) Owner(key string) Shard {
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= h
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.shards[r.points[i].shard]
+}
+
+// Shards returns the shards in the order they were given
+func (r *Ring) Shards() []Shard {
+	return append([]Shard(nil), r.shards...)
+}
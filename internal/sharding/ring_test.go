@@ -0,0 +1,62 @@
+package sharding
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func shardsOf(n int) []Shard {
+	var shards []Shard
+	for i := 1; i <= n; i++ {
+		shards = append(shards, Shard{ID: "s" + strconv.Itoa(i), URL: "http://shard" + strconv.Itoa(i)})
+	}
+	return shards
+}
+
+func TestRing_SpreadsPools(t *testing.T) {
+	ring, err := NewRing(shardsOf(4), DefaultVirtualNodes)
+	require.NoError(t, err)
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[ring.Owner("pool-"+strconv.Itoa(i)).ID]++
+	}
+	require.Len(t, counts, 4)
+	for id, count := range counts {
+		assert.InDelta(t, 2500, count, 750, "shard %s", id)
+	}
+	assert.Equal(t, ring.Owner("eu-west"), ring.Owner("eu-west"))
+}
+
+func TestRing_AddingAShardOnlyMovesPoolsToIt(t *testing.T) {
+	before, err := NewRing(shardsOf(3), DefaultVirtualNodes)
+	require.NoError(t, err)
+	after, err := NewRing(shardsOf(4), DefaultVirtualNodes)
+	require.NoError(t, err)
+
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		key := "pool-" + strconv.Itoa(i)
+		if from, to := before.Owner(key), after.Owner(key); from != to {
+			assert.Equal(t, "s4", to.ID, "%s moved between old shards", key)
+			moved++
+		}
+	}
+	assert.InDelta(t, 2500, moved, 750, "about a quarter of the pools should move to the new shard")
+}
+
+func TestParseShards(t *testing.T) {
+	shards, err := ParseShards("a=http://one:8080/, b=http://two:8080")
+	require.NoError(t, err)
+	assert.Equal(t, []Shard{{ID: "a", URL: "http://one:8080"}, {ID: "b", URL: "http://two:8080"}}, shards)
+
+	_, err = ParseShards("a")
+	assert.Error(t, err)
+	_, err = NewRing([]Shard{{ID: "a"}, {ID: "a"}}, 0)
+	assert.Error(t, err)
+	_, err = NewRing(nil, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package sharding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/api/handlers"
+	"matching_system/internal/api/middleware"
+	"matching_system/internal/models"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/pkg/logger"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	poolsPrefix = "/v1/pools/"
+	// maxPoolBody bounds the pool creation bodies the router reads to find the pool's name
+	maxPoolBody = 1 << 20
+	// defaultListTimeout bounds the calls listing every shard's pools
+	defaultListTimeout = 10 * time.Second
+)
+
+type Router struct {
+	ring      *Ring
+	proxies   map[string]*httputil.ReverseProxy
+	transport http.RoundTripper
+	client    *http.Client
+	logger    *logger.Logger
+}
+
+// Option configures a Router
+type Option func(*Router)
+
+// WithTransport reaches the shards through transport instead of http.DefaultTransport
+func WithTransport(transport http.RoundTripper) Option {
+	return func(r *Router) {
+		r.transport = transport
+	}
+}
+
+// WithLogger logs requests with l instead of the default logger
+func WithLogger(l *logger.Logger) Option {
+	return func(r *Router) {
+		r.logger = l
+	}
+}
+
+// ginContextKey carries the gin context of a forwarded request to the proxy's error handler
+type ginContextKey struct{}
+
+// NewRouter builds the router in front of the shards of ring. Every pool lives on one shard, so calls
+// under /v1/pools/{pool} go to the shard owning the pool, and every other call, the default pool's
+// routes, webhooks, GraphQL and the event stream, to the shard owning the default pool.
+func NewRouter(ring *Ring, opts ...Option) *gin.Engine {
+	r := &Router{
+		ring:      ring,
+		proxies:   make(map[string]*httputil.ReverseProxy),
+		transport: http.DefaultTransport,
+		logger:    logger.Default(),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.client = &http.Client{Transport: r.transport, Timeout: defaultListTimeout}
+	for _, shard := range ring.Shards() {
+		shard := shard
+		target, _ := url.Parse(shard.URL)
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.Transport = r.transport
+		// streams such as /events and the replication stream are passed on as they are written
+		proxy.FlushInterval = -1
+		proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+			c := req.Context().Value(ginContextKey{}).(*gin.Context)
+			middleware.WriteProblem(c, &services.Error{
+				Code:    services.CodeShardUnavailable,
+				Message: "shard " + shard.ID + " is unavailable: " + err.Error(),
+			})
+		}
+		r.proxies[shard.ID] = proxy
+	}
+
+	// the request ID is set on forwarded requests, so the shard logs them with the router's ID
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.RequestID(r.logger), middleware.AccessLog(), middleware.Problems())
+	router.GET("/health", handlers.HealthCheck)
+	router.GET("/v1/pools", r.ListPools)
+	router.POST("/v1/pools", r.CreatePool)
+	router.NoRoute(r.Forward)
+	return router
+}
+
+// Forward passes the request on to the shard owning its pool
+func (r *Router) Forward(c *gin.Context) {
+	pool := pools.DefaultPool
+	if rest, ok := strings.CutPrefix(c.Request.URL.Path, poolsPrefix); ok {
+		pool, _, _ = strings.Cut(rest, "/")
+	}
+	r.forward(c, r.ring.Owner(pool))
+}
+
+func (r *Router) forward(c *gin.Context, shard Shard) {
+	req := c.Request.WithContext(context.WithValue(c.Request.Context(), ginContextKey{}, c))
+	r.proxies[shard.ID].ServeHTTP(c.Writer, req)
+}
+
+// CreatePool creates the pool on the shard that will own it
+func (r *Router) CreatePool(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPoolBody))
+	if err != nil {
+		c.Error(&services.Error{Code: services.CodeMalformedRequest, Message: "could not read the request body"})
+		return
+	}
+	// a body without a name is passed on anyway, for the shard to reject like any other bad request
+	var req struct {
+		Name string `json:"name"`
+	}
+	json.Unmarshal(body, &req)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+	r.forward(c, r.ring.Owner(req.Name))
+}
+
+// ListPools lists the pools of every shard, each pool from the shard owning it, so pools a shard
+// still holds from before a rebalance are not listed twice
+func (r *Router) ListPools(c *gin.Context) {
+	shards := r.ring.Shards()
+	lists := make([][]models.Pool, len(shards))
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, shard := range shards {
+		wg.Add(1)
+		go func(i int, shard Shard) {
+			defer wg.Done()
+			lists[i], errs[i] = listPools(c.Request.Context(), r.client, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	var all []models.Pool
+	for i, shard := range shards {
+		if errs[i] != nil {
+			c.Error(errs[i])
+			return
+		}
+		for _, pool := range lists[i] {
+			if r.ring.Owner(pool.Name).ID == shard.ID {
+				all = append(all, pool)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name < all[j].Name
+	})
+
+	c.JSON(http.StatusOK, dto.QueryPoolsResponse{
+		Pools:   all,
+		Message: "pools queried successfully",
+	})
+}
@@ -0,0 +1,249 @@
+package sharding_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/api/routes"
+	"matching_system/internal/events"
+	"matching_system/internal/pools"
+	"matching_system/internal/sharding"
+	"matching_system/internal/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startShards runs n API servers in process
+func startShards(t *testing.T, n int) []sharding.Shard {
+	gin.SetMode(gin.TestMode)
+	var shards []sharding.Shard
+	for i := 1; i <= n; i++ {
+		registry, err := pools.NewRegistry(pools.NewMemoryBackend())
+		require.NoError(t, err)
+		server := httptest.NewServer(routes.Setup(registry, events.NewBroker(events.DefaultHistorySize), webhooks.NewStore(), nil, nil, nil, nil, nil))
+		t.Cleanup(server.Close)
+		shards = append(shards, sharding.Shard{ID: fmt.Sprintf("s%d", i), URL: server.URL})
+	}
+	return shards
+}
+
+func newRing(t *testing.T, shards []sharding.Shard) *sharding.Ring {
+	ring, err := sharding.NewRing(shards, sharding.DefaultVirtualNodes)
+	require.NoError(t, err)
+	return ring
+}
+
+func startRouter(t *testing.T, ring *sharding.Ring) string {
+	server := httptest.NewServer(sharding.NewRouter(ring))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func request(t *testing.T, base, method, path, body string) (int, string) {
+	req, err := http.NewRequest(method, base+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func poolNames(t *testing.T, base string) []string {
+	code, body := request(t, base, "GET", "/v1/pools", "")
+	require.Equal(t, http.StatusOK, code, body)
+	var resp dto.QueryPoolsResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	var names []string
+	for _, pool := range resp.Pools {
+		names = append(names, pool.Name)
+	}
+	return names
+}
+
+func peopleOf(t *testing.T, base, pool string) []string {
+	code, body := request(t, base, "GET", "/v1/pools/"+pool+"/people", "")
+	require.Equal(t, http.StatusOK, code, body)
+	var resp dto.QueryPeopleResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+	var ids []string
+	for _, person := range resp.People {
+		ids = append(ids, person.ID)
+	}
+	return ids
+}
+
+var regions = []string{"eu-west", "eu-north", "us-east", "us-west", "ap-south", "ap-east", "sa-east", "af-south"}
+
+func TestRouter_RoutesPoolsToTheirShard(t *testing.T) {
+	shards := startShards(t, 3)
+	ring := newRing(t, shards)
+	router := startRouter(t, ring)
+
+	for _, region := range regions {
+		code, body := request(t, router, "POST", "/v1/pools", `{"name":"`+region+`"}`)
+		require.Equal(t, http.StatusCreated, code, body)
+		code, body = request(t, router, "POST", "/v1/pools/"+region+"/people", `{"name":"Alice","height":160,"gender":"female","wanted_dates":1}`)
+		require.Equal(t, http.StatusCreated, code, body)
+	}
+	code, _ := request(t, router, "POST", "/v1/pools", `{"name":"eu-west"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = request(t, router, "POST", "/v1/people", `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`)
+	require.Equal(t, http.StatusCreated, code)
+
+	// each pool is on its owner only, and the router lists every pool once
+	for _, region := range regions {
+		owner := ring.Owner(region)
+		for _, shard := range shards {
+			code, _ := request(t, shard.URL, "GET", "/v1/pools/"+region, "")
+			if shard.ID == owner.ID {
+				assert.Equal(t, http.StatusOK, code, "%s should be on %s", region, shard.ID)
+			} else {
+				assert.Equal(t, http.StatusNotFound, code, "%s should not be on %s", region, shard.ID)
+			}
+		}
+		assert.Len(t, peopleOf(t, router, region), 1)
+	}
+	assert.ElementsMatch(t, append([]string{pools.DefaultPool}, regions...), poolNames(t, router))
+	assert.Len(t, peopleOf(t, ring.Owner(pools.DefaultPool).URL, pools.DefaultPool), 1, "the default pool's routes go to its owner")
+
+	code, _ = request(t, router, "DELETE", "/v1/pools/eu-west", "")
+	assert.Equal(t, http.StatusNoContent, code)
+	code, body := request(t, router, "GET", "/v1/pools/eu-west", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Contains(t, body, `"code":"pool_not_found"`, "problems from the shard are passed on")
+}
+
+func TestRouter_ShardUnavailable(t *testing.T) {
+	shards := startShards(t, 1)
+	shards = append(shards, sharding.Shard{ID: "down", URL: "http://127.0.0.1:1"})
+	ring := newRing(t, shards)
+	router := startRouter(t, ring)
+
+	pool := ""
+	for _, region := range regions {
+		if ring.Owner(region).ID == "down" {
+			pool = region
+			break
+		}
+	}
+	require.NotEmpty(t, pool, "some region should belong to the shard that is down")
+
+	code, body := request(t, router, "GET", "/v1/pools/"+pool+"/people", "")
+	assert.Equal(t, http.StatusBadGateway, code)
+	assert.Contains(t, body, `"code":"shard_unavailable"`)
+	code, _ = request(t, router, "GET", "/v1/pools", "")
+	assert.Equal(t, http.StatusBadGateway, code)
+	code, _ = request(t, router, "GET", "/health", "")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestRebalance_MovesPoolsToAddedShards(t *testing.T) {
+	shards := startShards(t, 3)
+	before := newRing(t, shards[:2])
+	router := startRouter(t, before)
+
+	people := make(map[string][]string)
+	for _, region := range regions {
+		code, body := request(t, router, "POST", "/v1/pools", `{"name":"`+region+`","rules":{"min_height_gap":3,"max_height_gap":0}}`)
+		require.Equal(t, http.StatusCreated, code, body)
+		for i := 0; i < 3; i++ {
+			code, body = request(t, router, "POST", "/v1/pools/"+region+"/people", fmt.Sprintf(`{"name":"P%d","height":%d,"gender":"female","wanted_dates":1}`, i, 160+i))
+			require.Equal(t, http.StatusCreated, code, body)
+		}
+		people[region] = peopleOf(t, router, region)
+	}
+	code, _ := request(t, router, "POST", "/v1/people", `{"name":"Bob","height":150,"gender":"male","wanted_dates":1}`)
+	require.Equal(t, http.StatusCreated, code)
+	people[pools.DefaultPool] = peopleOf(t, router, pools.DefaultPool)
+
+	after := newRing(t, shards)
+	planned, err := sharding.Rebalance(context.Background(), http.DefaultClient, after, true)
+	require.NoError(t, err)
+	require.NotEmpty(t, planned, "some pools should belong to the new shard")
+	for _, move := range planned {
+		assert.Equal(t, "s3", move.To)
+		assert.Equal(t, len(people[move.Pool]), move.People)
+	}
+	assert.Equal(t, people, func() map[string][]string {
+		got := make(map[string][]string)
+		for pool := range people {
+			got[pool] = peopleOf(t, router, pool)
+		}
+		return got
+	}(), "a dry run should move nothing")
+
+	moves, err := sharding.Rebalance(context.Background(), http.DefaultClient, after, false)
+	require.NoError(t, err)
+	assert.Equal(t, planned, moves)
+
+	// through a router on the new ring every pool has its people, with the same IDs, and rules
+	router = startRouter(t, after)
+	for pool, ids := range people {
+		assert.ElementsMatch(t, ids, peopleOf(t, after.Owner(pool).URL, pool), pool)
+		assert.ElementsMatch(t, ids, peopleOf(t, router, pool), pool)
+	}
+	assert.ElementsMatch(t, append([]string{pools.DefaultPool}, regions...), poolNames(t, router))
+	for _, move := range moves {
+		if move.Pool != pools.DefaultPool {
+			_, body := request(t, router, "GET", "/v1/pools/"+move.Pool, "")
+			assert.Contains(t, body, `"min_height_gap":3`, "%s should keep its rules", move.Pool)
+		}
+	}
+
+	moves, err = sharding.Rebalance(context.Background(), http.DefaultClient, after, false)
+	require.NoError(t, err)
+	assert.Empty(t, moves, "a second rebalance has nothing left to move")
+}
+
+// failDeletes is a transport that fails every DELETE, cutting a rebalance short once it has imported
+type failDeletes struct{}
+
+func (failDeletes) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodDelete {
+		return nil, errors.New("connection reset")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRebalance_RunsAgainAfterAnInterruption(t *testing.T) {
+	shards := startShards(t, 3)
+	router := startRouter(t, newRing(t, shards[:2]))
+	people := make(map[string][]string)
+	for _, region := range regions {
+		code, body := request(t, router, "POST", "/v1/pools", `{"name":"`+region+`"}`)
+		require.Equal(t, http.StatusCreated, code, body)
+		for i := 0; i < 3; i++ {
+			code, body = request(t, router, "POST", "/v1/pools/"+region+"/people", fmt.Sprintf(`{"name":"P%d","height":%d,"gender":"female","wanted_dates":1}`, i, 160+i))
+			require.Equal(t, http.StatusCreated, code, body)
+		}
+		people[region] = peopleOf(t, router, region)
+	}
+
+	after := newRing(t, shards)
+	_, err := sharding.Rebalance(context.Background(), &http.Client{Transport: failDeletes{}}, after, false)
+	require.Error(t, err, "the first move stops before deleting the pool it copied")
+
+	// the pool copied first is on both shards now, running again completes every move
+	moves, err := sharding.Rebalance(context.Background(), http.DefaultClient, after, false)
+	require.NoError(t, err)
+	require.NotEmpty(t, moves)
+	router = startRouter(t, after)
+	for pool, ids := range people {
+		assert.ElementsMatch(t, ids, peopleOf(t, router, pool), pool)
+	}
+	moves, err = sharding.Rebalance(context.Background(), http.DefaultClient, after, false)
+	require.NoError(t, err)
+	assert.Empty(t, moves)
+}
@@ -0,0 +1,61 @@
+package snapshot
+
+import (
+	"context"
+	"matching_system/pkg/logger"
+	"time"
+)
+
+// Source produces a consistent copy of the state to snapshot
+type Source interface {
+	Snapshot() State
+}
+
+// Compactor drops what a snapshot at revision already contains, such as write-ahead log records
+type Compactor interface {
+	Compact(revision uint64) error
+}
+
+// Run saves source every interval while it keeps changing, and once more when ctx is done so
+// a clean shutdown loses nothing. After each save the compactors are told the revision of the oldest
+// snapshot kept: Latest falls back to it when the newer ones are damaged, and what it does not
+// contain must still be there to recover. It returns after the final save.
+func (s *Store) Run(ctx context.Context, source Source, interval time.Duration, l *logger.Logger, compactors ...Compactor) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// a freshly restored state is already on disk
+	saved := source.Snapshot().Revision
+	save := func() {
+		state := source.Snapshot()
+		if state.Revision == saved {
+			return
+		}
+		if err := s.Save(state); err != nil {
+			l.Error("Failed to save snapshot", "error", err)
+			return
+		}
+		saved = state.Revision
+
+		oldest, err := s.Oldest()
+		if err != nil || oldest == nil {
+			l.Error("Failed to read the oldest snapshot, not compacting", "error", err)
+			return
+		}
+		for _, compactor := range compactors {
+			if err := compactor.Compact(oldest.Revision); err != nil {
+				l.Error("Failed to compact after snapshot", "error", err)
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			save()
+		case <-ctx.Done():
+			save()
+			return
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package snapshot
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"matching_system/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	formatVersion  = 1
+	filePrefix     = "snapshot-"
+	fileSuffix     = ".json"
+	tempFilePrefix = ".snapshot-"
+	checksumPrefix = "sha256:"
+)
+
+// ErrNoValidSnapshot is returned when snapshot files exist but none of them passes its checksum
+var ErrNoValidSnapshot = errors.New("no valid snapshot found")
+
+// State is everything needed to rebuild the match service
+type State struct {
+	// Revision counts the changes applied to the service, so unchanged state is not saved twice
+	Revision uint64          `json:"revision"`
+	People   []models.Person `json:"people"`
+	Matches  []models.Match  `json:"matches"`
+	TakenAt  time.Time       `json:"taken_at"`
+}
+
+// file is the on-disk envelope. The checksum covers the raw state bytes, so a torn or edited
+// file is detected before any of it is trusted.
+type file struct {
+	Format   int             `json:"format"`
+	Checksum string          `json:"checksum"`
+	State    json.RawMessage `json:"state"`
+}
+
+// Store writes snapshots into a directory and keeps the most recent few
+type Store struct {
+	dir    string
+	retain int
+}
+
+// NewStore creates dir if needed and keeps the latest retain snapshots in it
+func NewStore(dir string, retain int) (*Store, error) {
+	if retain < 1 {
+		retain = 1
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create snapshot directory: %w", err)
+	}
+	return &Store{dir: dir, retain: retain}, nil
+}
+
+// Save writes state to a temporary file, syncs it and renames it into place, so a crash leaves
+// either the previous snapshots or the complete new one, never a partial file.
+func (s *Store) Save(state State) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	data, err := json.Marshal(file{Format: formatVersion, Checksum: checksum(raw), State: raw})
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, tempFilePrefix+"*.tmp")
+	if err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close snapshot: %w", err)
+	}
+
+	name := filepath.Join(s.dir, fmt.Sprintf("%s%020d%s", filePrefix, state.TakenAt.UnixNano(), fileSuffix))
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	if err := syncDir(s.dir); err != nil {
+		return err
+	}
+	return s.prune()
+}
+
+// Latest loads the newest snapshot that passes its checksum, falling back to older ones when the
+// newest is damaged. It returns nil without error when there are no snapshots yet.
+func (s *Store) Latest() (*State, error) {
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return s.first(names)
+}
+
+// Oldest loads the oldest snapshot that passes its checksum, the last one Latest can fall back to.
+// It returns nil without error when there are no snapshots yet.
+func (s *Store) Oldest() (*State, error) {
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	return s.first(names)
+}
+
+// first loads the first of names that passes its checksum
+func (s *Store) first(names []string) (*State, error) {
+	var errs []error
+	for _, name := range names {
+		state, err := load(filepath.Join(s.dir, name))
+		if err == nil {
+			return state, nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrNoValidSnapshot, errors.Join(errs...))
+	}
+	return nil, nil
+}
+
+func load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if f.Format != formatVersion {
+		return nil, fmt.Errorf("%s: unsupported format %d", filepath.Base(path), f.Format)
+	}
+	if f.Checksum != checksum(f.State) {
+		return nil, fmt.Errorf("%s: checksum mismatch", filepath.Base(path))
+	}
+
+	var state State
+	if err := json.Unmarshal(f.State, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &state, nil
+}
+
+// list returns the snapshot file names, oldest first
+func (s *Store) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			names = append(names, name)
+		}
+	}
+	// names embed a zero-padded timestamp, so lexical order is chronological
+	sort.Strings(names)
+	return names, nil
+}
+
+// prune removes all but the latest retain snapshots
+func (s *Store) prune() error {
+	names, err := s.list()
+	if err != nil {
+		return err
+	}
+	for len(names) > s.retain {
+		if err := os.Remove(filepath.Join(s.dir, names[0])); err != nil {
+			return fmt.Errorf("prune snapshot: %w", err)
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return checksumPrefix + hex.EncodeToString(sum[:])
+}
+
+// syncDir makes a rename durable by syncing the directory entry
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open snapshot directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync snapshot directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"matching_system/internal/models"
+	"matching_system/pkg/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testState(revision uint64, takenAt time.Time) State {
+	alice := models.Person{ID: "1", Name: "Alice", Height: 160, Gender: "female", WantedDates: 1, Version: 2}
+	bob := models.Person{ID: "2", Name: "Bob", Height: 180, Gender: "male", WantedDates: 0, Version: 1}
+	return State{
+		Revision: revision,
+		People:   []models.Person{alice},
+		Matches:  []models.Match{{Person1: bob, Person2: alice, MatchedAt: takenAt.UTC()}},
+		TakenAt:  takenAt.UTC(),
+	}
+}
+
+func snapshotFiles(t *testing.T, dir string) []string {
+	names, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	return names
+}
+
+func TestStore_SaveAndLatest(t *testing.T) {
+	store, err := NewStore(t.TempDir(), 3)
+	require.NoError(t, err)
+
+	state, err := store.Latest()
+	require.NoError(t, err)
+	assert.Nil(t, state, "no snapshot yet")
+
+	now := time.Now()
+	require.NoError(t, store.Save(testState(1, now)))
+	require.NoError(t, store.Save(testState(2, now.Add(time.Second))))
+
+	state, err = store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, testState(2, now.Add(time.Second)), *state)
+}
+
+func TestStore_PrunesOldSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, 2)
+	require.NoError(t, err)
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, store.Save(testState(uint64(i+1), now.Add(time.Duration(i)*time.Second))))
+	}
+
+	assert.Len(t, snapshotFiles(t, dir), 2, "only the latest snapshots are kept and no temp files are left")
+	state, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), state.Revision)
+}
+
+func TestStore_FallsBackWhenLatestIsDamaged(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, 3)
+	require.NoError(t, err)
+
+	now := time.Now()
+	require.NoError(t, store.Save(testState(1, now)))
+	require.NoError(t, store.Save(testState(2, now.Add(time.Second))))
+
+	files := snapshotFiles(t, dir)
+	latest := files[len(files)-1]
+	data, err := os.ReadFile(latest)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "truncated", data: data[:len(data)/2]},
+		{name: "bit flip", data: []byte(string(data[:len(data)-20]) + "X" + string(data[len(data)-19:]))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(latest, tt.data, 0o644))
+
+			state, err := store.Latest()
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1), state.Revision, "the previous snapshot should be used")
+		})
+	}
+
+	// with every snapshot damaged, refuse to start empty
+	require.NoError(t, os.WriteFile(files[0], []byte("{}"), 0o644))
+	_, err = store.Latest()
+	assert.True(t, errors.Is(err, ErrNoValidSnapshot))
+}
+
+type fakeSource struct {
+	revision atomic.Uint64
+}
+
+func (f *fakeSource) Snapshot() State {
+	return testState(f.revision.Load(), time.Now())
+}
+
+func TestStore_Run(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, 10)
+	require.NoError(t, err)
+
+	source := &fakeSource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.Run(ctx, source, 5*time.Millisecond, logger.New())
+		close(done)
+	}()
+
+	// nothing changed, nothing is written
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, snapshotFiles(t, dir))
+
+	source.revision.Store(1)
+	require.Eventually(t, func() bool { return len(snapshotFiles(t, dir)) == 1 }, time.Second, 5*time.Millisecond)
+
+	// a change right before shutdown is saved by the final snapshot
+	source.revision.Store(2)
+	cancel()
+	<-done
+
+	state, err := store.Latest()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), state.Revision)
+}
+
+type fakeCompactor struct {
+	mu        sync.Mutex
+	revisions []uint64
+}
+
+func (f *fakeCompactor) Compact(revision uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revisions = append(f.revisions, revision)
+	return nil
+}
+
+func TestStore_RunCompactsToTheOldestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir, 2)
+	require.NoError(t, err)
+
+	source := &fakeSource{}
+	compactor := &fakeCompactor{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.Run(ctx, source, 5*time.Millisecond, logger.New(), compactor)
+		close(done)
+	}()
+	// Run takes the revision it starts from as saved
+	time.Sleep(20 * time.Millisecond)
+	for revision := uint64(1); revision <= 3; revision++ {
+		source.revision.Store(revision)
+		require.Eventually(t, func() bool {
+			state, err := store.Latest()
+			return err == nil && state != nil && state.Revision == revision
+		}, time.Second, 5*time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// the log keeps what the older snapshot kept lacks, in case the newest is damaged
+	compactor.mu.Lock()
+	defer compactor.mu.Unlock()
+	assert.Equal(t, []uint64{1, 1, 2}, compactor.revisions)
+}
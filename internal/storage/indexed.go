@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"matching_system/internal/models"
+	"sort"
+)
+
+// indexKey is a person's position in a gender index. Height is copied so an index stays ordered
+// even when a stored person's height is changed in place before it is put again.
+type indexKey struct {
+	height int
+	id     string
+	person *models.Person
+}
+
+func (k indexKey) less(height int, id string) bool {
+	if k.height != height {
+		return k.height < height
+	}
+	return k.id < id
+}
+
+type indexed struct {
+	gender string
+	key    indexKey
+}
+
+// IndexedStore keeps, per gender, people sorted by height and ID next to the map by ID, so Range
+// and TopN only visit the people they return.
+type IndexedStore struct {
+	people  map[string]indexed
+	genders map[string][]indexKey
+}
+
+func NewIndexedStore() *IndexedStore {
+	return &IndexedStore{
+		people:  make(map[string]indexed),
+		genders: make(map[string][]indexKey),
+	}
+}
+
+func (s *IndexedStore) Get(id string) (*models.Person, bool) {
+	entry, ok := s.people[id]
+	return entry.key.person, ok
+}
+
+func (s *IndexedStore) Put(person *models.Person) {
+	s.Delete(person.ID)
+
+	key := indexKey{height: person.Height, id: person.ID, person: person}
+	index := s.genders[person.Gender]
+	i := search(index, key.height, key.id)
+	index = append(index, indexKey{})
+	copy(index[i+1:], index[i:])
+	index[i] = key
+
+	s.genders[person.Gender] = index
+	s.people[person.ID] = indexed{gender: person.Gender, key: key}
+}
+
+func (s *IndexedStore) Delete(id string) {
+	entry, ok := s.people[id]
+	if !ok {
+		return
+	}
+	delete(s.people, id)
+
+	index := s.genders[entry.gender]
+	i := search(index, entry.key.height, id)
+	if len(index) == 1 {
+		delete(s.genders, entry.gender)
+		return
+	}
+	copy(index[i:], index[i+1:])
+	index[len(index)-1] = indexKey{}
+	s.genders[entry.gender] = index[:len(index)-1]
+}
+
+func (s *IndexedStore) Len() int {
+	return len(s.people)
+}
+
+func (s *IndexedStore) Each(fn func(*models.Person) bool) {
+	for _, entry := range s.people {
+		if !fn(entry.key.person) {
+			return
+		}
+	}
+}
+
+func (s *IndexedStore) Range(r HeightRange, fn func(*models.Person) bool) {
+	index := s.genders[r.Gender]
+	// [lo, hi) are the people between Min and Max
+	lo := sort.Search(len(index), func(i int) bool { return index[i].height >= r.Min })
+	hi := sort.Search(len(index), func(i int) bool { return index[i].height > r.Max })
+	if hi < lo {
+		// Min is above Max
+		return
+	}
+
+	if !r.Descending {
+		for _, key := range index[lo:hi] {
+			if !fn(key.person) {
+				return
+			}
+		}
+		return
+	}
+
+	// walk the heights down, but each height's people still by ID
+	for hi > lo {
+		height := index[hi-1].height
+		start := sort.Search(hi-lo, func(i int) bool { return index[lo+i].height >= height }) + lo
+		for _, key := range index[start:hi] {
+			if !fn(key.person) {
+				return
+			}
+		}
+		hi = start
+	}
+}
+
+func (s *IndexedStore) TopN(r HeightRange, n int) []*models.Person {
+	return topN(s, r, n)
+}
+
+// search returns where height and id are, or would be inserted, in index
+func search(index []indexKey, height int, id string) int {
+	return sort.Search(len(index), func(i int) bool { return !index[i].less(height, id) })
+}
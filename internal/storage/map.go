@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"matching_system/internal/models"
+	"sort"
+)
+
+// MapStore keeps people in a map keyed by ID. Range scans and sorts the whole pool, which is fine
+// for small pools and is the simplest reference for other implementations.
+type MapStore struct {
+	people map[string]*models.Person
+}
+
+func NewMapStore() *MapStore {
+	return &MapStore{people: make(map[string]*models.Person)}
+}
+
+func (s *MapStore) Get(id string) (*models.Person, bool) {
+	person, ok := s.people[id]
+	return person, ok
+}
+
+func (s *MapStore) Put(person *models.Person) {
+	s.people[person.ID] = person
+}
+
+func (s *MapStore) Delete(id string) {
+	delete(s.people, id)
+}
+
+func (s *MapStore) Len() int {
+	return len(s.people)
+}
+
+func (s *MapStore) Each(fn func(*models.Person) bool) {
+	for _, person := range s.people {
+		if !fn(person) {
+			return
+		}
+	}
+}
+
+func (s *MapStore) Range(r HeightRange, fn func(*models.Person) bool) {
+	var people []*models.Person
+	for _, person := range s.people {
+		if r.contains(person) {
+			people = append(people, person)
+		}
+	}
+
+	sort.Slice(people, func(i, j int) bool {
+		if people[i].Height != people[j].Height {
+			return (people[i].Height < people[j].Height) != r.Descending
+		}
+		return people[i].ID < people[j].ID
+	})
+
+	for _, person := range people {
+		if !fn(person) {
+			return
+		}
+	}
+}
+
+func (s *MapStore) TopN(r HeightRange, n int) []*models.Person {
+	return topN(s, r, n)
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"slices"
+	"sort"
+)
+
+// chunkSize is how many elements a chunk of a Sorted holds before it is split
+const chunkSize = 128
+
+// Sorted is an immutable sequence kept in the order of less, which must be a strict total order.
+// Apply returns a changed copy that shares every chunk it did not touch with the original, so a new
+// version costs about as much as the change rather than the whole sequence. Sorted is safe for
+// concurrent readers.
+type Sorted[T any] struct {
+	less   func(a, b T) bool
+	chunks [][]T
+	length int
+}
+
+// NewSorted returns the sequence of items, which are copied and sorted
+func NewSorted[T any](less func(a, b T) bool, items ...T) *Sorted[T] {
+	sorted := append([]T(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+
+	s := &Sorted[T]{less: less, length: len(sorted)}
+	for len(sorted) > 0 {
+		n := min(len(sorted), chunkSize)
+		s.chunks = append(s.chunks, sorted[:n:n])
+		sorted = sorted[n:]
+	}
+	return s
+}
+
+func (s *Sorted[T]) Len() int {
+	return s.length
+}
+
+// Each calls fn for every element, in order, until fn returns false
+func (s *Sorted[T]) Each(fn func(T) bool) {
+	for _, chunk := range s.chunks {
+		for _, item := range chunk {
+			if !fn(item) {
+				return
+			}
+		}
+	}
+}
+
+// Ascend calls fn for the elements from the first one not less than pivot, in order, until fn
+// returns false
+func (s *Sorted[T]) Ascend(pivot T, fn func(T) bool) {
+	c := s.chunkFor(pivot)
+	if c == len(s.chunks) {
+		return
+	}
+	i := s.search(s.chunks[c], pivot)
+	for ; c < len(s.chunks); c++ {
+		for _, item := range s.chunks[c][i:] {
+			if !fn(item) {
+				return
+			}
+		}
+		i = 0
+	}
+}
+
+// Get returns the element equal to item in the order of less
+func (s *Sorted[T]) Get(item T) (T, bool) {
+	var found T
+	ok := false
+	s.Ascend(item, func(candidate T) bool {
+		found, ok = candidate, !s.less(item, candidate)
+		return false
+	})
+	return found, ok
+}
+
+// Apply returns the sequence without the elements equal to those in remove, and with add inserted.
+// Removing an element that is not there does nothing.
+func (s *Sorted[T]) Apply(remove, add []T) *Sorted[T] {
+	if len(remove) == 0 && len(add) == 0 {
+		return s
+	}
+	changed := &Sorted[T]{less: s.less, chunks: append([][]T(nil), s.chunks...), length: s.length}
+	// chunks copied by this Apply belong to it and are changed in place
+	owned := make(map[*T]bool)
+	own := func(c int) []T {
+		chunk := changed.chunks[c]
+		if !owned[&chunk[0]] {
+			chunk = append(make([]T, 0, len(chunk)+1), chunk...)
+			changed.chunks[c] = chunk
+			owned[&chunk[0]] = true
+		}
+		return chunk
+	}
+
+	for _, item := range remove {
+		c := changed.chunkFor(item)
+		if c == len(changed.chunks) {
+			continue
+		}
+		i := changed.search(changed.chunks[c], item)
+		if i == len(changed.chunks[c]) || s.less(item, changed.chunks[c][i]) {
+			continue
+		}
+		if len(changed.chunks[c]) == 1 {
+			changed.chunks = slices.Delete(changed.chunks, c, c+1)
+		} else {
+			changed.chunks[c] = slices.Delete(own(c), i, i+1)
+			// merge a chunk that shrank into the next one, so removals do not leave many tiny chunks
+			if c+1 < len(changed.chunks) && len(changed.chunks[c]) < chunkSize/4 && len(changed.chunks[c])+len(changed.chunks[c+1]) <= chunkSize {
+				merged := append(changed.chunks[c], changed.chunks[c+1]...)
+				owned[&merged[0]] = true
+				changed.chunks[c] = merged
+				changed.chunks = slices.Delete(changed.chunks, c+1, c+2)
+			}
+		}
+		changed.length--
+	}
+
+	for _, item := range add {
+		if len(changed.chunks) == 0 {
+			changed.chunks = [][]T{{item}}
+			changed.length++
+			continue
+		}
+		// past the last chunk the item goes to the end of it
+		c := min(changed.chunkFor(item), len(changed.chunks)-1)
+		chunk := own(c)
+		chunk = slices.Insert(chunk, changed.search(chunk, item), item)
+		owned[&chunk[0]] = true
+		changed.chunks[c] = chunk
+		changed.length++
+
+		if len(chunk) > chunkSize {
+			// the second half gets an array of its own, the first keeps the owned one
+			half := len(chunk) / 2
+			second := slices.Clone(chunk[half:])
+			changed.chunks[c] = chunk[:half]
+			changed.chunks = slices.Insert(changed.chunks, c+1, second)
+			owned[&second[0]] = true
+		}
+	}
+	return changed
+}
+
+// chunkFor returns the first chunk whose last element is not less than item, len(s.chunks) when
+// item is after every element
+func (s *Sorted[T]) chunkFor(item T) int {
+	return sort.Search(len(s.chunks), func(c int) bool {
+		chunk := s.chunks[c]
+		return !s.less(chunk[len(chunk)-1], item)
+	})
+}
+
+// search returns the first index in chunk whose element is not less than item
+func (s *Sorted[T]) search(chunk []T, item T) int {
+	return sort.Search(len(chunk), func(i int) bool { return !s.less(chunk[i], item) })
+}
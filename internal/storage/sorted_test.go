@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intLess(a, b int) bool { return a < b }
+
+func items(s *Sorted[int]) []int {
+	result := []int{}
+	s.Each(func(item int) bool {
+		result = append(result, item)
+		return true
+	})
+	return result
+}
+
+func TestSorted(t *testing.T) {
+	s := NewSorted(intLess, 5, 1, 3)
+	assert.Equal(t, []int{1, 3, 5}, items(s))
+
+	changed := s.Apply([]int{3, 4}, []int{2, 6})
+	assert.Equal(t, []int{1, 2, 5, 6}, items(changed))
+	assert.Equal(t, 4, changed.Len())
+	assert.Equal(t, []int{1, 3, 5}, items(s), "the original must not change")
+
+	var from []int
+	changed.Ascend(3, func(item int) bool {
+		from = append(from, item)
+		return true
+	})
+	assert.Equal(t, []int{5, 6}, from)
+
+	found, ok := changed.Get(5)
+	assert.True(t, ok)
+	assert.Equal(t, 5, found)
+	_, ok = changed.Get(3)
+	assert.False(t, ok)
+}
+
+func TestSorted_MatchesSortedSlice(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var (
+		want     []int
+		versions []*Sorted[int]
+		wants    [][]int
+	)
+	s := NewSorted(intLess)
+
+	for round := 0; round < 300; round++ {
+		// mostly inserts at first, then mostly removals, so chunks split and merge
+		var remove, add []int
+		for i := rng.Intn(40); i > 0; i-- {
+			if rng.Intn(300) < round {
+				if len(want) > 0 {
+					remove = append(remove, want[rng.Intn(len(want))])
+				}
+			} else if item := rng.Intn(100000); !slices.Contains(want, item) && !slices.Contains(add, item) {
+				// duplicates would not be in a strict order
+				add = append(add, item)
+			}
+		}
+
+		s = s.Apply(remove, add)
+		for _, item := range remove {
+			if i, ok := slices.BinarySearch(want, item); ok {
+				want = slices.Delete(want, i, i+1)
+			}
+		}
+		for _, item := range add {
+			i, _ := slices.BinarySearch(want, item)
+			want = slices.Insert(want, i, item)
+		}
+
+		require.Equal(t, want, items(s), "round %d", round)
+		require.Equal(t, len(want), s.Len())
+		versions = append(versions, s)
+		wants = append(wants, slices.Clone(want))
+	}
+
+	// every version still holds what it held when it was made
+	for i, version := range versions {
+		assert.Equal(t, wants[i], items(version), "version %d", i)
+	}
+}
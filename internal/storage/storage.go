@@ -0,0 +1,46 @@
+package storage
+
+import "matching_system/internal/models"
+
+// HeightRange selects the people of one gender whose height lies between Min and Max, inclusive
+type HeightRange struct {
+	Gender string
+	Min    int
+	Max    int
+	// Descending visits the tallest people first; people of equal height are always visited by ID
+	Descending bool
+}
+
+// PeopleStore holds the active people. Implementations are not safe for concurrent use, the match
+// service serializes access to them.
+//
+// Stored people are returned by pointer, so matching can count down WantedDates and bump Version in
+// place. Any other change, in particular to Gender or Height, must be followed by Put so indexes
+// stay correct.
+type PeopleStore interface {
+	Get(id string) (*models.Person, bool)
+	// Put adds person or replaces the stored person with the same ID
+	Put(person *models.Person)
+	Delete(id string)
+	Len() int
+	// Each calls fn for every person, in no particular order, until fn returns false
+	Each(fn func(*models.Person) bool)
+	// Range calls fn for the people in r, in height order, until fn returns false
+	Range(r HeightRange, fn func(*models.Person) bool)
+	// TopN returns the first n people Range would visit, or all of them when n <= 0
+	TopN(r HeightRange, n int) []*models.Person
+}
+
+func (r HeightRange) contains(person *models.Person) bool {
+	return person.Gender == r.Gender && person.Height >= r.Min && person.Height <= r.Max
+}
+
+// topN collects from Range, for stores whose Range is already ordered
+func topN(store PeopleStore, r HeightRange, n int) []*models.Person {
+	var people []*models.Person
+	store.Range(r, func(person *models.Person) bool {
+		people = append(people, person)
+		return n <= 0 || len(people) < n
+	})
+	return people
+}
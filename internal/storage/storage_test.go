@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"fmt"
+	"matching_system/internal/models"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var stores = map[string]func() PeopleStore{
+	"map":     func() PeopleStore { return NewMapStore() },
+	"indexed": func() PeopleStore { return NewIndexedStore() },
+}
+
+func names(people []*models.Person) []string {
+	result := make([]string, 0, len(people))
+	for _, person := range people {
+		result = append(result, person.Name)
+	}
+	return result
+}
+
+func TestPeopleStore(t *testing.T) {
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			for _, person := range []models.Person{
+				{ID: "4", Name: "Alice", Height: 160, Gender: "female"},
+				{ID: "2", Name: "Carol", Height: 170, Gender: "female"},
+				{ID: "1", Name: "Eve", Height: 160, Gender: "female"},
+				{ID: "3", Name: "Bob", Height: 180, Gender: "male"},
+				{ID: "5", Name: "David", Height: 175, Gender: "male"},
+			} {
+				person := person
+				store.Put(&person)
+			}
+			assert.Equal(t, 5, store.Len())
+
+			women := HeightRange{Gender: "female", Min: 0, Max: math.MaxInt}
+			assert.Equal(t, []string{"Eve", "Alice", "Carol"}, names(store.TopN(women, 0)), "ascending, equal heights by ID")
+			women.Descending = true
+			assert.Equal(t, []string{"Carol", "Eve", "Alice"}, names(store.TopN(women, 0)), "descending, equal heights by ID")
+			assert.Equal(t, []string{"Carol", "Eve"}, names(store.TopN(women, 2)))
+
+			men := HeightRange{Gender: "male", Min: 176, Max: math.MaxInt, Descending: true}
+			assert.Equal(t, []string{"Bob"}, names(store.TopN(men, 0)))
+			assert.Empty(t, store.TopN(HeightRange{Gender: "other", Max: math.MaxInt}, 0))
+
+			// changing height in place and putting again moves the person
+			bob, ok := store.Get("3")
+			require.True(t, ok)
+			bob.Height = 150
+			store.Put(bob)
+			assert.Empty(t, store.TopN(men, 0))
+			assert.Equal(t, 5, store.Len())
+
+			store.Delete("1")
+			store.Delete("missing")
+			_, ok = store.Get("1")
+			assert.False(t, ok)
+			assert.Equal(t, []string{"Carol", "Alice"}, names(store.TopN(women, 0)))
+
+			seen := 0
+			store.Each(func(*models.Person) bool {
+				seen++
+				return true
+			})
+			assert.Equal(t, 4, seen)
+		})
+	}
+}
+
+// TestIndexedStore_MatchesMapStore checks the index against the map's scan-and-sort on random pools
+func TestIndexedStore_MatchesMapStore(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	reference, indexed := NewMapStore(), NewIndexedStore()
+	genders := []string{"male", "female"}
+
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("%03d", rnd.Intn(300))
+		if rnd.Intn(4) == 0 {
+			reference.Delete(id)
+			indexed.Delete(id)
+			continue
+		}
+		person := models.Person{ID: id, Gender: genders[rnd.Intn(2)], Height: 150 + rnd.Intn(40)}
+		a, b := person, person
+		reference.Put(&a)
+		indexed.Put(&b)
+
+		r := HeightRange{Gender: genders[rnd.Intn(2)], Min: 150 + rnd.Intn(40), Max: 150 + rnd.Intn(40), Descending: rnd.Intn(2) == 0}
+		n := rnd.Intn(5)
+		require.Equal(t, ids(reference.TopN(r, n)), ids(indexed.TopN(r, n)), "range %+v, n %d", r, n)
+	}
+	assert.Equal(t, reference.Len(), indexed.Len())
+}
+
+func ids(people []*models.Person) []string {
+	result := make([]string, 0, len(people))
+	for _, person := range people {
+		result = append(result, person.ID)
+	}
+	return result
+}
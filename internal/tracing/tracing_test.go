@@ -0,0 +1,135 @@
+package tracing_test
+
+import (
+	"context"
+	"matching_system/internal/api/routes"
+	"matching_system/internal/events"
+	"matching_system/internal/pools"
+	"matching_system/internal/services"
+	"matching_system/internal/tracing"
+	"matching_system/internal/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	installTracing sync.Once
+	spans          = tracetest.NewInMemoryExporter()
+)
+
+// recordSpans has the spans ended from now on recorded by spans, see services' tests
+func recordSpans(t *testing.T) {
+	installTracing.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans)))
+	})
+	spans.Reset()
+	t.Cleanup(spans.Reset)
+}
+
+func setupRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	broker := events.NewBroker(events.DefaultHistorySize)
+	registry, err := pools.NewRegistry(pools.NewMemoryBackend(services.WithEvents(broker)))
+	require.NoError(t, err)
+	_, err = registry.Create("eu", services.DefaultRules)
+	require.NoError(t, err)
+	return routes.Setup(registry, broker, webhooks.NewStore(), nil, nil, nil, nil, nil)
+}
+
+func serve(router *gin.Engine, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// children returns the names of the spans whose parent is parent
+func children(stubs tracetest.SpanStubs, parent tracetest.SpanStub) []string {
+	var names []string
+	for _, stub := range stubs {
+		if stub.Parent.SpanID() == parent.SpanContext.SpanID() {
+			names = append(names, stub.Name)
+		}
+	}
+	return names
+}
+
+func spanNamed(t *testing.T, stubs tracetest.SpanStubs, name string) tracetest.SpanStub {
+	for _, stub := range stubs {
+		if stub.Name == name {
+			return stub
+		}
+	}
+	require.Failf(t, "span not found", "no span named %s", name)
+	return tracetest.SpanStub{}
+}
+
+func TestMiddleware_TracesRequestsThroughTheServices(t *testing.T) {
+	recordSpans(t)
+	router := setupRouter(t)
+	serve(router, "POST", "/v1/pools/eu/people", `{"name":"Alice","height":160,"gender":"female","wanted_dates":1}`, nil)
+	spans.Reset()
+
+	// the caller's trace continues here
+	header := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	w := serve(router, "POST", "/v1/pools/eu/people", `{"name":"Bob","height":180,"gender":"male","wanted_dates":1}`, header)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	stubs := spans.GetSpans()
+	request := spanNamed(t, stubs, "POST /v1/pools/:pool/people")
+	assert.Equal(t, trace.SpanKindServer, request.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", request.Parent.SpanID().String())
+	assert.True(t, request.Parent.IsRemote())
+	for _, stub := range stubs {
+		assert.Equal(t, request.SpanContext.TraceID(), stub.SpanContext.TraceID(), "%s should be part of the trace", stub.Name)
+	}
+
+	assert.Equal(t, []string{"MatchService.AddPerson"}, children(stubs, request))
+	add := spanNamed(t, stubs, "MatchService.AddPerson")
+	assert.Equal(t, []string{"match.find_candidates", "match.pair", "match.commit"}, children(stubs, add))
+}
+
+func TestMiddleware_StartsTraces(t *testing.T) {
+	recordSpans(t)
+	router := setupRouter(t)
+
+	w := serve(router, "POST", "/v1/people", `{"name":"Bob"}`, nil)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	w = serve(router, "GET", "/v1/nowhere", "", nil)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	stubs := spans.GetSpans()
+	require.Len(t, stubs, 2)
+	invalid := spanNamed(t, stubs, "POST /v1/people")
+	assert.False(t, invalid.Parent.IsValid(), "without a traceparent header the request starts a trace")
+	assert.Equal(t, codes.Unset, invalid.Status.Code, "client errors do not fail the span")
+	require.Len(t, invalid.Events, 1)
+	assert.Equal(t, "exception", invalid.Events[0].Name)
+	assert.Equal(t, "GET", stubs[1].Name, "unknown routes are named by their method only")
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := tracing.Setup("")
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = tracing.Setup("zipkin")
+	assert.Error(t, err)
+}
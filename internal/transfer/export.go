@@ -0,0 +1,82 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"matching_system/internal/models"
+	"strconv"
+	"time"
+)
+
+// personColumns is the CSV header of people, and of each side of a match prefixed with person1_ and person2_
+var personColumns = []string{"id", "name", "height", "gender", "wanted_dates", "version"}
+
+func personRow(person models.Person) []string {
+	return []string{
+		person.ID,
+		person.Name,
+		strconv.Itoa(person.Height),
+		person.Gender,
+		strconv.Itoa(person.WantedDates),
+		strconv.FormatInt(person.Version, 10),
+	}
+}
+
+// WritePeople writes people in format, one per line or row
+func WritePeople(w io.Writer, format Format, people []models.Person) error {
+	if format == CSV {
+		rows := make([][]string, 0, len(people))
+		for _, person := range people {
+			rows = append(rows, personRow(person))
+		}
+		return writeCSV(w, personColumns, rows)
+	}
+	return writeJSONL(w, len(people), func(i int) interface{} { return people[i] })
+}
+
+// WriteMatches writes the match history in format. CSV flattens both people of a match into one row.
+func WriteMatches(w io.Writer, format Format, matches []models.Match) error {
+	if format == CSV {
+		header := []string{"matched_at"}
+		for _, prefix := range []string{"person1_", "person2_"} {
+			for _, column := range personColumns {
+				header = append(header, prefix+column)
+			}
+		}
+
+		rows := make([][]string, 0, len(matches))
+		for _, match := range matches {
+			row := []string{match.MatchedAt.Format(time.RFC3339Nano)}
+			row = append(row, personRow(match.Person1)...)
+			row = append(row, personRow(match.Person2)...)
+			rows = append(rows, row)
+		}
+		return writeCSV(w, header, rows)
+	}
+	return writeJSONL(w, len(matches), func(i int) interface{} { return matches[i] })
+}
+
+func writeCSV(w io.Writer, header []string, rows [][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+func writeJSONL(w io.Writer, n int, item func(i int) interface{}) error {
+	writer := bufio.NewWriter(w)
+	// Encode ends every value with a newline
+	encoder := json.NewEncoder(writer)
+	for i := 0; i < n; i++ {
+		if err := encoder.Encode(item(i)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,157 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/services"
+	"strconv"
+	"strings"
+)
+
+// maxLineSize bounds a single JSONL line, far above any real person
+const maxLineSize = 64 * 1024
+
+// ReadPeople decodes the people of an import file, remembering the line each came from. It only
+// checks that the file is well formed; the service validates the people themselves.
+func ReadPeople(r io.Reader, format Format) ([]dto.ImportPerson, error) {
+	if format == CSV {
+		return readCSV(r)
+	}
+	return readJSONL(r)
+}
+
+func readJSONL(r io.Reader) ([]dto.ImportPerson, error) {
+	var people []dto.ImportPerson
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		var person dto.ImportPerson
+		if err := decoder.Decode(&person); err != nil {
+			return nil, malformed(line, err.Error())
+		}
+		if decoder.More() {
+			return nil, malformed(line, "expected one JSON object per line")
+		}
+		person.Line = line
+		people = append(people, person)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, services.NewMalformedRequestError(fmt.Sprintf("line longer than %d bytes", maxLineSize))
+		}
+		return nil, err
+	}
+	return people, nil
+}
+
+func readCSV(r io.Reader) ([]dto.ImportPerson, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns, err := columnIndexes(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var people []dto.ImportPerson
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return people, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		person := dto.ImportPerson{
+			Line:   line,
+			ID:     field("id"),
+			Name:   field("name"),
+			Gender: field("gender"),
+		}
+		// empty numbers are left zero, for validation to report as missing
+		numbers := []struct {
+			column string
+			target *int
+		}{{"height", &person.Height}, {"wanted_dates", &person.WantedDates}}
+		for _, number := range numbers {
+			if value := field(number.column); value != "" {
+				if *number.target, err = strconv.Atoi(value); err != nil {
+					return nil, malformed(line, number.column+" must be an integer")
+				}
+			}
+		}
+		if value := field("version"); value != "" {
+			if person.Version, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return nil, malformed(line, "version must be an integer")
+			}
+		}
+		people = append(people, person)
+	}
+}
+
+// columnIndexes maps the header onto person columns. id and version are optional, as for JSONL.
+func columnIndexes(header []string) (map[string]int, error) {
+	known := make(map[string]bool, len(personColumns))
+	for _, column := range personColumns {
+		known[column] = true
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !known[name] {
+			return nil, malformed(1, "unknown column "+strconv.Quote(name))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, malformed(1, "duplicate column "+strconv.Quote(name))
+		}
+		columns[name] = i
+	}
+	for _, name := range []string{"name", "height", "gender", "wanted_dates"} {
+		if _, ok := columns[name]; !ok {
+			return nil, malformed(1, "missing column "+strconv.Quote(name))
+		}
+	}
+	return columns, nil
+}
+
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return malformed(parseErr.Line, parseErr.Err.Error())
+	}
+	return err
+}
+
+func malformed(line int, message string) error {
+	return services.NewMalformedRequestError(fmt.Sprintf("line %d: %s", line, message))
+}
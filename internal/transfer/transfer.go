@@ -0,0 +1,34 @@
+package transfer
+
+import (
+	"matching_system/internal/services"
+	"strings"
+)
+
+// Format is the file format of an export or import
+type Format string
+
+const (
+	// JSONL is one JSON object per line
+	JSONL Format = "jsonl"
+	// CSV has a header row naming the columns
+	CSV Format = "csv"
+)
+
+// ParseFormat accepts jsonl or csv, defaulting to JSONL when name is empty
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case "", JSONL:
+		return JSONL, nil
+	case CSV:
+		return CSV, nil
+	}
+	return "", services.NewValidationError(services.FieldError{Field: "format", Message: "must be one of: jsonl, csv"})
+}
+
+func (f Format) ContentType() string {
+	if f == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
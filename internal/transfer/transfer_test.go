@@ -0,0 +1,99 @@
+package transfer
+
+import (
+	"bytes"
+	"matching_system/internal/api/dto"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPeople = []models.Person{
+	{ID: "1", Name: "Alice", Height: 160, Gender: "female", WantedDates: 2, Version: 3},
+	{ID: "2", Name: "Bob, Jr.", Height: 180, Gender: "male", WantedDates: 1, Version: 1},
+}
+
+func TestPeople_RoundTrip(t *testing.T) {
+	for _, format := range []Format{JSONL, CSV} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, WritePeople(&buf, format, testPeople))
+
+			people, err := ReadPeople(&buf, format)
+			require.NoError(t, err)
+			require.Len(t, people, 2)
+			line := 2
+			if format == CSV {
+				// the header is line 1
+				line = 3
+			}
+			assert.Equal(t, dto.ImportPerson{Line: line, ID: "2", Name: "Bob, Jr.", Height: 180, Gender: "male", WantedDates: 1, Version: 1}, people[1])
+		})
+	}
+}
+
+func TestWriteMatches_CSV(t *testing.T) {
+	match := models.Match{Person1: testPeople[1], Person2: testPeople[0], MatchedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteMatches(&buf, CSV, []models.Match{match}))
+	assert.Equal(t, "matched_at,"+
+		"person1_id,person1_name,person1_height,person1_gender,person1_wanted_dates,person1_version,"+
+		"person2_id,person2_name,person2_height,person2_gender,person2_wanted_dates,person2_version\n"+
+		"2024-01-02T03:04:05Z,2,\"Bob, Jr.\",180,male,1,1,1,Alice,160,female,2,3\n", buf.String())
+}
+
+func TestReadPeople_OptionalColumns(t *testing.T) {
+	people, err := ReadPeople(strings.NewReader("Name,Gender,Height,Wanted_Dates\nAlice,female,160,\n"), CSV)
+	require.NoError(t, err)
+	assert.Equal(t, []dto.ImportPerson{{Line: 2, Name: "Alice", Gender: "female", Height: 160}}, people,
+		"an empty number is left for validation to report")
+
+	people, err = ReadPeople(strings.NewReader("\n{\"name\":\"Bob\",\"height\":180}\n\n"), JSONL)
+	require.NoError(t, err)
+	assert.Equal(t, []dto.ImportPerson{{Line: 2, Name: "Bob", Height: 180}}, people)
+}
+
+func TestReadPeople_Malformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+		want   string
+	}{
+		{"invalid JSON", JSONL, "{\"name\":\"Alice\"}\n{\"name\":", "line 2: unexpected EOF"},
+		{"unknown JSON field", JSONL, "{\"name\":\"Alice\",\"age\":30}", "line 1: json: unknown field \"age\""},
+		{"two JSON objects on a line", JSONL, "{} {}", "line 1: expected one JSON object per line"},
+		{"unknown column", CSV, "name,height,gender,wanted_dates,age\n", "line 1: unknown column \"age\""},
+		{"missing column", CSV, "name,height,gender\n", "line 1: missing column \"wanted_dates\""},
+		{"not a number", CSV, "name,height,gender,wanted_dates\nAlice,160,female,1\nBob,tall,male,1\n", "line 3: height must be an integer"},
+		{"wrong number of fields", CSV, "name,height,gender,wanted_dates\nAlice,160\n", "line 2: wrong number of fields"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadPeople(strings.NewReader(tt.input), tt.format)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, &services.Error{Code: services.CodeMalformedRequest})
+			assert.Equal(t, tt.want, err.Error())
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, JSONL, format)
+
+	format, err = ParseFormat("CSV")
+	require.NoError(t, err)
+	assert.Equal(t, CSV, format)
+
+	_, err = ParseFormat("xml")
+	assert.ErrorIs(t, err, &services.Error{Code: services.CodeValidationFailed})
+}
@@ -0,0 +1,273 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"matching_system/internal/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Op is the kind of mutating operation a record describes
+type Op string
+
+const (
+	OpAdd    Op = "add"
+	OpUpdate Op = "update"
+	OpRemove Op = "remove"
+	// OpImport adds a person without matching them
+	OpImport Op = "import"
+)
+
+// Record is one mutating operation, logged before it is applied. Replaying the records in order
+// on top of the state they were logged against reproduces the same pool and match history.
+type Record struct {
+	// Revision is the service revision the operation was applied to
+	Revision uint64    `json:"revision"`
+	Op       Op        `json:"op"`
+	At       time.Time `json:"at"`
+	// Person holds the assigned ID and the new attributes for add, import and update, only the ID for remove
+	Person models.Person `json:"person"`
+}
+
+// frameHeaderSize is the length and CRC-32C that precede every record
+const frameHeaderSize = 8
+
+// maxRecordSize guards against reading a garbage length as a huge allocation
+const maxRecordSize = 1 << 20
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Log is an append-only file of records. Every append is fsynced before it returns.
+type Log struct {
+	mu        sync.Mutex
+	path      string
+	file      *os.File
+	truncated int64
+}
+
+// Open reads every intact record of the log at path, creating it if needed, and opens it for
+// appending. A torn or corrupt tail, as left by a crash mid-write, is cut off so later appends
+// stay readable; Truncated reports how many bytes were dropped.
+func Open(path string) (*Log, []Record, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, fmt.Errorf("create log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open log: %w", err)
+	}
+	defer file.Close()
+
+	records, valid, err := read(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat log: %w", err)
+	}
+	truncated := info.Size() - valid
+	if truncated > 0 {
+		if err := file.Truncate(valid); err != nil {
+			return nil, nil, fmt.Errorf("truncate log: %w", err)
+		}
+		if err := file.Sync(); err != nil {
+			return nil, nil, fmt.Errorf("sync log: %w", err)
+		}
+	}
+
+	appender, err := openAppend(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Log{path: path, file: appender, truncated: truncated}, records, nil
+}
+
+func openAppend(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open log: %w", err)
+	}
+	return file, nil
+}
+
+// read decodes records until the end of the file or the first damaged frame, returning the
+// records and the offset just past the last intact one
+func read(file io.Reader) ([]Record, int64, error) {
+	var (
+		records []Record
+		valid   int64
+		header  [frameHeaderSize]byte
+	)
+	reader := bufio.NewReader(file)
+	for {
+		if _, err := io.ReadFull(reader, header[:]); err != nil {
+			// EOF is a clean end, a short header a torn write
+			return records, valid, endOfFrames(err)
+		}
+		length := binary.LittleEndian.Uint32(header[0:4])
+		sum := binary.LittleEndian.Uint32(header[4:8])
+		if length == 0 || length > maxRecordSize {
+			return records, valid, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return records, valid, endOfFrames(err)
+		}
+		if crc32.Checksum(payload, crcTable) != sum {
+			return records, valid, nil
+		}
+
+		var record Record
+		if err := json.Unmarshal(payload, &record); err != nil {
+			return records, valid, nil
+		}
+		records = append(records, record)
+		valid += frameHeaderSize + int64(length)
+	}
+}
+
+// endOfFrames tells running out of data, which ends the readable log, from a real read failure
+func endOfFrames(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	}
+	return fmt.Errorf("read log: %w", err)
+}
+
+// Append writes records and fsyncs the log once, so the operations survive a crash once Append
+// returns. Appending several records at a time shares the cost of the fsync between them.
+func (l *Log) Append(records ...Record) error {
+	var frames []byte
+	for _, record := range records {
+		frame, err := encode(record)
+		if err != nil {
+			return err
+		}
+		frames = append(frames, frame...)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(frames); err != nil {
+		return fmt.Errorf("append log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("sync log: %w", err)
+	}
+	return nil
+}
+
+// Compact drops the records a snapshot at revision already contains. The remaining records are
+// written to a new file that atomically replaces the log.
+func (l *Log) Compact(revision uint64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current, err := os.Open(l.path)
+	if err != nil {
+		return fmt.Errorf("open log: %w", err)
+	}
+	records, _, err := read(current)
+	current.Close()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create compacted log: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	for _, record := range records {
+		if record.Revision < revision {
+			continue
+		}
+		frame, err := encode(record)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		writer.Write(frame)
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write compacted log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync compacted log: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close compacted log: %w", err)
+	}
+
+	// the appender is opened first, so once the new file replaces the log it is the one appended
+	// to, even if what follows fails; the old handle still points at the replaced file
+	appender, err := openAppend(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		appender.Close()
+		return fmt.Errorf("replace log: %w", err)
+	}
+	l.file.Close()
+	l.file = appender
+	return syncDir(filepath.Dir(l.path))
+}
+
+// Truncated is how many damaged bytes Open cut off the end of the log
+func (l *Log) Truncated() int64 {
+	return l.truncated
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
+
+func encode(record Record) ([]byte, error) {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("encode log record: %w", err)
+	}
+	if len(payload) > maxRecordSize {
+		return nil, errors.New("log record too large")
+	}
+
+	frame := make([]byte, frameHeaderSize+len(payload))
+	binary.LittleEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[frameHeaderSize:], payload)
+	return frame, nil
+}
+
+// syncDir makes a rename durable by syncing the directory entry
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open log directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync log directory: %w", err)
+	}
+	return nil
+}
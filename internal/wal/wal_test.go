@@ -0,0 +1,160 @@
+package wal
+
+import (
+	"matching_system/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRecord(revision uint64) Record {
+	return Record{
+		Revision: revision,
+		Op:       OpAdd,
+		At:       time.Unix(1700000000+int64(revision), 0).UTC(),
+		Person:   models.Person{ID: "person", Name: "Alice", Height: 160, Gender: "female", WantedDates: 1, Version: 1},
+	}
+}
+
+func revisions(records []Record) []uint64 {
+	result := make([]uint64, 0, len(records))
+	for _, record := range records {
+		result = append(result, record.Revision)
+	}
+	return result
+}
+
+// writeLog appends records to a fresh log and returns its path and size after each record
+func writeLog(t *testing.T, records ...Record) (string, []int64) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	log, existing, err := Open(path)
+	require.NoError(t, err)
+	require.Empty(t, existing)
+
+	var sizes []int64
+	for _, record := range records {
+		require.NoError(t, log.Append(record))
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		sizes = append(sizes, info.Size())
+	}
+	require.NoError(t, log.Close())
+	return path, sizes
+}
+
+func TestLog_AppendAndReopen(t *testing.T) {
+	path, _ := writeLog(t, testRecord(0), testRecord(1), testRecord(3))
+
+	log, records, err := Open(path)
+	require.NoError(t, err)
+	defer log.Close()
+	assert.Equal(t, []Record{testRecord(0), testRecord(1), testRecord(3)}, records)
+	assert.Zero(t, log.Truncated())
+}
+
+func TestLog_AppendSeveral(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wal.log")
+	log, _, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, log.Append(testRecord(0), testRecord(1)))
+	require.NoError(t, log.Append())
+	require.NoError(t, log.Append(testRecord(2)))
+	require.NoError(t, log.Close())
+
+	_, records, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0, 1, 2}, revisions(records))
+}
+
+func TestLog_CrashMidWrite(t *testing.T) {
+	path, sizes := writeLog(t, testRecord(0), testRecord(1), testRecord(2))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// cut the last record short at every possible byte, as a crash during the write would
+	for size := sizes[1]; size < sizes[2]; size++ {
+		require.NoError(t, os.WriteFile(path, data[:size], 0o644))
+
+		log, records, err := Open(path)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1}, revisions(records), "size %d", size)
+		assert.Equal(t, size-sizes[1], log.Truncated())
+
+		// the torn tail is gone, so new records are readable after the old ones
+		require.NoError(t, log.Append(testRecord(5)))
+		require.NoError(t, log.Close())
+		_, records, err = Open(path)
+		require.NoError(t, err)
+		assert.Equal(t, []uint64{0, 1, 5}, revisions(records), "size %d", size)
+	}
+}
+
+func TestLog_CorruptTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt func(data []byte, sizes []int64) []byte
+		want    []uint64
+	}{
+		{
+			name: "flipped payload byte",
+			corrupt: func(data []byte, sizes []int64) []byte {
+				data[sizes[1]+frameHeaderSize+3] ^= 0xff
+				return data
+			},
+			want: []uint64{0, 1},
+		},
+		{
+			name: "garbage after the last record",
+			corrupt: func(data []byte, sizes []int64) []byte {
+				return append(data, 0xde, 0xad, 0xbe, 0xef, 0x01, 0x02, 0x03, 0x04, 0x05)
+			},
+			want: []uint64{0, 1, 2},
+		},
+		{
+			name: "zeroed tail, as left by a preallocated block",
+			corrupt: func(data []byte, sizes []int64) []byte {
+				return append(data, make([]byte, 64)...)
+			},
+			want: []uint64{0, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, sizes := writeLog(t, testRecord(0), testRecord(1), testRecord(2))
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(path, tt.corrupt(data, sizes), 0o644))
+
+			log, records, err := Open(path)
+			require.NoError(t, err)
+			defer log.Close()
+			assert.Equal(t, tt.want, revisions(records))
+			assert.Positive(t, log.Truncated())
+		})
+	}
+}
+
+func TestLog_Compact(t *testing.T) {
+	path, _ := writeLog(t, testRecord(0), testRecord(3), testRecord(7))
+
+	log, _, err := Open(path)
+	require.NoError(t, err)
+	require.NoError(t, log.Compact(3))
+
+	// appends after compaction go to the compacted file
+	require.NoError(t, log.Append(testRecord(9)))
+	require.NoError(t, log.Close())
+
+	_, records, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{3, 7, 9}, revisions(records))
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary files should be left behind")
+}
@@ -0,0 +1,337 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/pkg/logger"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// dispatchBuffer is how many events the dispatcher may fall behind the broker before resubscribing
+	dispatchBuffer = 256
+
+	defaultWorkers      = 4
+	defaultMaxAttempts  = 5
+	defaultBaseBackoff  = time.Second
+	defaultMaxBackoff   = time.Minute
+	defaultTimeout      = 10 * time.Second
+	deliveryContentType = "application/json"
+	deliveryUserAgent   = "matching-system-webhooks/1.0"
+	// drainPollInterval is how often Shutdown checks whether the deliveries are done
+	drainPollInterval = 10 * time.Millisecond
+)
+
+// Dispatcher POSTs the broker's events to every subscription that wants them, retrying failed
+// deliveries with exponential backoff and recording each attempt in the store. A delivery waiting
+// for its retry does not hold up a worker, so failing endpoints do not delay the others.
+type Dispatcher struct {
+	broker      *events.Broker
+	store       *Store
+	client      *http.Client
+	workers     int
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+	logger      *logger.Logger
+	// observeLost is told how many events were lost, see WithLostObserver
+	observeLost func(events int)
+
+	jobs   chan job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// dispatched is the ID of the last event queued for delivery, active counts the deliveries
+	// queued, under way or waiting for a retry, and draining is closed by Shutdown to stop retries
+	dispatched atomic.Uint64
+	active     atomic.Int64
+	draining   chan struct{}
+	drainOnce  sync.Once
+}
+
+type job struct {
+	subscription models.Webhook
+	delivery     *models.WebhookDelivery
+	body         []byte
+	// attempts made so far
+	attempts int
+}
+
+// DispatcherOption configures a Dispatcher
+type DispatcherOption func(*Dispatcher)
+
+// WithHTTPClient sends deliveries with client instead of a default client with a 10s timeout
+func WithHTTPClient(client *http.Client) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithRetry sets how many times a delivery is attempted and how the wait between attempts grows
+func WithRetry(maxAttempts int, baseBackoff, maxBackoff time.Duration) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.baseBackoff = baseBackoff
+		d.maxBackoff = maxBackoff
+	}
+}
+
+// WithLogger logs the events lost with l instead of the default logger
+func WithLogger(l *logger.Logger) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.logger = l
+	}
+}
+
+// WithLostObserver has observe told how many events the dispatcher lost whenever it falls so far
+// behind that the broker no longer has them. Those events are never delivered.
+func WithLostObserver(observe func(events int)) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.observeLost = observe
+	}
+}
+
+// WithWorkers sets how many deliveries run concurrently
+func WithWorkers(workers int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.workers = workers
+	}
+}
+
+func NewDispatcher(broker *events.Broker, store *Store, opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		broker:      broker,
+		store:       store,
+		client:      &http.Client{Timeout: defaultTimeout},
+		workers:     defaultWorkers,
+		maxAttempts: defaultMaxAttempts,
+		baseBackoff: defaultBaseBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		logger:      logger.Default(),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.jobs = make(chan job, d.workers)
+	d.draining = make(chan struct{})
+	return d
+}
+
+// Start subscribes to the broker and starts the workers. Events published after Start returns are delivered.
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	sub := d.broker.Subscribe(dispatchBuffer)
+	d.wg.Add(1 + d.workers)
+	go d.consume(ctx, sub)
+	for i := 0; i < d.workers; i++ {
+		go d.work(ctx)
+	}
+}
+
+// Stop abandons queued retries and waits for in-flight requests to return
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// Shutdown delivers the events published so far, each attempted once more at most, and then stops.
+// Deliveries that would need a retry are left pending. If ctx is done first, it stops right away and
+// returns ctx's error.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	last := d.broker.LastID()
+	d.drainOnce.Do(func() { close(d.draining) })
+	defer d.Stop()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for d.dispatched.Load() < last || d.active.Load() > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) consume(ctx context.Context, sub *events.Subscription) {
+	defer d.wg.Done()
+	defer func() { sub.Close() }()
+
+	var lastID uint64
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				// dropped for falling behind, pick up where we left off from the broker's history
+				var missed []events.Event
+				var complete bool
+				sub, missed, complete = d.broker.SubscribeFrom(lastID, dispatchBuffer)
+				if !complete && len(missed) > 0 {
+					d.lost(missed[0].ID - lastID - 1)
+				}
+				for _, event := range missed {
+					lastID = event.ID
+					d.dispatch(ctx, event)
+				}
+				d.dispatched.Store(lastID)
+				continue
+			}
+			lastID = event.ID
+			d.dispatch(ctx, event)
+			d.dispatched.Store(lastID)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// lost reports n events the broker no longer had when the dispatcher caught up
+func (d *Dispatcher) lost(n uint64) {
+	d.logger.Warn("Webhook deliveries fell behind, events were lost", "events", n)
+	if d.observeLost != nil {
+		d.observeLost(int(n))
+	}
+}
+
+// dispatch queues a delivery of event to every subscription that wants it
+func (d *Dispatcher) dispatch(ctx context.Context, event events.Event) {
+	subscriptions := d.store.subscribersOf(event.Type)
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	for _, subscription := range subscriptions {
+		d.active.Add(1)
+		select {
+		case d.jobs <- job{subscription: subscription, delivery: d.store.startDelivery(subscription.ID, event), body: body}:
+		case <-ctx.Done():
+			d.active.Add(-1)
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case j := <-d.jobs:
+			d.deliver(ctx, j)
+			d.active.Add(-1)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// deliver makes the next attempt of j. Until it succeeds, fails permanently or runs out of attempts,
+// it is retried after its backoff, see retry.
+func (d *Dispatcher) deliver(ctx context.Context, j job) {
+	j.attempts++
+	attempt, retryable := d.post(ctx, j, j.attempts)
+
+	switch {
+	case attempt.Error == "":
+		d.store.recordAttempt(j.delivery, attempt, models.DeliverySucceeded)
+		return
+	case !retryable || j.attempts >= d.maxAttempts:
+		d.store.recordAttempt(j.delivery, attempt, models.DeliveryFailed)
+		return
+	}
+	d.store.recordAttempt(j.delivery, attempt, models.DeliveryPending)
+	d.retry(ctx, j)
+}
+
+// retry queues j again once its backoff has passed, unless the dispatcher stops or drains or the
+// subscription is deleted first, which leave the delivery pending. The wait counts as active but
+// holds no worker.
+func (d *Dispatcher) retry(ctx context.Context, j job) {
+	d.active.Add(1)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		timer := time.NewTimer(d.backoff(j.attempts))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			d.active.Add(-1)
+			return
+		case <-d.draining:
+			d.active.Add(-1)
+			return
+		}
+		if !d.store.exists(j.subscription.ID) {
+			d.active.Add(-1)
+			return
+		}
+		select {
+		case d.jobs <- j:
+		case <-ctx.Done():
+			d.active.Add(-1)
+		}
+	}()
+}
+
+// post sends one attempt and reports whether a failure is worth retrying
+func (d *Dispatcher) post(ctx context.Context, j job, number int) (models.WebhookAttempt, bool) {
+	started := time.Now()
+	attempt := models.WebhookAttempt{Number: number, At: started}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.subscription.URL, bytes.NewReader(j.body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, false
+	}
+	timestamp := started.Unix()
+	req.Header.Set("Content-Type", deliveryContentType)
+	req.Header.Set("User-Agent", deliveryUserAgent)
+	req.Header.Set(EventHeader, j.delivery.EventType)
+	req.Header.Set(DeliveryHeader, j.delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(j.subscription.Secret, timestamp, j.body))
+
+	resp, err := d.client.Do(req)
+	attempt.DurationMS = time.Since(started).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, true
+	}
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return attempt, true
+	}
+	attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	// other client errors will not go away by sending the same request again
+	return attempt, resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+}
+
+// backoff is the wait after the given failed attempt: baseBackoff doubled per attempt, up to maxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.baseBackoff
+	for i := 1; i < attempt && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.maxBackoff {
+		wait = d.maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,254 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startDispatcher(t *testing.T, broker *events.Broker, store *Store) {
+	dispatcher := NewDispatcher(broker, store, WithRetry(3, time.Millisecond, 5*time.Millisecond))
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+}
+
+// waitForDelivery polls until the subscription's latest delivery leaves the pending state
+func waitForDelivery(t *testing.T, store *Store, subscriptionID string) models.WebhookDelivery {
+	var latest models.WebhookDelivery
+	require.Eventually(t, func() bool {
+		deliveries, _ := store.Deliveries(subscriptionID)
+		if len(deliveries) == 0 {
+			return false
+		}
+		latest = deliveries[0]
+		return latest.Status != models.DeliveryPending
+	}, time.Second, 5*time.Millisecond)
+	return latest
+}
+
+func TestDispatcher_RetriesAndSigns(t *testing.T) {
+	var calls int32
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// fail twice before accepting
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	broker := events.NewBroker(events.DefaultHistorySize)
+	store := NewStore()
+	subscription := store.Create(server.URL, []string{"matched"}, "top-secret")
+	startDispatcher(t, broker, store)
+
+	broker.Publish(events.Event{Type: events.PersonAdded, PersonID: "1"})
+	broker.Publish(events.Event{Type: events.Matched, PersonID: "2"})
+
+	delivery := waitForDelivery(t, store, subscription.ID)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, uint64(2), delivery.EventID, "person_added was not subscribed to")
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode)
+	assert.NotEmpty(t, delivery.Attempts[0].Error)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[2].StatusCode)
+	assert.Empty(t, delivery.Attempts[2].Error)
+
+	req, body := <-received, <-bodies
+	assert.Equal(t, "matched", req.Header.Get(EventHeader))
+	assert.Equal(t, delivery.ID, req.Header.Get(DeliveryHeader))
+	timestamp, err := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify("top-secret", timestamp, body, req.Header.Get(SignatureHeader)))
+
+	var event events.Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "2", event.PersonID)
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantAttempts int
+	}{
+		{name: "server errors are retried up to the limit", status: http.StatusInternalServerError, wantAttempts: 3},
+		{name: "client errors are not retried", status: http.StatusGone, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			broker := events.NewBroker(events.DefaultHistorySize)
+			store := NewStore()
+			subscription := store.Create(server.URL, []string{"person_removed"}, "secret")
+			startDispatcher(t, broker, store)
+
+			broker.Publish(events.Event{Type: events.PersonRemoved, PersonID: "1"})
+
+			delivery := waitForDelivery(t, store, subscription.ID)
+			assert.Equal(t, models.DeliveryFailed, delivery.Status)
+			assert.Len(t, delivery.Attempts, tt.wantAttempts)
+		})
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(events.NewBroker(1), NewStore(), WithRetry(10, time.Second, 10*time.Second))
+
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 8*time.Second, d.backoff(4))
+	assert.Equal(t, 10*time.Second, d.backoff(5), "capped at the maximum")
+	assert.Equal(t, 10*time.Second, d.backoff(50))
+}
+
+func TestDispatcher_ShutdownDeliversPublishedEvents(t *testing.T) {
+	var delivered int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer server.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	broker := events.NewBroker(events.DefaultHistorySize)
+	store := NewStore()
+	store.Create(server.URL, []string{"matched"}, "secret")
+	retried := store.Create(failing.URL, []string{"matched"}, "secret")
+	// retries are an hour apart, so waiting for them would never end
+	dispatcher := NewDispatcher(broker, store, WithRetry(3, time.Hour, time.Hour))
+	dispatcher.Start()
+
+	for i := 0; i < 10; i++ {
+		broker.Publish(events.Event{Type: events.Matched, PersonID: strconv.Itoa(i)})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dispatcher.Shutdown(ctx))
+	assert.Equal(t, int32(10), atomic.LoadInt32(&delivered), "every event published before Shutdown should be delivered")
+
+	deliveries, _ := store.Deliveries(retried.ID)
+	require.Len(t, deliveries, 10)
+	for _, delivery := range deliveries {
+		assert.Equal(t, models.DeliveryPending, delivery.Status, "a failed delivery is left pending rather than retried")
+		assert.Len(t, delivery.Attempts, 1)
+	}
+}
+
+func TestDispatcher_ShutdownTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	broker := events.NewBroker(events.DefaultHistorySize)
+	store := NewStore()
+	store.Create(server.URL, []string{"matched"}, "secret")
+	dispatcher := NewDispatcher(broker, store)
+	dispatcher.Start()
+
+	broker.Publish(events.Event{Type: events.Matched, PersonID: "1"})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, dispatcher.Shutdown(ctx), context.DeadlineExceeded)
+}
+
+func TestDispatcher_RetriesDoNotHoldWorkers(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	var delivered int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer healthy.Close()
+
+	broker := events.NewBroker(events.DefaultHistorySize)
+	store := NewStore()
+	retried := store.Create(failing.URL, []string{"matched"}, "secret")
+	store.Create(healthy.URL, []string{"matched"}, "secret")
+	// a single worker, which would be stuck for an hour if it waited for the retries itself
+	dispatcher := NewDispatcher(broker, store, WithWorkers(1), WithRetry(3, time.Hour, time.Hour))
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+
+	for i := 0; i < 5; i++ {
+		broker.Publish(events.Event{Type: events.Matched, PersonID: strconv.Itoa(i)})
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&delivered) == 5 }, time.Second, 5*time.Millisecond)
+
+	deliveries, _ := store.Deliveries(retried.ID)
+	require.Len(t, deliveries, 5)
+	for _, delivery := range deliveries {
+		assert.Equal(t, models.DeliveryPending, delivery.Status, "waiting for its retry")
+	}
+}
+
+func TestDispatcher_CountsLostEvents(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var delivered int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer server.Close()
+
+	broker := events.NewBroker(10)
+	store := NewStore()
+	store.Create(server.URL, []string{"matched"}, "secret")
+	var lost int32
+	dispatcher := NewDispatcher(broker, store, WithWorkers(1), WithLostObserver(func(events int) {
+		atomic.AddInt32(&lost, int32(events))
+	}))
+	dispatcher.Start()
+	t.Cleanup(dispatcher.Stop)
+
+	// with the only worker stuck, the dispatcher falls further behind than the broker remembers
+	broker.Publish(events.Event{Type: events.Matched, PersonID: "0"})
+	<-started
+	const published = 2*dispatchBuffer + 10
+	for i := 1; i < published; i++ {
+		broker.Publish(events.Event{Type: events.Matched, PersonID: strconv.Itoa(i)})
+	}
+	close(release)
+
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&delivered)+atomic.LoadInt32(&lost) == published
+	}, 5*time.Second, 5*time.Millisecond, "every event is either delivered or counted as lost")
+	assert.Greater(t, atomic.LoadInt32(&lost), int32(0))
+}
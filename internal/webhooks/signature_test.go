@@ -0,0 +1,20 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"type":"matched"}`)
+	signature := Sign("secret", 1700000000, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, Verify("secret", 1700000000, body, signature))
+
+	assert.False(t, Verify("other", 1700000000, body, signature), "wrong secret")
+	assert.False(t, Verify("secret", 1700000001, body, signature), "replayed with another timestamp")
+	assert.False(t, Verify("secret", 1700000000, []byte(`{"type":"person_added"}`), signature), "tampered body")
+	assert.False(t, Verify("secret", 1700000000, body, ""))
+}
@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"matching_system/internal/events"
+	"matching_system/internal/models"
+	"matching_system/internal/services"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxDeliveriesPerSubscription bounds the delivery log kept for each subscription
+const maxDeliveriesPerSubscription = 100
+
+func wants(webhook *models.Webhook, eventType events.Type) bool {
+	for _, t := range webhook.EventTypes {
+		if t == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store keeps webhook subscriptions and their recent deliveries in memory
+type Store struct {
+	mu            sync.RWMutex
+	subscriptions map[string]*models.Webhook
+	// order keeps subscription IDs in creation order
+	order      []string
+	deliveries map[string][]*models.WebhookDelivery
+	now        func() time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		subscriptions: make(map[string]*models.Webhook),
+		deliveries:    make(map[string][]*models.WebhookDelivery),
+		now:           time.Now,
+	}
+}
+
+// Create registers a subscription and returns it with its generated ID
+func (s *Store) Create(url string, eventTypes []string, secret string) models.Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscription := &models.Webhook{
+		ID:         uuid.New().String(),
+		URL:        url,
+		EventTypes: append([]string(nil), eventTypes...),
+		Secret:     secret,
+		CreatedAt:  s.now(),
+	}
+	s.subscriptions[subscription.ID] = subscription
+	s.order = append(s.order, subscription.ID)
+	return *subscription
+}
+
+// List returns every subscription, oldest first
+func (s *Store) List() []models.Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]models.Webhook, 0, len(s.order))
+	for _, id := range s.order {
+		result = append(result, *s.subscriptions[id])
+	}
+	return result
+}
+
+func (s *Store) Get(id string) (models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscription, exists := s.subscriptions[id]
+	if !exists {
+		return models.Webhook{}, services.ErrWebhookNotFound
+	}
+	return *subscription, nil
+}
+
+// Delete removes a subscription together with its delivery log. Pending retries give up.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.subscriptions[id]; !exists {
+		return services.ErrWebhookNotFound
+	}
+	delete(s.subscriptions, id)
+	delete(s.deliveries, id)
+	for i, existing := range s.order {
+		if existing == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Deliveries returns the recent deliveries of a subscription, newest first
+func (s *Store) Deliveries(id string) ([]models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.subscriptions[id]; !exists {
+		return nil, services.ErrWebhookNotFound
+	}
+
+	log := s.deliveries[id]
+	result := make([]models.WebhookDelivery, 0, len(log))
+	for i := len(log) - 1; i >= 0; i-- {
+		delivery := *log[i]
+		delivery.Attempts = append([]models.WebhookAttempt(nil), delivery.Attempts...)
+		result = append(result, delivery)
+	}
+	return result, nil
+}
+
+// subscribersOf returns the subscriptions that want eventType
+func (s *Store) subscribersOf(eventType events.Type) []models.Webhook {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []models.Webhook
+	for _, id := range s.order {
+		if subscription := s.subscriptions[id]; wants(subscription, eventType) {
+			result = append(result, *subscription)
+		}
+	}
+	return result
+}
+
+// startDelivery adds a pending delivery to the subscription's log, dropping the oldest when full
+func (s *Store) startDelivery(subscriptionID string, event events.Event) *models.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery := &models.WebhookDelivery{
+		ID:        uuid.New().String(),
+		WebhookID: subscriptionID,
+		EventID:   event.ID,
+		EventType: string(event.Type),
+		Status:    models.DeliveryPending,
+		Attempts:  []models.WebhookAttempt{},
+		CreatedAt: s.now(),
+	}
+
+	log := append(s.deliveries[subscriptionID], delivery)
+	if len(log) > maxDeliveriesPerSubscription {
+		log = log[len(log)-maxDeliveriesPerSubscription:]
+	}
+	s.deliveries[subscriptionID] = log
+	return delivery
+}
+
+// recordAttempt appends attempt to the delivery and moves it to status
+func (s *Store) recordAttempt(delivery *models.WebhookDelivery, attempt models.WebhookAttempt, status models.DeliveryStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.Status = status
+}
+
+func (s *Store) exists(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.subscriptions[id]
+	return exists
+}
@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"errors"
+	"matching_system/internal/events"
+	"matching_system/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_CreateListDelete(t *testing.T) {
+	store := NewStore()
+	first := store.Create("http://a.example", []string{"matched"}, "secret-a")
+	second := store.Create("http://b.example", []string{"person_added", "matched"}, "secret-b")
+
+	list := store.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, first.ID, list[0].ID, "subscriptions should be listed oldest first")
+	assert.Equal(t, second.ID, list[1].ID)
+
+	assert.Len(t, store.subscribersOf(events.Matched), 2)
+	assert.Len(t, store.subscribersOf(events.PersonAdded), 1)
+	assert.Empty(t, store.subscribersOf(events.PersonRemoved))
+
+	require.NoError(t, store.Delete(first.ID))
+	_, err := store.Get(first.ID)
+	assert.True(t, errors.Is(err, services.ErrWebhookNotFound))
+	assert.True(t, errors.Is(store.Delete(first.ID), services.ErrWebhookNotFound))
+	assert.Len(t, store.List(), 1)
+}
+
+func TestStore_DeliveryLogIsBounded(t *testing.T) {
+	store := NewStore()
+	subscription := store.Create("http://a.example", []string{"matched"}, "secret")
+
+	for id := uint64(1); id <= maxDeliveriesPerSubscription+5; id++ {
+		store.startDelivery(subscription.ID, events.Event{ID: id, Type: events.Matched})
+	}
+
+	deliveries, err := store.Deliveries(subscription.ID)
+	require.NoError(t, err)
+	require.Len(t, deliveries, maxDeliveriesPerSubscription)
+	assert.Equal(t, uint64(maxDeliveriesPerSubscription+5), deliveries[0].EventID, "newest first")
+	assert.Equal(t, uint64(6), deliveries[len(deliveries)-1].EventID, "the oldest are dropped")
+}
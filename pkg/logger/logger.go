@@ -1,36 +1,144 @@
 package logger
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strings"
 )
 
+// Format is how log lines are written
+type Format string
+
+const (
+	// Text writes key=value lines
+	Text Format = "text"
+	// JSON writes one JSON object per line
+	JSON Format = "json"
+)
+
+// Logger writes leveled lines of a message and key/value pairs, such as
+// l.Info("Pool restored", "pool", name, "people", n). It is a slog.Logger with a few additions.
 type Logger struct {
-	infoLogger  *log.Logger
-	errorLogger *log.Logger
-	warnLogger  *log.Logger
+	*slog.Logger
+}
+
+type options struct {
+	level  slog.Level
+	format Format
+	output io.Writer
+}
+
+// Option configures a Logger
+type Option func(*options)
+
+// WithLevel drops lines below level, Info by default
+func WithLevel(level slog.Level) Option {
+	return func(o *options) {
+		o.level = level
+	}
 }
 
-func New() *Logger {
-	return &Logger{
-		infoLogger:  log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile),
-		errorLogger: log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile),
-		warnLogger:  log.New(os.Stdout, "WARN: ", log.Ldate|log.Ltime|log.Lshortfile),
+// WithFormat writes lines in format, Text by default
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
 	}
 }
 
-func (l *Logger) Info(message string) {
-	l.infoLogger.Println(message)
+// WithOutput writes lines to w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
 }
 
-func (l *Logger) Error(message string) {
-	l.errorLogger.Println(message)
+// New returns a logger writing Info lines and above as text to stdout, unless opts say otherwise
+func New(opts ...Option) *Logger {
+	o := options{level: slog.LevelInfo, format: Text, output: os.Stdout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: o.level}
+	if o.format == JSON {
+		return &Logger{slog.New(slog.NewJSONHandler(o.output, handlerOptions))}
+	}
+	return &Logger{slog.New(slog.NewTextHandler(o.output, handlerOptions))}
 }
 
-func (l *Logger) Warn(message string) {
-	l.warnLogger.Println(message)
+// ParseLevel reads debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return level, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+	}
+	return level, nil
 }
 
-func (l *Logger) Fatal(message string) {
-	l.errorLogger.Fatal(message)
+// ParseFormat reads text or json
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(s)); format {
+	case Text, JSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, want text or json", s)
+}
+
+// With returns a logger adding args to every line
+func (l *Logger) With(args ...any) *Logger {
+	return &Logger{l.Logger.With(args...)}
+}
+
+// Fatal logs at error level and exits
+func (l *Logger) Fatal(msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Default returns the process-wide logger, see SetDefault
+func Default() *Logger {
+	return &Logger{slog.Default()}
+}
+
+// SetDefault makes l the process-wide logger, which the standard log package writes through as well
+func SetDefault(l *Logger) {
+	slog.SetDefault(l.Logger)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying l, usually a logger with the request's ID
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger ctx carries, or the default one
+func FromContext(ctx context.Context) *Logger {
+	return FromContextOr(ctx, Default())
+}
+
+// FromContextOr returns the logger ctx carries, or fallback
+func FromContextOr(ctx context.Context, fallback *Logger) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+// Setup builds the logger of a process from the names of its level and format, and makes it the default
+func Setup(level, format string) (*Logger, error) {
+	parsedLevel, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	parsedFormat, err := ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	l := New(WithLevel(parsedLevel), WithFormat(parsedFormat))
+	SetDefault(l)
+	return l, nil
 }
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger_JSON(t *testing.T) {
+	var out bytes.Buffer
+	l := New(WithFormat(JSON), WithOutput(&out)).With("request_id", "req-42")
+
+	l.Info("Person added", "person_id", "p1", "matches", 2)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "INFO", line["level"])
+	assert.Equal(t, "Person added", line["msg"])
+	assert.Equal(t, "req-42", line["request_id"])
+	assert.Equal(t, "p1", line["person_id"])
+	assert.Equal(t, float64(2), line["matches"])
+}
+
+func TestLogger_Level(t *testing.T) {
+	var out bytes.Buffer
+	l := New(WithLevel(slog.LevelWarn), WithOutput(&out))
+
+	l.Info("dropped")
+	assert.Empty(t, out.String())
+	l.Warn("kept", "pool", "eu")
+	assert.Contains(t, out.String(), "level=WARN msg=kept pool=eu")
+}
+
+func TestParse(t *testing.T) {
+	level, err := ParseLevel("debug")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+
+	format, err := ParseFormat("JSON")
+	require.NoError(t, err)
+	assert.Equal(t, JSON, format)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	fallback := New()
+	assert.Same(t, fallback, FromContextOr(context.Background(), fallback))
+
+	l := New().With("request_id", "req-42")
+	assert.Same(t, l, FromContext(NewContext(context.Background(), l)))
+}